@@ -22,6 +22,13 @@ type API struct {
 	Operations []Operation `json:"operations,omitempty"`
 	Auth       []APIAuth   `json:"auth,omitempty"`
 	AutoConfig AutoConfig  `json:"autoconfig,omitempty"`
+
+	// DocsURL and TermsURL link to the API's documentation and terms of
+	// service, if the description provides them. SpecURL is where the API
+	// description was loaded from when it was fetched over HTTP.
+	DocsURL  string `json:"docs_url,omitempty"`
+	TermsURL string `json:"terms_url,omitempty"`
+	SpecURL  string `json:"spec_url,omitempty"`
 }
 
 // Merge two APIs together. Takes the description if none is set and merges
@@ -35,6 +42,18 @@ func (a *API) Merge(other API) {
 		a.Long = other.Long
 	}
 
+	if a.DocsURL == "" {
+		a.DocsURL = other.DocsURL
+	}
+
+	if a.TermsURL == "" {
+		a.TermsURL = other.TermsURL
+	}
+
+	if a.SpecURL == "" {
+		a.SpecURL = other.SpecURL
+	}
+
 	a.Operations = append(a.Operations, other.Operations...)
 }
 
@@ -128,6 +147,9 @@ func Load(entrypoint string, root *cobra.Command) (API, error) {
 						return API{}, err
 					}
 					LogDebug("Loaded %s", filename)
+					if strings.HasPrefix(strings.ToLower(filename), "http") {
+						tmp.SpecURL = filename
+					}
 					desc.Merge(tmp)
 					break
 				}
@@ -154,7 +176,7 @@ func Load(entrypoint string, root *cobra.Command) (API, error) {
 		client = &http.Client{Transport: InvalidateCachedTransport()}
 	}
 
-	httpResp, err := MakeRequest(req, WithClient(client), WithoutLog())
+	httpResp, err := MakeRequest(req, WithClient(client), WithoutLog(), WithoutDryRun())
 	if err != nil {
 		return API{}, err
 	}
@@ -208,7 +230,11 @@ func Load(entrypoint string, root *cobra.Command) (API, error) {
 			if l.Detect(resp) {
 				resp.Body = ioutil.NopCloser(bytes.NewReader(body))
 
-				return load(root, *uri, *resolved, resp, name, l)
+				api, err := load(root, *uri, *resolved, resp, name, l)
+				if err == nil && api.SpecURL == "" {
+					api.SpecURL = resolved.String()
+				}
+				return api, err
 			}
 		}
 	}
@@ -1,9 +1,11 @@
 package cli
 
 import (
+	"fmt"
 	"io/ioutil"
 	"os"
 	"path"
+	"regexp"
 	"strings"
 
 	"github.com/spf13/cobra"
@@ -32,6 +34,118 @@ type APIProfile struct {
 	Headers map[string]string `json:"headers,omitempty"`
 	Query   map[string]string `json:"query,omitempty"`
 	Auth    *APIAuth          `json:"auth"`
+
+	// AuthChain is an ordered list of additional auth handlers which are
+	// applied after `Auth`, e.g. an API key header followed by a signature.
+	AuthChain []*APIAuth `json:"auth_chain,omitempty" mapstructure:"auth_chain,omitempty"`
+}
+
+// Auths returns the ordered list of auth handlers to apply for this profile.
+func (p *APIProfile) Auths() []*APIAuth {
+	auths := []*APIAuth{}
+
+	if p.Auth != nil && p.Auth.Name != "" {
+		auths = append(auths, p.Auth)
+	}
+
+	for _, a := range p.AuthChain {
+		if a != nil && a.Name != "" {
+			auths = append(auths, a)
+		}
+	}
+
+	return auths
+}
+
+// envRef matches environment variable references like `${PROD_USER}`.
+var envRef = regexp.MustCompile(`\$\{([A-Za-z_][A-Za-z0-9_]*)\}`)
+
+// expandEnvRefs replaces `${NAME}` references in the value with the named
+// environment variable. Unlike `os.ExpandEnv`, a variable which is not set is
+// an error rather than an empty string, and a bare `$` is left as-is.
+func expandEnvRefs(value string) (string, error) {
+	var missing []string
+	expanded := envRef.ReplaceAllStringFunc(value, func(ref string) string {
+		name := envRef.FindStringSubmatch(ref)[1]
+		v, ok := os.LookupEnv(name)
+		if !ok {
+			missing = append(missing, name)
+		}
+		return v
+	})
+
+	if len(missing) > 0 {
+		return "", fmt.Errorf("environment variable %s is not set", strings.Join(missing, ", "))
+	}
+
+	return expanded, nil
+}
+
+// expandEnvMap returns a copy of the values with encrypted secrets decrypted
+// and environment variable references expanded. The kind describes the
+// values in errors.
+func expandEnvMap(kind string, values map[string]string) (map[string]string, error) {
+	if values == nil {
+		return nil, nil
+	}
+
+	expanded := make(map[string]string, len(values))
+	for k, v := range values {
+		e, err := resolveSecret(v)
+		if err == nil {
+			e, err = expandEnvRefs(e)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%s %s: %w", kind, k, err)
+		}
+		expanded[k] = e
+	}
+
+	return expanded, nil
+}
+
+// ExpandEnv returns a copy of the profile with `${NAME}` environment variable
+// references in headers, query params and auth params expanded, so the same
+// configuration can be used across environments by swapping variables.
+// Secrets encrypted via `api encrypt` are decrypted first.
+func (p *APIProfile) ExpandEnv() (*APIProfile, error) {
+	var err error
+	expanded := &APIProfile{}
+
+	if expanded.Headers, err = expandEnvMap("header", p.Headers); err != nil {
+		return nil, err
+	}
+
+	if expanded.Query, err = expandEnvMap("query param", p.Query); err != nil {
+		return nil, err
+	}
+
+	expandAuth := func(a *APIAuth) (*APIAuth, error) {
+		if a == nil {
+			return nil, nil
+		}
+
+		params, err := expandEnvMap(a.Name+" auth param", a.Params)
+		if err != nil {
+			return nil, err
+		}
+
+		return &APIAuth{Name: a.Name, Params: params}, nil
+	}
+
+	if expanded.Auth, err = expandAuth(p.Auth); err != nil {
+		return nil, err
+	}
+
+	for _, a := range p.AuthChain {
+		e, err := expandAuth(a)
+		if err != nil {
+			return nil, err
+		}
+		expanded.AuthChain = append(expanded.AuthChain, e)
+	}
+
+	return expanded, nil
 }
 
 // APIConfig describes per-API configuration options like the base URI and
@@ -42,6 +156,28 @@ type APIConfig struct {
 	SpecFiles []string               `json:"spec_files,omitempty" mapstructure:"spec_files,omitempty"`
 	Profiles  map[string]*APIProfile `json:"profiles,omitempty" mapstructure:",omitempty"`
 	TLS       *TLSConfig             `json:"tls,omitempty" mapstructure:",omitempty"`
+
+	// CompressRequests is a hint that the server accepts gzip-encoded request
+	// bodies, so large bodies are compressed automatically.
+	CompressRequests bool `json:"compress_requests,omitempty" mapstructure:"compress_requests,omitempty"`
+
+	// Pagination configures how paginated collections are followed.
+	Pagination *PaginationConfig `json:"pagination,omitempty" mapstructure:",omitempty"`
+
+	// Since configures the query param set by `--rsh-since`.
+	Since *SinceConfig `json:"since,omitempty" mapstructure:",omitempty"`
+
+	// CacheTTL overrides how long responses are cached, e.g. `1h`, ignoring
+	// any cache headers sent by the server.
+	CacheTTL string `json:"cache_ttl,omitempty" mapstructure:"cache_ttl,omitempty"`
+
+	// Protobuf configures the messages used to encode and decode
+	// `application/x-protobuf` bodies.
+	Protobuf *ProtobufConfig `json:"protobuf,omitempty" mapstructure:",omitempty"`
+
+	// UserAgent overrides the `User-Agent` header sent to the API, e.g. for
+	// servers which allowlist clients.
+	UserAgent string `json:"user_agent,omitempty" mapstructure:"user_agent,omitempty"`
 }
 
 // Save the API configuration to disk.
@@ -56,7 +192,8 @@ var configs apiConfigs
 var apiCommand *cobra.Command
 var profileCommand *cobra.Command
 
-func initAPIConfig() {
+// loadAPIConfigs reads the per-API configuration file into `configs`.
+func loadAPIConfigs() {
 	apis = viper.New()
 
 	apis.SetConfigName("apis")
@@ -73,6 +210,17 @@ func initAPIConfig() {
 
 	apis.ReadInConfig()
 
+	configs = apiConfigs{}
+	if err := apis.Unmarshal(&configs); err != nil {
+		panic(err)
+	}
+
+	for apiName, config := range configs {
+		config.name = apiName
+	}
+}
+
+func initAPIConfig() {
 	// Register api init sub-command to register the API.
 	apiCommand = &cobra.Command{
 		Use:   "api",
@@ -89,16 +237,54 @@ func initAPIConfig() {
 		Run:     askInitAPIDefault,
 	})
 
-	// Register API sub-commands
-	configs = apiConfigs{}
-	if err := apis.Unmarshal(&configs); err != nil {
-		panic(err)
+	apiCommand.AddCommand(&cobra.Command{
+		Use:   "open short-name [docs|terms|spec|swagger-ui]",
+		Short: "Open API docs in a browser",
+		Long:  "Opens the API's documentation or terms of service URL from its description in a browser. If the description was loaded from a URL, the spec itself or a hosted Swagger UI for it can be opened instead.",
+		Args:  cobra.RangeArgs(1, 2),
+		Run:   apiOpen,
+	})
+
+	operations := &cobra.Command{
+		Use:   "operations short-name",
+		Short: "List an API's operations by tag",
+		Long:  "Lists the operations of an API grouped by the tags from its description, e.g. OpenAPI operation tags. Use `--tag` to only show operations with a given tag.",
+		Args:  cobra.ExactArgs(1),
+		Run:   apiOperations,
 	}
+	operations.Flags().String("tag", "", "Only show operations with this tag")
+	apiCommand.AddCommand(operations)
 
-	for apiName, config := range configs {
-		config.name = apiName
-		configs[apiName] = config
+	apiCommand.AddCommand(&cobra.Command{
+		Use:   "encrypt short-name",
+		Short: "Encrypt an API's secrets",
+		Long:  "Encrypts secrets like passwords, tokens and API keys in the API's profiles using a passphrase, which is prompted for or read from `RSH_PASSPHRASE`. Secrets are decrypted in memory when making requests.",
+		Args:  cobra.ExactArgs(1),
+		Run:   apiEncrypt,
+	})
+
+	apiCommand.AddCommand(&cobra.Command{
+		Use:   "decrypt short-name",
+		Short: "Decrypt an API's secrets",
+		Long:  "Decrypts secrets previously encrypted via `api encrypt`, storing them as plaintext again.",
+		Args:  cobra.ExactArgs(1),
+		Run:   apiDecrypt,
+	})
 
+	apiCommand.AddCommand(&cobra.Command{
+		Use:   "profiles short-name",
+		Short: "List an API's profiles",
+		Long:  "Lists the profiles configured for an API along with the auth handlers, headers and query params each uses. Credentials are masked unless `--rsh-show-secrets` is passed.",
+		Args:  cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			if err := listProfiles(args[0]); err != nil {
+				panic(err)
+			}
+		},
+	})
+
+	// Register API sub-commands
+	for apiName, config := range configs {
 		n := apiName
 		c := config
 		cmd := &cobra.Command{
@@ -1,8 +1,13 @@
 package cli
 
 import (
+	"crypto/tls"
+	"fmt"
+	"io/ioutil"
 	"net/http"
+	"os"
 	"os/exec"
+	"path/filepath"
 	"strings"
 )
 
@@ -31,20 +36,67 @@ func AddAuth(name string, h AuthHandler) {
 	authHandlers[name] = h
 }
 
-// BasicAuth implements HTTP Basic authentication.
+// authCacheKey returns the namespaced cache key for a profile's auth data.
+func authCacheKey(profileKey string) string {
+	return "auth." + profileKey
+}
+
+// GetAuthCache returns the cached auth data (e.g. tokens) for a profile key as
+// passed to `AuthHandler.OnRequest`. Returns an empty map if nothing has been
+// cached yet.
+func GetAuthCache(profileKey string) map[string]interface{} {
+	data := Cache.GetStringMap(authCacheKey(profileKey))
+	if len(data) == 0 {
+		// Fall back to where older versions stored tokens.
+		data = Cache.GetStringMap(profileKey)
+	}
+
+	if data == nil {
+		data = map[string]interface{}{}
+	}
+
+	return data
+}
+
+// SetAuthCache replaces the cached auth data for a profile key and saves the
+// cache to disk so it can be reused by later invocations.
+func SetAuthCache(profileKey string, data map[string]interface{}) error {
+	Cache.Set(authCacheKey(profileKey), data)
+
+	if err := Cache.WriteConfig(); err != nil {
+		return err
+	}
+
+	// The cache may contain secrets, so keep it private.
+	return os.Chmod(Cache.ConfigFileUsed(), 0600)
+}
+
+// BasicAuth implements HTTP Basic authentication. If the username and
+// password are omitted, then credentials for the request's host are loaded
+// from the user's netrc file if possible.
 type BasicAuth struct{}
 
 // Parameters define the HTTP Basic Auth parameter names.
 func (a *BasicAuth) Parameters() []AuthParam {
 	return []AuthParam{
-		{Name: "username", Required: true},
-		{Name: "password", Required: true},
+		{Name: "username", Help: "Leave blank to use ~/.netrc"},
+		{Name: "password", Help: "Leave blank to use ~/.netrc"},
 	}
 }
 
 // OnRequest gets run before the request goes out on the wire.
 func (a *BasicAuth) OnRequest(req *http.Request, key string, params map[string]string) error {
-	req.SetBasicAuth(params["username"], params["password"])
+	username, password := params["username"], params["password"]
+
+	if username == "" && password == "" {
+		username, password = netrcCredentials(req.URL.Hostname())
+		if username == "" && password == "" {
+			LogDebug("No basic auth credentials found for %s", req.URL.Hostname())
+			return nil
+		}
+	}
+
+	req.SetBasicAuth(username, password)
 	return nil
 }
 
@@ -70,3 +122,163 @@ func (a *ApiKeyHeaderFromShellAuth) OnRequest(req *http.Request, key string, par
 	req.Header.Add(data[0], data[1])
 	return nil
 }
+
+// ApiKeyQueryAuth implements authentication via an API key in a query param.
+type ApiKeyQueryAuth struct{}
+
+// Parameters define the ApiKeyQueryAuth parameter names.
+func (a *ApiKeyQueryAuth) Parameters() []AuthParam {
+	return []AuthParam{
+		{Name: "name", Required: true, Help: "Query param name, e.g. apikey"},
+		{Name: "value", Required: true, Help: "API key value"},
+	}
+}
+
+// OnRequest gets run before the request goes out on the wire.
+func (a *ApiKeyQueryAuth) OnRequest(req *http.Request, key string, params map[string]string) error {
+	if params["name"] == "" {
+		return fmt.Errorf("api-key-query auth: name is required")
+	}
+
+	// Add rather than set, so existing values (including duplicates) are kept.
+	query := req.URL.Query()
+	query.Add(params["name"], params["value"])
+	req.URL.RawQuery = query.Encode()
+
+	return nil
+}
+
+// BearerTokenAuth implements bearer token authentication via the
+// `Authorization` header. The token may reference environment variables like
+// `${MY_TOKEN}`, which are expanded with the rest of the profile at request
+// time so that secrets need not be stored in the configuration file.
+type BearerTokenAuth struct{}
+
+// Parameters define the BearerTokenAuth parameter names.
+func (a *BearerTokenAuth) Parameters() []AuthParam {
+	return []AuthParam{
+		{Name: "token", Required: true, Help: "Bearer token, e.g. ${MY_TOKEN} to read from the environment"},
+	}
+}
+
+// OnRequest gets run before the request goes out on the wire.
+func (a *BearerTokenAuth) OnRequest(req *http.Request, key string, params map[string]string) error {
+	token := params["token"]
+	if token == "" {
+		return fmt.Errorf("bearer-token auth: token is empty")
+	}
+
+	req.Header.Set("Authorization", "Bearer "+token)
+	return nil
+}
+
+// BearerFileAuth implements bearer token authentication where the token is
+// read from a file, e.g. one written by a sidecar agent or a Kubernetes
+// projected service account token. The file is read on every request so that
+// rotated tokens are picked up automatically.
+type BearerFileAuth struct{}
+
+// Parameters define the BearerFileAuth parameter names.
+func (a *BearerFileAuth) Parameters() []AuthParam {
+	return []AuthParam{
+		{Name: "path", Required: true, Help: "Path to a file containing the bearer token"},
+	}
+}
+
+// OnRequest gets run before the request goes out on the wire.
+func (a *BearerFileAuth) OnRequest(req *http.Request, key string, params map[string]string) error {
+	path := params["path"]
+	if path == "" {
+		return fmt.Errorf("bearer-file auth: path is required")
+	}
+
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return fmt.Errorf("bearer-file auth: token file %s does not exist", path)
+		}
+		return fmt.Errorf("bearer-file auth: %w", err)
+	}
+
+	token := strings.TrimSpace(string(data))
+	if token == "" {
+		return fmt.Errorf("bearer-file auth: token file %s is empty", path)
+	}
+
+	req.Header.Set("Authorization", "Bearer "+token)
+	return nil
+}
+
+// k8sServiceAccountDir is where Kubernetes mounts the service account
+// credentials inside of a pod.
+const k8sServiceAccountDir = "/var/run/secrets/kubernetes.io/serviceaccount"
+
+// K8sInClusterAuth implements auth for requests made from within a Kubernetes
+// pod, e.g. to the Kubernetes API or other in-cluster services. The pod's
+// service account token is sent as a bearer token and the cluster CA is
+// trusted for TLS connections. Both are read on each request since projected
+// tokens are rotated periodically.
+type K8sInClusterAuth struct {
+	trustedCA string
+}
+
+// Parameters define the K8sInClusterAuth parameter names.
+func (a *K8sInClusterAuth) Parameters() []AuthParam {
+	return []AuthParam{
+		{Name: "dir", Help: "Service account directory, defaults to " + k8sServiceAccountDir},
+	}
+}
+
+// OnRequest gets run before the request goes out on the wire.
+func (a *K8sInClusterAuth) OnRequest(req *http.Request, key string, params map[string]string) error {
+	dir := params["dir"]
+	if dir == "" {
+		dir = k8sServiceAccountDir
+	}
+
+	token, err := ioutil.ReadFile(filepath.Join(dir, "token"))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return fmt.Errorf("k8s-incluster auth: no service account token in %s, is this running in a pod?", dir)
+		}
+		return fmt.Errorf("k8s-incluster auth: %w", err)
+	}
+
+	if strings.TrimSpace(string(token)) == "" {
+		return fmt.Errorf("k8s-incluster auth: service account token is empty")
+	}
+
+	req.Header.Set("Authorization", "Bearer "+strings.TrimSpace(string(token)))
+
+	ca, err := ioutil.ReadFile(filepath.Join(dir, "ca.crt"))
+	if err != nil {
+		if os.IsNotExist(err) {
+			// Some services use publicly trusted certs, so the CA is optional.
+			return nil
+		}
+		return fmt.Errorf("k8s-incluster auth: %w", err)
+	}
+
+	// Request transports are cloned from the default HTTP transport, see
+	// `configuredTransport`. Its TLS config may be shared with them, so it is
+	// replaced rather than modified.
+	return updateDefaultTransport(func(t *http.Transport) (bool, error) {
+		if string(ca) == a.trustedCA {
+			return false, nil
+		}
+
+		pool := BestEffortSystemCertPool()
+		if !pool.AppendCertsFromPEM(ca) {
+			return false, fmt.Errorf("k8s-incluster auth: failed to parse cluster CA certificate")
+		}
+
+		cfg := &tls.Config{}
+		if t.TLSClientConfig != nil {
+			cfg = t.TLSClientConfig.Clone()
+		}
+		cfg.RootCAs = pool
+		t.TLSClientConfig = cfg
+		a.trustedCA = string(ca)
+		return true, nil
+	})
+}
@@ -0,0 +1,275 @@
+package cli
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/h2non/gock.v1"
+)
+
+func TestBearerTokenAuth(t *testing.T) {
+	auth := &BearerTokenAuth{}
+
+	req, _ := http.NewRequest(http.MethodGet, "http://example.com/", nil)
+	err := auth.OnRequest(req, "test:default", map[string]string{
+		"token": "abc123",
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, "Bearer abc123", req.Header.Get("Authorization"))
+
+	// Params are already expanded with the profile, so a `$` in the token is
+	// sent as-is rather than being expanded again.
+	req, _ = http.NewRequest(http.MethodGet, "http://example.com/", nil)
+	err = auth.OnRequest(req, "test:default", map[string]string{
+		"token": "abc$RSH_TEST_TOKEN",
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, "Bearer abc$RSH_TEST_TOKEN", req.Header.Get("Authorization"))
+}
+
+func TestBearerTokenAuthProfileEnv(t *testing.T) {
+	reset(false)
+
+	configs["bearer-env"] = &APIConfig{
+		Base: "https://bearer-env.example.com",
+		Profiles: map[string]*APIProfile{
+			"default": {
+				Auth: &APIAuth{
+					Name:   "bearer-token",
+					Params: map[string]string{"token": "${RSH_TEST_TOKEN}"},
+				},
+			},
+		},
+	}
+	defer delete(configs, "bearer-env")
+
+	os.Unsetenv("RSH_TEST_TOKEN")
+	req, _ := http.NewRequest(http.MethodGet, "https://bearer-env.example.com/", nil)
+	_, err := MakeRequest(req)
+	assert.EqualError(t, err, "profile default: bearer-token auth param token: environment variable RSH_TEST_TOKEN is not set")
+	assert.Empty(t, req.Header.Get("Authorization"))
+
+	// The variable is expanded exactly once, even if its value contains a `$`.
+	os.Setenv("RSH_TEST_TOKEN", "def$RSH_TEST_OTHER")
+	os.Setenv("RSH_TEST_OTHER", "oops")
+	defer os.Unsetenv("RSH_TEST_TOKEN")
+	defer os.Unsetenv("RSH_TEST_OTHER")
+
+	defer gock.Off()
+	gock.New("https://bearer-env.example.com").Get("/").Reply(204)
+
+	req, _ = http.NewRequest(http.MethodGet, "https://bearer-env.example.com/", nil)
+	_, err = MakeRequest(req)
+	assert.NoError(t, err)
+	assert.Equal(t, "Bearer def$RSH_TEST_OTHER", req.Header.Get("Authorization"))
+}
+
+func TestBearerFileAuth(t *testing.T) {
+	auth := &BearerFileAuth{}
+
+	dir, err := ioutil.TempDir("", "restish-bearer")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "token")
+
+	req, _ := http.NewRequest(http.MethodGet, "http://example.com/", nil)
+	err = auth.OnRequest(req, "test:default", map[string]string{"path": path})
+	assert.EqualError(t, err, "bearer-file auth: token file "+path+" does not exist")
+
+	assert.NoError(t, ioutil.WriteFile(path, []byte("\n"), 0600))
+	err = auth.OnRequest(req, "test:default", map[string]string{"path": path})
+	assert.EqualError(t, err, "bearer-file auth: token file "+path+" is empty")
+
+	assert.NoError(t, ioutil.WriteFile(path, []byte("abc123\n"), 0600))
+	err = auth.OnRequest(req, "test:default", map[string]string{"path": path})
+	assert.NoError(t, err)
+	assert.Equal(t, "Bearer abc123", req.Header.Get("Authorization"))
+
+	// Rotated tokens are picked up on the next request.
+	assert.NoError(t, ioutil.WriteFile(path, []byte("def456"), 0600))
+	err = auth.OnRequest(req, "test:default", map[string]string{"path": path})
+	assert.NoError(t, err)
+	assert.Equal(t, "Bearer def456", req.Header.Get("Authorization"))
+}
+
+func TestK8sInClusterAuth(t *testing.T) {
+	auth := &K8sInClusterAuth{}
+
+	dir, err := ioutil.TempDir("", "restish-k8s")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	req, _ := http.NewRequest(http.MethodGet, "https://kubernetes.default.svc/api", nil)
+	err = auth.OnRequest(req, "test:default", map[string]string{"dir": dir})
+	assert.EqualError(t, err, "k8s-incluster auth: no service account token in "+dir+", is this running in a pod?")
+
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(dir, "token"), []byte("abc123"), 0600))
+	err = auth.OnRequest(req, "test:default", map[string]string{"dir": dir})
+	assert.NoError(t, err)
+	assert.Equal(t, "Bearer abc123", req.Header.Get("Authorization"))
+
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(dir, "ca.crt"), []byte("not a cert"), 0600))
+	err = auth.OnRequest(req, "test:default", map[string]string{"dir": dir})
+	assert.EqualError(t, err, "k8s-incluster auth: failed to parse cluster CA certificate")
+}
+
+func TestApiKeyQueryAuth(t *testing.T) {
+	auth := &ApiKeyQueryAuth{}
+
+	req, _ := http.NewRequest(http.MethodGet, "http://example.com/?tag=a&tag=b", nil)
+	err := auth.OnRequest(req, "test:default", map[string]string{
+		"name":  "apikey",
+		"value": "abc 123&def",
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, "apikey=abc+123%26def&tag=a&tag=b", req.URL.RawQuery)
+
+	err = auth.OnRequest(req, "test:default", map[string]string{})
+	assert.Error(t, err)
+}
+
+func TestAuthCache(t *testing.T) {
+	reset(false)
+
+	err := SetAuthCache("cache-test:default", map[string]interface{}{
+		"token": "abc123",
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, "abc123", GetAuthCache("cache-test:default")["token"])
+
+	// Other profiles must not see the data.
+	assert.Empty(t, GetAuthCache("cache-test:other"))
+
+	info, err := os.Stat(Cache.ConfigFileUsed())
+	assert.NoError(t, err)
+	assert.Equal(t, os.FileMode(0600), info.Mode().Perm())
+}
+
+func TestBasicAuthNetrc(t *testing.T) {
+	dir, err := ioutil.TempDir("", "restish-netrc")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	filename := filepath.Join(dir, "netrc")
+	ioutil.WriteFile(filename, []byte(`# Comment
+machine example.com login user1 password pass1
+macdef init
+machine ignored.com login bad password bad
+
+machine api.example.com
+  login user2
+  password pass2
+default login anon password secret
+`), 0600)
+
+	os.Setenv("NETRC", filename)
+	defer os.Unsetenv("NETRC")
+
+	auth := &BasicAuth{}
+
+	for host, expected := range map[string][]string{
+		"example.com":     {"user1", "pass1"},
+		"api.example.com": {"user2", "pass2"},
+		"other.com":       {"anon", "secret"},
+	} {
+		req, _ := http.NewRequest(http.MethodGet, "https://"+host+"/", nil)
+		assert.NoError(t, auth.OnRequest(req, "test:default", map[string]string{}))
+		username, password, ok := req.BasicAuth()
+		assert.True(t, ok)
+		assert.Equal(t, expected[0], username)
+		assert.Equal(t, expected[1], password)
+	}
+
+	// Explicit params always win.
+	req, _ := http.NewRequest(http.MethodGet, "https://example.com/", nil)
+	assert.NoError(t, auth.OnRequest(req, "test:default", map[string]string{
+		"username": "explicit",
+		"password": "value",
+	}))
+	username, _, _ := req.BasicAuth()
+	assert.Equal(t, "explicit", username)
+
+	// A missing file is silently skipped.
+	os.Setenv("NETRC", filepath.Join(dir, "missing"))
+	req, _ = http.NewRequest(http.MethodGet, "https://example.com/", nil)
+	assert.NoError(t, auth.OnRequest(req, "test:default", map[string]string{}))
+	_, _, ok := req.BasicAuth()
+	assert.False(t, ok)
+}
+
+func TestHTTPMessageSignatureAuth(t *testing.T) {
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	assert.NoError(t, err)
+
+	der, err := x509.MarshalPKCS8PrivateKey(priv)
+	assert.NoError(t, err)
+
+	dir, err := ioutil.TempDir("", "restish-httpsig")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	keyFile := filepath.Join(dir, "key.pem")
+	assert.NoError(t, ioutil.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}), 0600))
+
+	signatureNow = func() time.Time { return time.Unix(1618884473, 0) }
+	defer func() { signatureNow = time.Now }()
+
+	auth := &HTTPMessageSignatureAuth{}
+
+	req, _ := http.NewRequest(http.MethodPost, "https://Example.com/foo?a=1", strings.NewReader(`{"hello": "world"}`))
+	req.Header.Set("Content-Type", "application/json")
+	err = auth.OnRequest(req, "test:default", map[string]string{
+		"key_id":   "test-key",
+		"key_file": keyFile,
+	})
+	assert.NoError(t, err)
+
+	sum := sha256.Sum256([]byte(`{"hello": "world"}`))
+	digest := "sha-256=:" + base64.StdEncoding.EncodeToString(sum[:]) + ":"
+	assert.Equal(t, digest, req.Header.Get("Content-Digest"))
+
+	params := `("@method" "@authority" "@path" "@query" "content-type" "content-digest");created=1618884473;keyid="test-key";alg="ed25519"`
+	assert.Equal(t, "sig1="+params, req.Header.Get("Signature-Input"))
+
+	base := strings.Join([]string{
+		`"@method": POST`,
+		`"@authority": example.com`,
+		`"@path": /foo`,
+		`"@query": ?a=1`,
+		`"content-type": application/json`,
+		`"content-digest": ` + digest,
+		`"@signature-params": ` + params,
+	}, "\n")
+
+	sig := req.Header.Get("Signature")
+	assert.True(t, strings.HasPrefix(sig, "sig1=:"))
+	decoded, err := base64.StdEncoding.DecodeString(strings.Trim(strings.TrimPrefix(sig, "sig1="), ":"))
+	assert.NoError(t, err)
+	assert.True(t, ed25519.Verify(pub, []byte(base), decoded))
+
+	// The body must still be readable after computing the digest.
+	body, _ := ioutil.ReadAll(req.Body)
+	assert.Equal(t, `{"hello": "world"}`, string(body))
+
+	// Explicit components must be present.
+	req, _ = http.NewRequest(http.MethodGet, "https://example.com/foo", nil)
+	err = auth.OnRequest(req, "test:default", map[string]string{
+		"key_id":     "test-key",
+		"key_file":   keyFile,
+		"components": "@method x-missing",
+	})
+	assert.Error(t, err)
+}
@@ -0,0 +1,195 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/spf13/viper"
+)
+
+// benchResult summarizes a benchmark run.
+type benchResult struct {
+	Requests   int            `json:"requests"`
+	Duration   float64        `json:"duration"`
+	Throughput float64        `json:"throughput"`
+	Latency    benchLatency   `json:"latency"`
+	Statuses   map[string]int `json:"statuses"`
+}
+
+// benchLatency holds latency percentiles in milliseconds.
+type benchLatency struct {
+	P50 float64 `json:"p50"`
+	P90 float64 `json:"p90"`
+	P99 float64 `json:"p99"`
+	Max float64 `json:"max"`
+}
+
+func milliseconds(d time.Duration) float64 {
+	return float64(d) / float64(time.Millisecond)
+}
+
+// benchRequest sends a copy of the template request through the full request
+// pipeline, including auth, and reads the whole response body. Failed
+// requests are recorded without a status and the error is returned.
+func benchRequest(template *http.Request, metrics *RequestMetrics) error {
+	req := template.Clone(template.Context())
+	if err := resetBody(req); err != nil {
+		metrics.Record(0, 0)
+		return err
+	}
+
+	start := time.Now()
+	resp, err := MakeRequest(req)
+	if err != nil {
+		LogDebug("Request failed: %v", err)
+		metrics.Record(0, time.Since(start))
+		return err
+	}
+
+	io.Copy(ioutil.Discard, resp.Body)
+	resp.Body.Close()
+	metrics.Record(resp.StatusCode, time.Since(start))
+	return nil
+}
+
+// runBench sends the request using a pool of workers, either a fixed number of
+// times or, if the duration is positive, repeatedly until it has passed. If
+// no request got a response then the first error is returned, as e.g. an
+// invalid profile or failing auth would otherwise only show up as a status.
+func runBench(template *http.Request, requests, workers int, duration time.Duration) (*RequestMetrics, time.Duration, error) {
+	if err := bufferBody(template); err != nil {
+		return nil, 0, err
+	}
+
+	metrics := &RequestMetrics{}
+	start := time.Now()
+
+	var errs []error
+	if duration > 0 {
+		deadline := start.Add(duration)
+		errs = make([]error, concurrency(workers))
+		wg := sync.WaitGroup{}
+		for w := range errs {
+			wg.Add(1)
+			go func(w int) {
+				defer wg.Done()
+				for time.Now().Before(deadline) {
+					if err := benchRequest(template, metrics); err != nil && errs[w] == nil {
+						errs[w] = err
+					}
+				}
+			}(w)
+		}
+		wg.Wait()
+	} else {
+		errs = make([]error, requests)
+		forEachParallel(workers, requests, func(i int) {
+			errs[i] = benchRequest(template, metrics)
+		})
+	}
+
+	elapsed := time.Since(start)
+
+	if statuses := metrics.Statuses(); len(statuses) == 1 && statuses["error"] > 0 {
+		for _, err := range errs {
+			if err != nil {
+				return nil, elapsed, err
+			}
+		}
+	}
+
+	return metrics, elapsed, nil
+}
+
+// summarizeBench computes the results to display.
+func summarizeBench(metrics *RequestMetrics, elapsed time.Duration) benchResult {
+	result := benchResult{
+		Requests: metrics.Count(),
+		Duration: elapsed.Seconds(),
+		Latency: benchLatency{
+			P50: milliseconds(metrics.Percentile(50)),
+			P90: milliseconds(metrics.Percentile(90)),
+			P99: milliseconds(metrics.Percentile(99)),
+			Max: milliseconds(metrics.Percentile(100)),
+		},
+		Statuses: metrics.Statuses(),
+	}
+
+	if elapsed > 0 {
+		result.Throughput = float64(result.Requests) / elapsed.Seconds()
+	}
+
+	return result
+}
+
+// formatLatency rounds a latency in milliseconds for display.
+func formatLatency(ms float64) string {
+	return (time.Duration(ms * float64(time.Millisecond))).Round(10 * time.Microsecond).String()
+}
+
+// bench benchmarks the request and prints latency percentiles, throughput and
+// the distribution of status codes. Responses are never served from the
+// cache so that every request reaches the server.
+func bench(template *http.Request, requests, workers int, duration time.Duration) error {
+	if duration <= 0 && requests < 1 {
+		return fmt.Errorf("invalid number of requests %d", requests)
+	}
+
+	viper.Set("rsh-no-cache", true)
+
+	metrics, elapsed, err := runBench(template, requests, workers, duration)
+	if err != nil {
+		return err
+	}
+
+	if err := writeMetricsFile(metrics); err != nil {
+		return err
+	}
+
+	result := summarizeBench(metrics, elapsed)
+
+	if viper.GetString("rsh-output-format") == "json" {
+		encoded, err := json.MarshalIndent(result, "", "  ")
+		if err != nil {
+			return err
+		}
+
+		if tty {
+			if encoded, err = Highlight("json", encoded); err != nil {
+				return err
+			}
+		}
+
+		fmt.Fprintln(Stdout, string(encoded))
+		return nil
+	}
+
+	codes := make([]string, 0, len(result.Statuses))
+	for code := range result.Statuses {
+		codes = append(codes, code)
+	}
+	sort.Strings(codes)
+
+	statuses := make([]string, 0, len(codes))
+	for _, code := range codes {
+		label := au.Index(150, code)
+		if n, err := strconv.Atoi(code); err != nil || n >= 400 {
+			label = au.Index(204, code)
+		}
+		statuses = append(statuses, fmt.Sprintf("%s: %d", label, result.Statuses[code]))
+	}
+
+	fmt.Fprintf(Stdout, "%s %d in %s (%.1f req/s)\n", au.Bold("Requests:"), result.Requests, elapsed.Round(time.Millisecond), result.Throughput)
+	fmt.Fprintf(Stdout, "%s  p50 %s, p90 %s, p99 %s, max %s\n", au.Bold("Latency:"), formatLatency(result.Latency.P50), formatLatency(result.Latency.P90), formatLatency(result.Latency.P99), formatLatency(result.Latency.Max))
+	fmt.Fprintf(Stdout, "%s   %s\n", au.Bold("Status:"), strings.Join(statuses, ", "))
+
+	return nil
+}
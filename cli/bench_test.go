@@ -0,0 +1,62 @@
+package cli
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBench(t *testing.T) {
+	var count int32
+	var body atomic.Value
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data, _ := ioutil.ReadAll(r.Body)
+		body.Store(r.Method + " " + string(data))
+
+		// Every fifth request fails.
+		if atomic.AddInt32(&count, 1)%5 == 0 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.Header().Set("Cache-Control", "max-age=3600")
+		w.Write([]byte(`{"ok": true}`))
+	}))
+	defer server.Close()
+
+	captured := run("bench " + server.URL + " --method post -n 20 -c 4 -o json name: test")
+
+	var result benchResult
+	assert.NoError(t, json.Unmarshal([]byte(captured), &result))
+	assert.Equal(t, 20, result.Requests)
+	assert.Equal(t, map[string]int{"200": 16, "503": 4}, result.Statuses)
+	assert.True(t, result.Latency.P50 <= result.Latency.P99)
+	assert.True(t, result.Throughput > 0)
+
+	// Cacheable responses are still requested from the server each time.
+	assert.Equal(t, int32(20), atomic.LoadInt32(&count))
+	assert.Equal(t, `POST {"name":"test"}`, body.Load())
+}
+
+func TestBenchDuration(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+
+	captured := run("bench " + server.URL + " --duration 50ms -c 2")
+	assert.Contains(t, captured, "Requests:")
+	assert.Contains(t, captured, "Latency:  p50")
+	assert.Contains(t, captured, "Status:   200:")
+}
+
+func TestBenchInvalidProfile(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+
+	captured := run("bench " + server.URL + " -n 4 -c 2 --rsh-profile missing")
+	assert.Contains(t, captured, "invalid profile missing")
+	assert.Equal(t, 1, exitCode)
+}
@@ -0,0 +1,339 @@
+package cli
+
+import (
+	"bytes"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"math"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/spf13/viper"
+	"golang.org/x/crypto/ocsp"
+)
+
+// keyUsageNames maps key usage bits to readable names.
+var keyUsageNames = []struct {
+	usage x509.KeyUsage
+	name  string
+}{
+	{x509.KeyUsageDigitalSignature, "digital_signature"},
+	{x509.KeyUsageContentCommitment, "content_commitment"},
+	{x509.KeyUsageKeyEncipherment, "key_encipherment"},
+	{x509.KeyUsageDataEncipherment, "data_encipherment"},
+	{x509.KeyUsageKeyAgreement, "key_agreement"},
+	{x509.KeyUsageCertSign, "cert_sign"},
+	{x509.KeyUsageCRLSign, "crl_sign"},
+	{x509.KeyUsageEncipherOnly, "encipher_only"},
+	{x509.KeyUsageDecipherOnly, "decipher_only"},
+}
+
+// extKeyUsageNames maps extended key usages to readable names.
+var extKeyUsageNames = map[x509.ExtKeyUsage]string{
+	x509.ExtKeyUsageAny:             "any",
+	x509.ExtKeyUsageServerAuth:      "server_auth",
+	x509.ExtKeyUsageClientAuth:      "client_auth",
+	x509.ExtKeyUsageCodeSigning:     "code_signing",
+	x509.ExtKeyUsageEmailProtection: "email_protection",
+	x509.ExtKeyUsageTimeStamping:    "time_stamping",
+	x509.ExtKeyUsageOCSPSigning:     "ocsp_signing",
+}
+
+// certAddress returns the `host:port` to dial and the host name to send via
+// SNI. The input may be a host, `host:port`, or a full URL.
+func certAddress(uri string) (string, string, error) {
+	if strings.Contains(uri, "://") {
+		parsed, err := url.Parse(uri)
+		if err != nil {
+			return "", "", err
+		}
+		uri = parsed.Host
+	} else if i := strings.IndexByte(uri, '/'); i != -1 {
+		uri = uri[:i]
+	}
+
+	host, port, err := net.SplitHostPort(uri)
+	if err != nil {
+		// No port was given, so use the HTTPS default.
+		host = strings.Trim(uri, "[]")
+		port = "443"
+	}
+
+	if host == "" {
+		return "", "", fmt.Errorf("no host found in %s", uri)
+	}
+
+	return net.JoinHostPort(host, port), host, nil
+}
+
+// fetchCertChain connects to the address and returns the server's
+// certificate chain, leaf first. The verified chain is used when available,
+// otherwise the certificates as sent by the server, e.g. with `rsh-insecure`.
+func fetchCertChain(uri string) ([]*x509.Certificate, error) {
+	addr, host, err := certAddress(uri)
+	if err != nil {
+		return nil, err
+	}
+
+	config := &tls.Config{
+		ServerName:         host,
+		InsecureSkipVerify: viper.GetBool("rsh-insecure"),
+	}
+
+	if caCert := viper.GetString("rsh-ca-cert"); caCert != "" {
+		data, err := ioutil.ReadFile(caCert)
+		if err != nil {
+			return nil, err
+		}
+		pool := BestEffortSystemCertPool()
+		if !pool.AppendCertsFromPEM(data) {
+			return nil, fmt.Errorf("Failed to append CACert %s RootCA list", caCert)
+		}
+		config.RootCAs = pool
+	}
+
+	conn, err := tls.DialWithDialer(&net.Dialer{Timeout: 30 * time.Second}, "tcp", addr, config)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	state := conn.ConnectionState()
+	if len(state.VerifiedChains) > 0 && len(state.VerifiedChains[0]) > 0 {
+		return state.VerifiedChains[0], nil
+	}
+
+	if len(state.PeerCertificates) == 0 {
+		return nil, fmt.Errorf("no certificates returned by %s", addr)
+	}
+
+	return state.PeerCertificates, nil
+}
+
+// daysUntil returns the fractional number of days until the given time, which
+// is negative if it is in the past.
+func daysUntil(t time.Time) float64 {
+	return time.Until(t).Hours() / 24
+}
+
+// checkCertExpiry sets a non-zero exit code if the certificate expires within
+// the given number of days, e.g. for monitoring scripts.
+func checkCertExpiry(c *x509.Certificate, threshold int) {
+	days := daysUntil(c.NotAfter)
+	if days >= float64(threshold) {
+		return
+	}
+
+	if days < 0 {
+		LogError("Certificate %s expired %.1f days ago", c.Subject.CommonName, -days)
+	} else {
+		LogError("Certificate %s expires in %.1f days, within %d days", c.Subject.CommonName, days, threshold)
+	}
+	exitCode = 1
+}
+
+// certInfo returns structured information about a certificate.
+func certInfo(c *x509.Certificate) map[string]interface{} {
+	keyUsage := []string{}
+	for _, ku := range keyUsageNames {
+		if c.KeyUsage&ku.usage != 0 {
+			keyUsage = append(keyUsage, ku.name)
+		}
+	}
+
+	extKeyUsage := []string{}
+	for _, eku := range c.ExtKeyUsage {
+		if name, ok := extKeyUsageNames[eku]; ok {
+			extKeyUsage = append(extKeyUsage, name)
+		} else {
+			extKeyUsage = append(extKeyUsage, fmt.Sprintf("unknown_%d", eku))
+		}
+	}
+
+	ips := []string{}
+	for _, ip := range c.IPAddresses {
+		ips = append(ips, ip.String())
+	}
+
+	return map[string]interface{}{
+		"subject":             c.Subject.String(),
+		"issuer":              c.Issuer.String(),
+		"serial":              hex.EncodeToString(c.SerialNumber.Bytes()),
+		"not_before":          c.NotBefore.UTC().Format(time.RFC3339),
+		"not_after":           c.NotAfter.UTC().Format(time.RFC3339),
+		"expires_in_days":     math.Round(daysUntil(c.NotAfter)*10) / 10,
+		"signature_algorithm": c.SignatureAlgorithm.String(),
+		"is_ca":               c.IsCA,
+		"dns_names":           append([]string{}, c.DNSNames...),
+		"ip_addresses":        ips,
+		"email_addresses":     append([]string{}, c.EmailAddresses...),
+		"key_usage":           keyUsage,
+		"ext_key_usage":       extKeyUsage,
+	}
+}
+
+// ocspStatusNames maps OCSP response statuses to readable names.
+var ocspStatusNames = map[int]string{
+	ocsp.Good:    "good",
+	ocsp.Revoked: "revoked",
+	ocsp.Unknown: "unknown",
+}
+
+// checkOCSP asks the certificate's OCSP responder, as listed in its authority
+// information access extension, whether it has been revoked.
+func checkOCSP(c, issuer *x509.Certificate) (map[string]interface{}, error) {
+	if len(c.OCSPServer) == 0 {
+		return map[string]interface{}{
+			"status": "unavailable",
+			"error":  "no OCSP responder available",
+		}, nil
+	}
+
+	if issuer == nil {
+		return nil, fmt.Errorf("issuer certificate required for OCSP check was not sent by the server")
+	}
+
+	ocspReq, err := ocsp.CreateRequest(c, issuer, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	responder := c.OCSPServer[0]
+	client := &http.Client{Timeout: 10 * time.Second}
+	resp, err := client.Post(responder, "application/ocsp-request", bytes.NewReader(ocspReq))
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("OCSP responder %s returned %s", responder, resp.Status)
+	}
+
+	data, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	parsed, err := ocsp.ParseResponseForCert(data, c, issuer)
+	if err != nil {
+		return nil, err
+	}
+
+	info := map[string]interface{}{
+		"status":      ocspStatusNames[parsed.Status],
+		"responder":   responder,
+		"this_update": parsed.ThisUpdate.UTC().Format(time.RFC3339),
+	}
+
+	if !parsed.NextUpdate.IsZero() {
+		info["next_update"] = parsed.NextUpdate.UTC().Format(time.RFC3339)
+	}
+
+	if parsed.Status == ocsp.Revoked {
+		info["revoked_at"] = parsed.RevokedAt.UTC().Format(time.RFC3339)
+		info["revocation_reason"] = parsed.RevocationReason
+	}
+
+	return info, nil
+}
+
+// showCert prints the certificate for a server. With `-o json` the whole
+// chain is printed as structured data, otherwise a summary of the leaf. If
+// `checkRevocation` is set then the leaf's OCSP status is included. If
+// `expiryDays` is not negative then a leaf expiring within that many days
+// sets a non-zero exit code.
+func showCert(uri string, checkRevocation bool, expiryDays int) error {
+	chain, err := fetchCertChain(uri)
+	if err != nil {
+		return err
+	}
+
+	var revocation map[string]interface{}
+	if checkRevocation {
+		var issuer *x509.Certificate
+		if len(chain) > 1 {
+			issuer = chain[1]
+		}
+
+		if revocation, err = checkOCSP(chain[0], issuer); err != nil {
+			return fmt.Errorf("OCSP check failed: %w", err)
+		}
+	}
+
+	if expiryDays >= 0 {
+		defer checkCertExpiry(chain[0], expiryDays)
+	}
+
+	if viper.GetString("rsh-output-format") == "json" {
+		output := []interface{}{}
+		for i, c := range chain {
+			info := certInfo(c)
+			if i == 0 && revocation != nil {
+				info["ocsp"] = revocation
+			}
+			output = append(output, info)
+		}
+
+		encoded, err := json.MarshalIndent(output, "", "  ")
+		if err != nil {
+			return err
+		}
+
+		if tty {
+			if encoded, err = Highlight("json", encoded); err != nil {
+				return err
+			}
+		}
+
+		fmt.Fprintln(Stdout, string(encoded))
+		return nil
+	}
+
+	// The first cert in the chain should represent the domain.
+	c := chain[0]
+
+	expiresRelative := ""
+	days := daysUntil(c.NotAfter)
+	if days > 0 {
+		expiresRelative = fmt.Sprintf("in %.1f days", days)
+	} else {
+		expiresRelative = fmt.Sprintf("%.1f days ago", -days)
+	}
+
+	info := fmt.Sprintf(`Issuer: %s
+Subject: %s
+Signature Algorithm: %s
+Not before: %s
+Not after (expires): %s (%s)
+`, c.Issuer.String(), c.Subject.String(), c.SignatureAlgorithm.String(), c.NotBefore.String(), c.NotAfter.String(), expiresRelative)
+
+	if len(c.DNSNames) > 0 {
+		info += "DNS names:\n  " + strings.Join(c.DNSNames, "\n  ") + "\n"
+	}
+
+	if revocation != nil {
+		if msg, ok := revocation["error"]; ok {
+			info += fmt.Sprintf("OCSP status: %s\n", msg)
+		} else {
+			info += fmt.Sprintf("OCSP status: %s\n", revocation["status"])
+			if at, ok := revocation["revoked_at"]; ok {
+				info += fmt.Sprintf("Revoked at: %s (reason %v)\n", at, revocation["revocation_reason"])
+			}
+			info += fmt.Sprintf("OCSP this update: %s\n", revocation["this_update"])
+			if next, ok := revocation["next_update"]; ok {
+				info += fmt.Sprintf("OCSP next update: %s\n", next)
+			}
+		}
+	}
+
+	fmt.Fprint(Stdout, info)
+	return nil
+}
@@ -0,0 +1,150 @@
+package cli
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/json"
+	"io/ioutil"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/crypto/ocsp"
+)
+
+func TestCertAddress(t *testing.T) {
+	addr, host, err := certAddress("example.com")
+	assert.NoError(t, err)
+	assert.Equal(t, "example.com:443", addr)
+	assert.Equal(t, "example.com", host)
+
+	addr, host, err = certAddress("example.com:8443")
+	assert.NoError(t, err)
+	assert.Equal(t, "example.com:8443", addr)
+	assert.Equal(t, "example.com", host)
+
+	addr, host, err = certAddress("https://example.com:8443/foo")
+	assert.NoError(t, err)
+	assert.Equal(t, "example.com:8443", addr)
+	assert.Equal(t, "example.com", host)
+
+	addr, _, err = certAddress("example.com/foo")
+	assert.NoError(t, err)
+	assert.Equal(t, "example.com:443", addr)
+}
+
+func TestCertChainJSON(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+
+	// The test server's cert is self-signed, so it only works when insecure.
+	out := run("cert " + server.URL)
+	assert.Contains(t, out, "certificate")
+
+	out = run("cert " + strings.TrimPrefix(server.URL, "https://") + " -o json --rsh-insecure")
+
+	var chain []map[string]interface{}
+	assert.NoError(t, json.Unmarshal([]byte(out), &chain))
+	assert.NotEmpty(t, chain)
+	assert.Contains(t, chain[0]["dns_names"], "example.com")
+	assert.Contains(t, chain[0]["ip_addresses"], "127.0.0.1")
+	assert.Contains(t, chain[0]["ext_key_usage"], "server_auth")
+	assert.NotEmpty(t, chain[0]["serial"])
+}
+
+func TestCertOCSP(t *testing.T) {
+	caKey, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	caTemplate := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "Test CA"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign,
+	}
+	caDER, err := x509.CreateCertificate(rand.Reader, caTemplate, caTemplate, &caKey.PublicKey, caKey)
+	assert.NoError(t, err)
+	ca, _ := x509.ParseCertificate(caDER)
+
+	status := ocsp.Good
+	responder := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		req, err := ocsp.ParseRequest(body)
+		assert.NoError(t, err)
+
+		resp, err := ocsp.CreateResponse(ca, ca, ocsp.Response{
+			Status:       status,
+			SerialNumber: req.SerialNumber,
+			ThisUpdate:   time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC),
+			NextUpdate:   time.Date(2021, 1, 8, 0, 0, 0, 0, time.UTC),
+			RevokedAt:    time.Date(2020, 12, 1, 0, 0, 0, 0, time.UTC),
+		}, caKey)
+		assert.NoError(t, err)
+		w.Write(resp)
+	}))
+	defer responder.Close()
+
+	leafKey, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	leafDER, err := x509.CreateCertificate(rand.Reader, &x509.Certificate{
+		SerialNumber: big.NewInt(2),
+		Subject:      pkix.Name{CommonName: "example.com"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		OCSPServer:   []string{responder.URL},
+	}, ca, &leafKey.PublicKey, caKey)
+	assert.NoError(t, err)
+	leaf, _ := x509.ParseCertificate(leafDER)
+
+	info, err := checkOCSP(leaf, ca)
+	assert.NoError(t, err)
+	assert.Equal(t, "good", info["status"])
+	assert.Equal(t, "2021-01-01T00:00:00Z", info["this_update"])
+	assert.Equal(t, "2021-01-08T00:00:00Z", info["next_update"])
+
+	status = ocsp.Revoked
+	info, err = checkOCSP(leaf, ca)
+	assert.NoError(t, err)
+	assert.Equal(t, "revoked", info["status"])
+	assert.Equal(t, "2020-12-01T00:00:00Z", info["revoked_at"])
+
+	// Without a responder there is nothing to check.
+	info, err = checkOCSP(ca, ca)
+	assert.NoError(t, err)
+	assert.Equal(t, "no OCSP responder available", info["error"])
+}
+
+func TestCertExpiryDays(t *testing.T) {
+	defer func() { exitCode = 0 }()
+
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+
+	exitCode = 0
+	run("cert " + server.URL + " --rsh-insecure --expiry-days 30")
+	assert.Equal(t, 0, exitCode)
+
+	// The test server's cert is valid for decades, but not this long. The
+	// exit goes through the hook rather than ending the tests.
+	reset(false)
+	exited := 0
+	osExit = func(code int) { exited = code }
+	captured := &strings.Builder{}
+	Stdout = captured
+	Stderr = captured
+	os.Args = strings.Split("restish cert "+server.URL+" --rsh-insecure -o json --expiry-days 100000", " ")
+	Run()
+
+	assert.Equal(t, 1, exitCode)
+	assert.Equal(t, 1, exited)
+	assert.Contains(t, captured.String(), "expires in")
+	assert.Contains(t, captured.String(), "within 100000 days")
+}
@@ -1,8 +1,8 @@
 package cli
 
 import (
-	"crypto/tls"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"io/ioutil"
@@ -12,6 +12,7 @@ import (
 	"path/filepath"
 	"runtime"
 	"runtime/debug"
+	"strconv"
 	"strings"
 	"time"
 
@@ -56,14 +57,17 @@ Aliases:
 Examples:
 {{.Example}}{{end}}{{if (not .Parent)}}{{if (gt (len .Commands) 9)}}
 
-Available API Commands:{{range .Commands}}{{if (not (or (eq .Name "help") (eq .Name "get") (eq .Name "put") (eq .Name "post") (eq .Name "patch") (eq .Name "delete") (eq .Name "head") (eq .Name "options") (eq .Name "cert") (eq .Name "api") (eq .Name "links")))}}
+Available API Commands:{{range .Commands}}{{if (not (or (eq .Name "help") (eq .Name "get") (eq .Name "put") (eq .Name "post") (eq .Name "patch") (eq .Name "delete") (eq .Name "head") (eq .Name "options") (eq .Name "cache") (eq .Name "cert") (eq .Name "curl-import") (eq .Name "doctor") (eq .Name "self") (eq .Name "version") (eq .Name "api") (eq .Name "links") (eq .Name "watch") (eq .Name "diff") (eq .Name "history") (eq .Name "bench")))}}
   {{rpad .Name .NamePadding }} {{.Short}}{{end}}{{end}}{{end}}{{if .HasAvailableSubCommands}}
 
-Generic Commands:{{range .Commands}}{{if (or (eq .Name "help") (eq .Name "get") (eq .Name "put") (eq .Name "post") (eq .Name "patch") (eq .Name "delete") (eq .Name "head") (eq .Name "options") (eq .Name "cert") (eq .Name "api") (eq .Name "links"))}}
-  {{rpad .Name .NamePadding }} {{.Short}}{{end}}{{end}}{{end}}{{else}}{{if .HasAvailableSubCommands}}
+Generic Commands:{{range .Commands}}{{if (or (eq .Name "help") (eq .Name "get") (eq .Name "put") (eq .Name "post") (eq .Name "patch") (eq .Name "delete") (eq .Name "head") (eq .Name "options") (eq .Name "cache") (eq .Name "cert") (eq .Name "curl-import") (eq .Name "doctor") (eq .Name "self") (eq .Name "version") (eq .Name "api") (eq .Name "links") (eq .Name "watch") (eq .Name "diff") (eq .Name "history") (eq .Name "bench"))}}
+  {{rpad .Name .NamePadding }} {{.Short}}{{end}}{{end}}{{end}}{{else}}{{if .HasAvailableSubCommands}}{{$groups := commandGroups .}}{{if $groups}}{{range $groups}}
+
+{{.Name}} Commands:{{range .Commands}}
+  {{rpad .Name .NamePadding }} {{.Short}}{{end}}{{end}}{{else}}
 
 Available Commands:{{range .Commands}}{{if (or .IsAvailableCommand (eq .Name "help"))}}
-  {{rpad .Name .NamePadding }} {{.Short}}{{end}}{{end}}{{end}}{{end}}{{if .HasAvailableLocalFlags}}
+  {{rpad .Name .NamePadding }} {{.Short}}{{end}}{{end}}{{end}}{{end}}{{end}}{{if .HasAvailableLocalFlags}}
 
 Flags:
 {{.LocalFlags.FlagUsages | trimTrailingWhitespaces}}{{end}}{{if .HasAvailableInheritedFlags}}
@@ -80,23 +84,94 @@ Use "{{.CommandPath}} [command] --help" for more information about a command.{{e
 var tty bool
 var au aurora.Aurora
 
-func generic(method string, addr string, args []string) {
+// formContentType is used for request bodies when `rsh-form` is set.
+const formContentType = "application/x-www-form-urlencoded"
+
+// customContentType returns the content type set via a custom header, if any.
+func customContentType() string {
+	for _, h := range viper.GetStringSlice("rsh-header") {
+		parts := strings.SplitN(h, ":", 2)
+		if len(parts) > 1 && strings.EqualFold(strings.TrimSpace(parts[0]), "content-type") {
+			return strings.TrimSpace(parts[1])
+		}
+	}
+
+	return ""
+}
+
+// newGenericRequest creates a request for one of the generic commands, with
+// the body built from the arguments, files or stdin. Also returns the body
+// as a string.
+func newGenericRequest(method string, addr string, args []string) (*http.Request, string, error) {
 	var body io.Reader
 
-	d, err := GetBody("application/json", args)
+	filename, isFile := bodyFileArg(args)
+
+	ct := customContentType()
+	if ct == "" && isFile {
+		ct = bodyFileContentType(filename)
+	}
+	if ct == "" {
+		ct = "application/json"
+		if viper.GetBool("rsh-form") {
+			ct = formContentType
+		} else if viper.GetBool("rsh-multipart") {
+			ct = multipartContentType()
+		}
+	}
+
+	mediaType := ct
+	if (Protobuf{}).Detect(ct) && len(args) > 0 && !isFile {
+		// Shorthand is encoded using the message type from the API config.
+		var err error
+		if mediaType, err = protobufContentType(fixAddress(addr), ct, true); err != nil {
+			return nil, "", err
+		}
+	}
+
+	d, err := GetBody(mediaType, args)
 	if err != nil {
-		panic(err)
+		return nil, "", err
 	}
 	if len(d) > 0 {
 		body = strings.NewReader(d)
 	}
 
-	req, _ := http.NewRequest(method, fixAddress(addr), body)
-	MakeRequestAndFormat(req)
+	req, err := http.NewRequest(method, fixAddress(addr), body)
+	if err != nil {
+		return nil, "", err
+	}
+
+	if hasRawBody() && customContentType() == "" {
+		// Raw bytes are not JSON, so don't let the default kick in.
+		req.Header.Set("Content-Type", "application/octet-stream")
+	} else if (isFile || viper.GetBool("rsh-form") || viper.GetBool("rsh-multipart")) && customContentType() == "" {
+		req.Header.Set("Content-Type", ct)
+	}
+
+	return req, d, nil
 }
 
-// Init will set up the CLI.
-func Init(name string, version string) {
+// generic makes a request for one of the generic commands and formats the
+// response.
+func generic(method string, addr string, args []string) error {
+	req, d, err := newGenericRequest(method, addr, args)
+	if err != nil {
+		return err
+	}
+
+	if canEditBodyOnError(d) {
+		return makeRequestWithBodyEdit(req, d)
+	}
+
+	return sendAndFormat(req)
+}
+
+// Setup loads the configuration and resets the registries without creating
+// any commands, so that the request pipeline can be used as a library via
+// `Client`. Call `Defaults` afterward to register the built-in content
+// types, encodings, link parsers and auth handlers. `Init` calls this.
+func Setup(name string) {
 	initConfig(name, "")
 	initCache(name)
 
@@ -126,8 +201,21 @@ func Init(name string, version string) {
 
 	au = aurora.NewAurora(tty)
 
+	if err := loadTheme(); err != nil {
+		LogWarning("Unable to load theme: %v", err)
+	}
+
 	Formatter = NewDefaultFormatter(tty)
 
+	viper.SetDefault("rsh-profile", "default")
+	loadAPIConfigs()
+	resetSharedClients()
+}
+
+// Init will set up the CLI.
+func Init(name string, version string) {
+	Setup(name)
+
 	Root = &cobra.Command{
 		Use:     filepath.Base(os.Args[0]),
 		Long:    "A generic client for REST-ish APIs <https://rest.sh/>",
@@ -138,14 +226,24 @@ func Init(name string, version string) {
   # Specify verb, header, and body shorthand
   $ %s post :8888/users -H authorization:abc123 name: Kari, role: admin`, name, name),
 		Args: cobra.MinimumNArgs(1),
-		PersistentPreRun: func(cmd *cobra.Command, args []string) {
+		PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+			// Arguments have been validated, so any error from here on is a
+			// failed request rather than a usage problem.
+			cmd.SilenceUsage = true
+
 			settings := viper.AllSettings()
 			LogDebug("Configuration: %v", settings)
+			seedRandom()
+
+			return loadFilterFile()
 		},
-		Run: func(cmd *cobra.Command, args []string) {
-			generic(http.MethodGet, args[0], args[1:])
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return generic(http.MethodGet, args[0], args[1:])
 		},
+		// Errors are logged by `Run` instead.
+		SilenceErrors: true,
 	}
+	cobra.AddTemplateFunc("commandGroups", commandGroups)
 	Root.SetUsageTemplate(usageTemplate)
 
 	head := &cobra.Command{
@@ -153,8 +251,8 @@ func Init(name string, version string) {
 		Short: "Head a URI",
 		Long:  "Perform an HTTP HEAD on the given URI",
 		Args:  cobra.MinimumNArgs(1),
-		Run: func(cmd *cobra.Command, args []string) {
-			generic(http.MethodHead, args[0], args[1:])
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return generic(http.MethodHead, args[0], args[1:])
 		},
 	}
 	Root.AddCommand(head)
@@ -164,21 +262,33 @@ func Init(name string, version string) {
 		Short: "Options a URI",
 		Long:  "Perform an HTTP OPTIONS on the given URI",
 		Args:  cobra.MinimumNArgs(1),
-		Run: func(cmd *cobra.Command, args []string) {
-			generic(http.MethodOptions, args[0], args[1:])
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return generic(http.MethodOptions, args[0], args[1:])
 		},
 	}
 	Root.AddCommand(options)
 
+	var parallel bool
+	var getWorkers int
 	get := &cobra.Command{
 		Use:   "get uri",
 		Short: "Get a URI",
-		Long:  "Perform an HTTP GET on the given URI",
-		Args:  cobra.MinimumNArgs(1),
-		Run: func(cmd *cobra.Command, args []string) {
-			generic(http.MethodGet, args[0], args[1:])
+		Long:  "Perform an HTTP GET on the given URI. With `--parallel`, URIs are instead read one per line from stdin and fetched concurrently, printing each result prefixed by its URI.",
+		Args: func(cmd *cobra.Command, args []string) error {
+			if parallel {
+				return cobra.NoArgs(cmd, args)
+			}
+			return cobra.MinimumNArgs(1)(cmd, args)
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if parallel {
+				return getParallel(os.Stdin, getWorkers)
+			}
+			return generic(http.MethodGet, args[0], args[1:])
 		},
 	}
+	get.Flags().BoolVar(&parallel, "parallel", false, "Read URIs from stdin, one per line, and fetch them concurrently")
+	get.Flags().IntVar(&getWorkers, "concurrency", 4, "Number of requests to send at once with --parallel")
 	Root.AddCommand(get)
 
 	post := &cobra.Command{
@@ -186,32 +296,48 @@ func Init(name string, version string) {
 		Short: "Post a URI",
 		Long:  "Perform an HTTP POST on the given URI",
 		Args:  cobra.MinimumNArgs(1),
-		Run: func(cmd *cobra.Command, args []string) {
-			generic(http.MethodPost, args[0], args[1:])
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return generic(http.MethodPost, args[0], args[1:])
 		},
 	}
 	Root.AddCommand(post)
 
+	var putEdit bool
 	put := &cobra.Command{
 		Use:   "put uri [body...]",
 		Short: "Put a URI",
-		Long:  "Perform an HTTP PUT on the given URI",
+		Long:  "Perform an HTTP PUT on the given URI. With `--edit`, the current resource is fetched and opened in your editor first, then the edited result is sent.",
 		Args:  cobra.MinimumNArgs(1),
-		Run: func(cmd *cobra.Command, args []string) {
-			generic(http.MethodPut, args[0], args[1:])
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if putEdit {
+				if len(args) > 1 {
+					return errors.New("body arguments cannot be combined with --edit")
+				}
+				return editResource(http.MethodPut, args[0])
+			}
+			return generic(http.MethodPut, args[0], args[1:])
 		},
 	}
+	put.Flags().BoolVar(&putEdit, "edit", false, "Fetch the resource and edit it in $EDITOR before sending")
 	Root.AddCommand(put)
 
+	var patchEdit bool
 	patch := &cobra.Command{
 		Use:   "patch uri [body...]",
 		Short: "Patch a URI",
-		Long:  "Perform an HTTP PATCH on the given URI",
+		Long:  "Perform an HTTP PATCH on the given URI. With `--edit`, the current resource is fetched and opened in your editor first, then the edited result is sent.",
 		Args:  cobra.MinimumNArgs(1),
-		Run: func(cmd *cobra.Command, args []string) {
-			generic(http.MethodPatch, args[0], args[1:])
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if patchEdit {
+				if len(args) > 1 {
+					return errors.New("body arguments cannot be combined with --edit")
+				}
+				return editResource(http.MethodPatch, args[0])
+			}
+			return generic(http.MethodPatch, args[0], args[1:])
 		},
 	}
+	patch.Flags().BoolVar(&patchEdit, "edit", false, "Fetch the resource and edit it in $EDITOR before sending")
 	Root.AddCommand(patch)
 
 	delete := &cobra.Command{
@@ -219,74 +345,175 @@ func Init(name string, version string) {
 		Short: "Delete a URI",
 		Long:  "Perform an HTTP DELETE on the given URI",
 		Args:  cobra.MinimumNArgs(1),
-		Run: func(cmd *cobra.Command, args []string) {
-			generic(http.MethodDelete, args[0], args[1:])
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return generic(http.MethodDelete, args[0], args[1:])
 		},
 	}
 	Root.AddCommand(delete)
 
+	var checkRevocation bool
+	var expiryDays int
 	cert := &cobra.Command{
 		Use:   "cert uri",
 		Short: "Get cert info",
-		Long:  "Get TLS certificate information including expiration date",
+		Long:  "Get TLS certificate information including expiration date. The URI may include a port, which defaults to 443. Use `-o json` to get the full certificate chain as structured data.",
 		Args:  cobra.ExactArgs(1),
-		Run: func(cmd *cobra.Command, args []string) {
-			addr := args[0]
-
-			if !strings.Contains(addr, ":") {
-				addr += ":443"
+		RunE: func(cmd *cobra.Command, args []string) error {
+			threshold := -1
+			if cmd.Flags().Changed("expiry-days") {
+				threshold = expiryDays
 			}
 
-			conn, err := tls.Dial("tcp", addr, nil)
+			return showCert(args[0], checkRevocation, threshold)
+		},
+	}
+	cert.Flags().BoolVar(&checkRevocation, "ocsp", false, "Check the certificate's revocation status via OCSP")
+	cert.Flags().IntVar(&expiryDays, "expiry-days", 0, "Exit non-zero if the certificate expires within this many days")
+	Root.AddCommand(cert)
+
+	curlImport := &cobra.Command{
+		Use:     "curl-import 'curl ...'",
+		Short:   "Run a pasted curl command",
+		Long:    "Parse a curl command, e.g. copied from API docs, and make the request with restish so the response is highlighted and can be filtered. Supports the common options -X, -H, -d and its variants, --json, -u, -A, -I, -G, -k and --url. Quote the whole command as a single argument.",
+		Example: fmt.Sprintf(`  $ %s curl-import 'curl -X POST https://api.example.com/items -H "Content-Type: application/json" -d "{\"name\": \"test\"}"'`, name),
+		Args:    cobra.MinimumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			req, err := parseCurl(strings.Join(args, " "))
 			if err != nil {
-				panic(err)
+				return err
 			}
 
-			chains := conn.ConnectionState().VerifiedChains
-			if chains != nil && len(chains) > 0 && len(chains[0]) > 0 {
-				// The first cert in the first chain should represent the domain.
-				c := chains[0][0]
-
-				expiresRelative := ""
-				days := c.NotAfter.Sub(time.Now()).Hours() / 24
-				if days > 0 {
-					expiresRelative = fmt.Sprintf("in %.1f days", days)
-				} else {
-					expiresRelative = fmt.Sprintf("%.1f days ago", -days)
-				}
+			return sendAndFormat(req)
+		},
+	}
+	Root.AddCommand(curlImport)
+
+	versionCmd := &cobra.Command{
+		Use:   "version",
+		Short: "Show version and build info",
+		Long:  "Show the version along with build metadata like the commit, build date, Go version, and OS/arch. Use `-o json` for structured output to include in bug reports.",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return showVersion(name, version)
+		},
+	}
+	Root.AddCommand(versionCmd)
 
-				info := fmt.Sprintf(`Issuer: %s
-Subject: %s
-Signature Algorithm: %s
-Not before: %s
-Not after (expires): %s (%s)
-`, c.Issuer.String(), c.Subject.String(), c.SignatureAlgorithm.String(), c.NotBefore.String(), c.NotAfter.String(), expiresRelative)
+	cacheCmd := &cobra.Command{
+		Use:   "cache",
+		Short: "Manage the HTTP response cache",
+	}
+	Root.AddCommand(cacheCmd)
+
+	cacheCmd.AddCommand(&cobra.Command{
+		Use:   "list [url-prefix|api-name]",
+		Short: "List cached responses",
+		Long:  "List cached responses with their sizes and ages, optionally filtered by a URL prefix or API short name.",
+		Args:  cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			prefix := ""
+			if len(args) > 0 {
+				prefix = cachePrefix(args[0])
+			}
 
-				if len(c.DNSNames) > 0 {
-					info += "DNS names:\n  " + strings.Join(c.DNSNames, "\n  ") + "\n"
-				}
+			return listCache(prefix)
+		},
+	})
+
+	cacheCmd.AddCommand(&cobra.Command{
+		Use:   "clear [url-prefix|api-name]",
+		Short: "Remove cached responses",
+		Long:  "Remove cached responses, optionally only those matching a URL prefix or API short name, e.g. when a server has returned stale data.",
+		Args:  cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			prefix := ""
+			if len(args) > 0 {
+				prefix = cachePrefix(args[0])
+			}
 
-				fmt.Print(info)
+			count, err := newResponseCache().Clear(prefix)
+			if err != nil {
+				return err
 			}
+
+			LogInfo("Removed %d cached responses", count)
+			return nil
+		},
+	})
+
+	doctor := &cobra.Command{
+		Use:   "doctor",
+		Short: "Diagnose setup problems",
+		Long:  "Check the environment for common problems, including config file validity, cache directory permissions, trust of the system TLS roots, connectivity to configured APIs, and optional external tools like an editor, pager, and jq. Exits non-zero if any check fails.",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runDoctor()
 		},
 	}
-	Root.AddCommand(cert)
+	Root.AddCommand(doctor)
 
+	self := &cobra.Command{
+		Use:   "self",
+		Short: "Manage this executable",
+	}
+	Root.AddCommand(self)
+
+	var checkOnly bool
+	selfUpdateCmd := &cobra.Command{
+		Use:   "update",
+		Short: "Update to the latest release",
+		Long:  "Check for a newer release and, if one is found, download the executable for this platform, verify its checksum, and atomically replace the running executable with it.",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return selfUpdate(name, version, checkOnly)
+		},
+	}
+	selfUpdateCmd.Flags().BoolVar(&checkOnly, "check-only", false, "Only check whether an update is available")
+	self.AddCommand(selfUpdateCmd)
+
+	var expand []string
+	var resolve bool
+	var workers int
 	linkCmd := &cobra.Command{
 		Use:   "links uri [rel1 rel2...]",
 		Short: "Get link relations from the given URI, with optional filtering",
-		Long:  "Returns a list of resolved references to the link relations after making an HTTP GET request to the given URI. Additional arguments filter down the set of returned relationship names.",
+		Long:  "Returns a list of resolved references to the link relations after making an HTTP GET request to the given URI. Additional arguments filter down the set of returned relationship names. Templated links are marked as such and can be expanded via `--expand key=value`.",
 		Args:  cobra.MinimumNArgs(1),
-		Run: func(cmd *cobra.Command, args []string) {
-			req, _ := http.NewRequest(http.MethodGet, fixAddress(args[0]), nil)
+		RunE: func(cmd *cobra.Command, args []string) error {
+			req, err := http.NewRequest(http.MethodGet, fixAddress(args[0]), nil)
+			if err != nil {
+				return err
+			}
+
 			resp, err := GetParsedResponse(req)
 			if err != nil {
-				panic(err)
+				return err
+			}
+
+			if len(expand) > 0 {
+				values := map[string]string{}
+				for _, e := range expand {
+					parts := strings.SplitN(e, "=", 2)
+					if len(parts) != 2 {
+						return fmt.Errorf("invalid expansion %s, expected key=value", e)
+					}
+					values[parts[0]] = parts[1]
+				}
+
+				for rel, links := range resp.Links {
+					for i, link := range links {
+						if resp.Links[rel][i], err = link.Expand(values); err != nil {
+							return err
+						}
+					}
+				}
 			}
 
 			var output interface{} = resp.Links
 
-			if len(args) > 1 {
+			if resolve {
+				output = resolveLinks(resp.Links, args[1:], workers)
+			} else if len(args) > 1 {
 				tmp := []*Link{}
 				for _, rel := range args[1:] {
 					for _, link := range resp.Links[rel] {
@@ -298,21 +525,112 @@ Not after (expires): %s (%s)
 
 			encoded, err := json.MarshalIndent(output, "", "  ")
 			if err != nil {
-				panic(err)
+				return err
 			}
 
 			if tty {
 				encoded, err = Highlight("json", encoded)
 				if err != nil {
-					panic(err)
+					return err
 				}
 			}
 
 			fmt.Fprintln(Stdout, string(encoded))
+			return nil
 		},
 	}
+	linkCmd.Flags().StringArrayVar(&expand, "expand", nil, "Expand templated links using key=value variables")
+	linkCmd.Flags().BoolVar(&resolve, "resolve", false, "Fetch each linked resource and output a map of rel to body")
+	linkCmd.Flags().IntVar(&workers, "concurrency", 4, "Number of links to fetch at once with --resolve")
 	Root.AddCommand(linkCmd)
 
+	var interval time.Duration
+	var watchCount int
+	watchCmd := &cobra.Command{
+		Use:   "watch uri",
+		Short: "Poll a URI and show changes",
+		Long:  "Repeatedly performs an HTTP GET on the given URI and redraws the formatted response in place, highlighting lines which changed since the previous poll, like the Unix `watch` command. Filter and output flags apply to each response. Press Ctrl-C to stop.",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return watch(fixAddress(args[0]), interval, watchCount)
+		},
+	}
+	watchCmd.Flags().DurationVar(&interval, "interval", 5*time.Second, "Time to wait between polls")
+	watchCmd.Flags().IntVar(&watchCount, "count", 0, "Stop after this many polls, or 0 to poll until interrupted")
+	Root.AddCommand(watchCmd)
+
+	var historyLimit int
+	historyCmd := &cobra.Command{
+		Use:   "history",
+		Short: "List recent requests",
+		Long:  "Lists recently made requests, newest first, when recording is enabled via `rsh-history`. Credentials in headers and query params are redacted. Use `history replay n` to send one again.",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return listHistory(historyLimit)
+		},
+	}
+	historyCmd.Flags().IntVar(&historyLimit, "limit", 20, "Number of requests to show, or 0 for all")
+	historyCmd.AddCommand(&cobra.Command{
+		Use:   "replay n",
+		Short: "Re-send a past request",
+		Long:  "Sends the nth most recent request from the history again, where 1 is the latest. Redacted credentials are replaced by the current profile's auth.",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			n, err := strconv.Atoi(args[0])
+			if err != nil {
+				return fmt.Errorf("invalid history entry %s", args[0])
+			}
+
+			return replayHistory(n)
+		},
+	})
+	historyCmd.AddCommand(&cobra.Command{
+		Use:   "clear",
+		Short: "Remove all recorded requests",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return clearHistory()
+		},
+	})
+	Root.AddCommand(historyCmd)
+
+	var benchMethod string
+	var benchRequests, benchWorkers int
+	var benchDuration time.Duration
+	benchCmd := &cobra.Command{
+		Use:   "bench uri [body...]",
+		Short: "Benchmark a URI",
+		Long:  "Sends a number of requests to the URI across concurrent workers and prints latency percentiles, throughput and the distribution of status codes. Requests go through the full request pipeline, including profiles, auth and body shorthand, but never use the cache. Use `--duration` to send requests for a fixed time instead of a fixed count.",
+		Example: fmt.Sprintf(`  $ %s bench api.example.com/items --requests 500 --concurrency 20
+  $ %s bench api.example.com/items --method post --duration 30s name: test`, name, name),
+		Args: cobra.MinimumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			req, _, err := newGenericRequest(strings.ToUpper(benchMethod), args[0], args[1:])
+			if err != nil {
+				return err
+			}
+
+			return bench(req, benchRequests, benchWorkers, benchDuration)
+		},
+	}
+	benchCmd.Flags().StringVar(&benchMethod, "method", http.MethodGet, "HTTP method to use")
+	benchCmd.Flags().IntVarP(&benchRequests, "requests", "n", 100, "Number of requests to send")
+	benchCmd.Flags().IntVarP(&benchWorkers, "concurrency", "c", 10, "Number of requests to send at once")
+	benchCmd.Flags().DurationVar(&benchDuration, "duration", 0, "Send requests for this long instead of a fixed number, e.g. 30s")
+	Root.AddCommand(benchCmd)
+
+	Root.AddCommand(&cobra.Command{
+		Use:   "diff uri-or-file uri-or-file",
+		Short: "Compare two responses",
+		Long:  "Fetches two URIs, or loads responses saved to files, and prints a structural diff of their bodies. Use `-f` to select the parts to compare and `-o json` for a machine-readable list of changes. Exits non-zero if differences were found.",
+		Example: fmt.Sprintf(`  $ %s diff staging.example.com/items prod.example.com/items
+  $ %s diff api.example.com/items items.json -f "[].id"`, name, name),
+		Args: cobra.ExactArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return diff(args[0], args[1])
+		},
+	})
+
 	GlobalFlags = pflag.NewFlagSet("eager-flags", pflag.ContinueOnError)
 	GlobalFlags.ParseErrorsWhitelist.UnknownFlags = true
 	// GlobalFlags are 'hidden', don't print anything on error
@@ -322,20 +640,78 @@ Not after (expires): %s (%s)
 	GlobalFlags.BoolP("help", "h", false, "")
 
 	AddGlobalFlag("rsh-verbose", "v", "Enable verbose log output", false, false)
-	AddGlobalFlag("rsh-output-format", "o", "Output format [auto, json, yaml]", "auto", false)
+	AddGlobalFlag("rsh-no-env", "", "Ignore config overrides from environment variables like RSH_VERBOSE", false, false)
+	AddGlobalFlag("rsh-show-secrets", "", "Show credentials like the Authorization header in verbose output", false, false)
+	AddGlobalFlag("rsh-dry-run", "", "Print the fully prepared request instead of sending it", false, false)
+	AddGlobalFlag("rsh-curl", "", "Print an equivalent curl command for each request to stderr", false, false)
+	AddGlobalFlag("rsh-user-agent", "", "User-Agent header to send (default restish-VERSION)", "", false)
+	AddGlobalFlag("rsh-unix-socket", "", "Connect to this Unix socket instead of the URL's host, e.g. /var/run/docker.sock", "", false)
+	AddGlobalFlag("rsh-accept", "", "Accept header to send, as media types or aliases like json, yaml, cbor or msgpack", "", false)
+	AddGlobalFlag("rsh-output-format", "o", "Output format [auto, json, ndjson, yaml, toml, csv, template, cbor, msgpack]", "auto", false)
+	AddGlobalFlag("rsh-template", "", "Go template for template output, inline or @filename", "", false)
+	AddGlobalFlag("rsh-output-file", "O", "Write the response body to this file, or a directory like . to derive the filename", "", false)
+	AddGlobalFlag("rsh-show-image", "", "Show images inline using the terminal image protocol (iTerm2, kitty, sixel) or save them to a temp file", false, false)
+	AddGlobalFlag("rsh-binary", "", "Write binary response bodies as raw bytes instead of a hexdump", false, false)
 	AddGlobalFlag("rsh-filter", "f", "Filter / project results using JMESPath Plus", "", false)
+	AddGlobalFlag("rsh-filter-file", "", "Load the filter expression from a file, ignoring lines starting with #", "", false)
 	AddGlobalFlag("rsh-raw", "r", "Output result of query as raw rather than an escaped JSON string or list", false, false)
 	AddGlobalFlag("rsh-server", "s", "Override scheme://server:port for an API", "", false)
 	AddGlobalFlag("rsh-header", "H", "Add custom header", []string{}, true)
 	AddGlobalFlag("rsh-query", "q", "Add custom query param", []string{}, true)
+	AddGlobalFlag("rsh-since", "", "Set the since query param from a duration like 24h or 7d, or an RFC3339 time", "", false)
 	AddGlobalFlag("rsh-no-paginate", "", "Disable auto-pagination", false, false)
+	AddGlobalFlag("rsh-max-pages", "", "Stop auto-pagination after this many pages (default no limit)", 0, false)
 	AddGlobalFlag("rsh-profile", "p", "API auth profile", "default", false)
 	AddGlobalFlag("rsh-no-cache", "", "Disable HTTP cache", false, false)
+	AddGlobalFlag("rsh-cache-ttl", "", "Cache responses for this long, e.g. 1h, ignoring server cache headers", "", false)
 	AddGlobalFlag("rsh-insecure", "", "Disable SSL verification", false, false)
 	AddGlobalFlag("rsh-client-cert", "", "Path to a PEM encoded client certificate", "", false)
 	AddGlobalFlag("rsh-client-key", "", "Path to a PEM encoded private key", "", false)
 	AddGlobalFlag("rsh-ca-cert", "", "Path to a PEM encoded CA cert", "", false)
 	AddGlobalFlag("rsh-table", "t", "Enable table formatted output for array of objects", false, false)
+	AddGlobalFlag("rsh-timeout", "", "Timeout for the request, e.g. 30s (default no timeout)", "", false)
+	AddGlobalFlag("rsh-connect-timeout", "", "Timeout for connecting to the server, e.g. 2s (default 30s)", "", false)
+	AddGlobalFlag("rsh-http-version", "", "HTTP version to use [auto, 1.1, 2]", "auto", false)
+	AddGlobalFlag("rsh-max-idle-conns", "", "Idle connections to keep open per host for reuse (default 16)", 0, false)
+	AddGlobalFlag("rsh-tls-timeout", "", "Timeout for the TLS handshake, e.g. 2s (default 10s)", "", false)
+	AddGlobalFlag("rsh-retry", "", "Number of times to retry on 429 or 5xx responses", 0, false)
+	AddGlobalFlag("rsh-retry-delay", "", "Base delay for exponential backoff between retries", "1s", false)
+	AddGlobalFlag("rsh-optimistic-retries", "", "Number of times to re-fetch and retry an If-Match write that failed with 409/412", 0, false)
+	AddGlobalFlag("rsh-precise-numbers", "", "Preserve the precision of large JSON numbers", false, false)
+	AddGlobalFlag("rsh-warn-dup-keys", "", "Warn about duplicate object keys in JSON responses", false, false)
+	AddGlobalFlag("rsh-unwrap-json", "", "JMESPath selecting string values containing JSON to decode for display", "", false)
+	AddGlobalFlag("rsh-unwrap-json-auto", "", "Decode all string values which contain JSON objects or arrays", false, false)
+	AddGlobalFlag("rsh-inflate", "", "JMESPath selecting base64-encoded gzip values to decompress for display", "", false)
+	AddGlobalFlag("rsh-max-depth", "", "Truncate output nested deeper than this many levels (default no limit)", 0, false)
+	AddGlobalFlag("rsh-repeat", "", "Send the request this many times, e.g. for load testing", 1, false)
+	AddGlobalFlag("rsh-metrics-out", "", "Write repeated request latency/status metrics to this file in Prometheus format", "", false)
+	AddGlobalFlag("rsh-history", "", "Record requests to the history log shown by the history command", false, false)
+	AddGlobalFlag("rsh-stream", "", "Output items of JSON array or NDJSON responses or events as they arrive rather than buffering", false, false)
+	AddGlobalFlag("rsh-seed", "", "Seed for random values like retry jitter, for reproducible runs", 0, false)
+	AddGlobalFlag("rsh-csv-delimiter", "", "Field delimiter for CSV input and output, e.g. ; or \\t", ",", false)
+	AddGlobalFlag("rsh-trace-out", "", "Write detailed request timing events as JSON to this file", "", false)
+	AddGlobalFlag("rsh-theme", "", "Color theme for highlighted output, dark or light", "", false)
+	AddGlobalFlag("rsh-compact", "", "Output JSON on a single line without indentation", false, false)
+	AddGlobalFlag("rsh-sort-keys", "", "Sort object keys, including table columns, for stable output", false, false)
+	AddGlobalFlag("rsh-body-compact-arrays", "", "Keep arrays of scalars on a single line in formatted output", false, false)
+	AddGlobalFlag("rsh-first", "", "Only output the first N items of an array result", 0, false)
+	AddGlobalFlag("rsh-last", "", "Only output the last N items of an array result", 0, false)
+	AddGlobalFlag("rsh-count", "", "Output the number of items in the array result instead of the result", false, false)
+	AddGlobalFlag("rsh-items-path", "", "Dot-separated path to the array of items for --rsh-first, --rsh-last and --rsh-count, e.g. data.items", "", false)
+	AddGlobalFlag("rsh-auto-compress", "", "Gzip-encode request bodies larger than --rsh-auto-compress-min", false, false)
+	AddGlobalFlag("rsh-auto-compress-min", "", "Minimum request body size in bytes for --rsh-auto-compress", 1024, false)
+	AddGlobalFlag("rsh-hide-header", "", "Hide response headers matching this case-insensitive regex from the output", []string{}, true)
+	AddGlobalFlag("rsh-ignore-status", "", "Exit with code 0 even if the response status is 4xx or 5xx", false, false)
+	AddGlobalFlag("rsh-fail-if", "", "Exit non-zero if this JMESPath expression is truthy for the response body", "", false)
+	AddGlobalFlag("rsh-summary", "", "Output a structural summary of the response instead of the full body", false, false)
+	AddGlobalFlag("rsh-summary-depth", "", "Nesting depth for --rsh-summary", 3, false)
+	AddGlobalFlag("rsh-number-format", "", "Thousands separator style for numbers in table and CSV output [en, de, fr, ch]", "", false)
+	AddGlobalFlag("rsh-time-format", "", "Time format for dates in readable output [rfc3339, unix, kitchen, or a Go layout]", "", false)
+	AddGlobalFlag("rsh-form", "", "Send shorthand body arguments as application/x-www-form-urlencoded", false, false)
+	AddGlobalFlag("rsh-body-edit-on-error", "", "Offer to edit and resend the body in $EDITOR if the server rejects it", false, false)
+	AddGlobalFlag("rsh-multipart", "", "Send name=value and name@file body arguments as multipart/form-data", false, false)
+	AddGlobalFlag("rsh-body-hex", "", "Send raw bytes decoded from a hex string as the request body", "", false)
+	AddGlobalFlag("rsh-body-base64", "", "Send raw bytes decoded from a base64 string as the request body", "", false)
 
 	initAPIConfig()
 }
@@ -355,6 +731,27 @@ func cacheDir() string {
 	return path.Join(userHomeDir(), "."+viper.GetString("app-name"))
 }
 
+// noEnvArg returns whether the args disable environment overrides via
+// `--rsh-no-env`. Arguments after `--` are positional and ignored.
+func noEnvArg(args []string) bool {
+	for _, arg := range args {
+		if arg == "--" {
+			break
+		}
+
+		if arg == "--rsh-no-env" {
+			return true
+		}
+
+		if strings.HasPrefix(arg, "--rsh-no-env=") {
+			v, err := strconv.ParseBool(strings.TrimPrefix(arg, "--rsh-no-env="))
+			return err == nil && v
+		}
+	}
+
+	return false
+}
+
 func initConfig(appName, envPrefix string) {
 	// One-time setup to ensure the path exists so we can write files into it
 	// later as needed.
@@ -371,9 +768,13 @@ func initConfig(appName, envPrefix string) {
 
 	// Load configuration from the environment if provided. Flags below get
 	// transformed automatically, e.g. `client-id` -> `PREFIX_CLIENT_ID`.
-	viper.SetEnvPrefix(envPrefix)
-	viper.SetEnvKeyReplacer(strings.NewReplacer("-", "_"))
-	viper.AutomaticEnv()
+	// Flags aren't parsed yet, so check the raw args to see whether this was
+	// disabled via `--rsh-no-env`.
+	if !noEnvArg(os.Args[1:]) {
+		viper.SetEnvPrefix(envPrefix)
+		viper.SetEnvKeyReplacer(strings.NewReplacer("-", "_"))
+		viper.AutomaticEnv()
+	}
 
 	// Save a few things that will be useful elsewhere.
 	viper.Set("app-name", appName)
@@ -404,6 +805,7 @@ func Defaults() {
 	// Register content encodings
 	AddEncoding("gzip", &GzipEncoding{})
 	AddEncoding("br", &BrotliEncoding{})
+	AddEncoding("zstd", &ZstdEncoding{})
 
 	// Register content type marshallers
 	AddContentType("application/cbor", 0.9, &CBOR{})
@@ -411,6 +813,14 @@ func Defaults() {
 	AddContentType("application/ion", 0.6, &Ion{})
 	AddContentType("application/json", 0.5, &JSON{})
 	AddContentType("application/yaml", 0.5, &YAML{})
+	AddContentType("application/x-ndjson", 0.4, &NDJSON{})
+	AddContentType("application/toml", 0.4, &TOML{})
+	AddContentType("text/toml", 0.4, &TOML{})
+	AddContentType("application/xml", 0.3, &XML{})
+	AddContentType("text/xml", 0.3, &XML{})
+	AddContentType("text/csv", 0.3, &CSV{})
+	AddContentType("multipart/mixed", 0.2, &Multipart{})
+	AddContentType("application/x-protobuf", 0.1, &Protobuf{})
 	AddContentType("text/*", 0.2, &Text{})
 
 	// Add link relation parsers
@@ -418,12 +828,22 @@ func Defaults() {
 	AddLinkParser(&HALParser{})
 	AddLinkParser(&TerrificallySimpleJSONParser{})
 	AddLinkParser(&JSONAPIParser{})
+	AddLinkParser(&CollectionJSONParser{})
+	AddLinkParser(&ODataParser{})
 
 	// Register auth schemes
 	AddAuth("http-basic", &BasicAuth{})
 	AddAuth("api-key-header", &ApiKeyHeaderFromShellAuth{})
+	AddAuth("api-key-query", &ApiKeyQueryAuth{})
+	AddAuth("bearer-token", &BearerTokenAuth{})
+	AddAuth("bearer-file", &BearerFileAuth{})
+	AddAuth("k8s-incluster", &K8sInClusterAuth{})
+	AddAuth("http-signature", &HTTPMessageSignatureAuth{})
 }
 
+// osExit exits the process and can be overridden in tests.
+var osExit = os.Exit
+
 // Run the CLI! Parse arguments, make requests, print responses.
 func Run() {
 	// We need to register new commands at runtime based on the selected API
@@ -432,7 +852,12 @@ func Run() {
 	// the input args to find non-option arguments, get the first arg, and
 	// if it isn't from a well-known set try to load that API.
 	args := []string{}
-	for _, arg := range os.Args {
+	for i, arg := range os.Args {
+		if arg == "--" {
+			// Everything after `--` is positional, even if it looks like a flag.
+			args = append(args, os.Args[i+1:]...)
+			break
+		}
 		if !strings.HasPrefix(arg, "-") {
 			args = append(args, arg)
 		}
@@ -467,6 +892,14 @@ func Run() {
 	if headers, _ := GlobalFlags.GetStringSlice("rsh-header"); len(headers) > 0 {
 		viper.Set("rsh-header", headers)
 	}
+	if theme, _ := GlobalFlags.GetString("rsh-theme"); theme != "" {
+		viper.Set("rsh-theme", theme)
+		if err := loadTheme(); err != nil {
+			LogError("%v", err)
+			osExit(1)
+			return
+		}
+	}
 
 	// Now that global flags are parsed we can enable verbose mode if requested.
 	if viper.GetBool("rsh-verbose") {
@@ -483,7 +916,7 @@ func Run() {
 			apiName = args[2]
 		}
 
-		if apiName != "help" && apiName != "head" && apiName != "options" && apiName != "get" && apiName != "post" && apiName != "put" && apiName != "patch" && apiName != "delete" && apiName != "api" && apiName != "links" {
+		if apiName != "help" && apiName != "head" && apiName != "options" && apiName != "get" && apiName != "post" && apiName != "put" && apiName != "patch" && apiName != "delete" && apiName != "api" && apiName != "links" && apiName != "watch" && apiName != "diff" && apiName != "history" && apiName != "bench" {
 			// Try to find the registered config for this API. If not found,
 			// there is no need to do anything since the normal flow will catch
 			// the command being missing and print help.
@@ -506,9 +939,15 @@ func Run() {
 		if err := recover(); err != nil {
 			LogError("Caught error: %v", err)
 			LogDebug("%s", string(debug.Stack()))
+			exitCode = 1
 		}
 	}()
 	if err := Root.Execute(); err != nil {
-		LogError("Error: %v", err)
+		LogError("%v", err)
+		exitCode = 1
+	}
+
+	if exitCode != 0 {
+		osExit(exitCode)
 	}
 }
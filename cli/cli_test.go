@@ -1,6 +1,10 @@
 package cli
 
 import (
+	"bytes"
+	"errors"
+	"io/ioutil"
+	"net/http"
 	"os"
 	"strings"
 	"testing"
@@ -21,6 +25,10 @@ func reset(color bool) {
 
 	Init("test", "1.0.0'")
 	Defaults()
+
+	// Commands which fail set the exit code rather than exiting the tests.
+	osExit = func(code int) {}
+	exitCode = 0
 }
 
 func run(cmd string, color ...bool) string {
@@ -108,3 +116,459 @@ func TestDefaultOutput(t *testing.T) {
 	captured := run("http://example.com/foo", true)
 	assert.Equal(t, "\x1b[38;5;204mHTTP\x1b[0m/\x1b[38;5;172m1.1\x1b[0m \x1b[38;5;172m200\x1b[0m \x1b[38;5;74mOK\x1b[0m\n\x1b[38;5;74mContent-Type\x1b[0m: application/json\n\n\x1b[38;5;247m{\x1b[0m\n  \x1b[38;5;74mhello\x1b[0m\x1b[38;5;247m:\x1b[0m \x1b[38;5;150m\"world\"\x1b[0m\x1b[38;5;247m\n}\x1b[0m\n", captured)
 }
+
+// matchBody matches requests whose body is exactly the expected bytes. Gock's
+// own body matcher only understands text, JSON, XML and form bodies.
+func matchBody(expected []byte) gock.MatchFunc {
+	return func(req *http.Request, _ *gock.Request) (bool, error) {
+		if req.Body == nil {
+			return len(expected) == 0, nil
+		}
+
+		data, err := ioutil.ReadAll(req.Body)
+		if err != nil {
+			return false, err
+		}
+		req.Body = ioutil.NopCloser(bytes.NewReader(data))
+
+		return bytes.Equal(data, expected), nil
+	}
+}
+
+func TestPostRawBody(t *testing.T) {
+	defer gock.Off()
+
+	gock.New("http://example.com").
+		Post("/foo").
+		MatchHeader("Content-Type", "application/octet-stream").
+		AddMatcher(matchBody([]byte("hello"))).
+		Reply(200).
+		JSON(map[string]interface{}{
+			"ok": true,
+		})
+
+	expectJSON(t, "post http://example.com/foo --rsh-body-base64 aGVsbG8=", `{
+		"ok": true
+	}`)
+
+	gock.New("http://example.com").
+		Post("/foo").
+		MatchHeader("Content-Type", "application/octet-stream").
+		AddMatcher(matchBody([]byte("hello"))).
+		Reply(200).
+		JSON(map[string]interface{}{
+			"ok": true,
+		})
+
+	expectJSON(t, "post http://example.com/foo --rsh-body-hex 68656c6c6f", `{
+		"ok": true
+	}`)
+}
+
+func TestPostForm(t *testing.T) {
+	defer gock.Off()
+
+	gock.New("http://example.com").
+		Post("/foo").
+		MatchHeader("Content-Type", "application/x-www-form-urlencoded").
+		BodyString("name=Kari&user%5Bid%5D=1").
+		Reply(200).
+		JSON(map[string]interface{}{
+			"ok": true,
+		})
+
+	expectJSON(t, "post http://example.com/foo --rsh-form name: Kari, user.id: 1", `{
+		"ok": true
+	}`)
+}
+
+func TestHideHeader(t *testing.T) {
+	defer gock.Off()
+
+	gock.New("http://example.com").
+		Get("/foo").
+		Reply(200).
+		SetHeader("X-Amz-Request-Id", "abc").
+		SetHeader("X-Amz-Cf-Pop", "SFO").
+		SetHeader("Etag", "\"v1\"").
+		JSON(map[string]interface{}{"id": 1})
+
+	captured := run("http://example.com/foo --rsh-hide-header ^x-amz-")
+	assert.Contains(t, captured, "Etag: \"v1\"")
+	assert.NotContains(t, captured, "X-Amz")
+}
+
+func TestBinaryHexdump(t *testing.T) {
+	defer gock.Off()
+
+	gock.New("http://example.com").
+		Get("/blob").
+		Times(2).
+		Reply(200).
+		SetHeader("Content-Type", "application/octet-stream").
+		Body(bytes.NewReader([]byte{0x00, 0x01, 'h', 'i', 0xff}))
+
+	captured := run("http://example.com/blob", true)
+	assert.Contains(t, captured, "00000000  00 01 68 69 ff                                    |..hi.|\n")
+
+	captured = run("http://example.com/blob --rsh-binary", true)
+	assert.Equal(t, "\x00\x01hi\xff", captured)
+}
+
+func TestCSVOutput(t *testing.T) {
+	defer gock.Off()
+
+	gock.New("http://example.com").
+		Get("/items").
+		Times(2).
+		Reply(200).
+		JSON([]interface{}{
+			map[string]interface{}{"id": 1, "name": "one"},
+			map[string]interface{}{"id": 2},
+		})
+
+	captured := run("http://example.com/items -o csv -f body")
+	assert.Equal(t, "id,name\n1,one\n2,\n", captured)
+
+	captured = run("http://example.com/items -o csv")
+	assert.Contains(t, captured, "use a filter")
+}
+
+func TestTOMLOutputArray(t *testing.T) {
+	defer gock.Off()
+
+	gock.New("http://example.com").
+		Get("/items").
+		Times(2).
+		Reply(200).
+		JSON([]interface{}{
+			map[string]interface{}{"name": "one"},
+		})
+
+	captured := run("http://example.com/items -o toml -f body")
+	assert.Contains(t, captured, "[[items]]")
+	assert.Contains(t, captured, `name = "one"`)
+
+	captured = run("http://example.com/items -o toml -f body[0].name")
+	assert.Contains(t, captured, "TOML can only encode objects")
+}
+
+func TestBinaryOutput(t *testing.T) {
+	defer gock.Off()
+
+	gock.New("http://example.com").
+		Get("/items").
+		Times(2).
+		Reply(200).
+		JSON(map[string]interface{}{
+			"hello": "world",
+		})
+
+	captured := run("http://example.com/items -o cbor -f body")
+	assert.Equal(t, "\xa1\x65hello\x65world", captured)
+
+	captured = run("http://example.com/items -o msgpack -f body")
+	assert.Equal(t, "\x81\xa5hello\xa5world", captured)
+}
+
+func TestSortKeysTable(t *testing.T) {
+	defer gock.Off()
+
+	gock.New("http://example.com").
+		Get("/items").
+		Reply(200).
+		JSON([]interface{}{
+			map[string]interface{}{"c": 3, "a": 1, "b": 2, "d": 4},
+		})
+
+	captured := run("http://example.com/items -f body --rsh-table --rsh-sort-keys")
+	header := strings.Split(captured, "\n")[0]
+	assert.Equal(t, []string{"a", "b", "c", "d"}, strings.Fields(header))
+}
+
+func TestFilterFile(t *testing.T) {
+	defer gock.Off()
+
+	gock.New("http://example.com").
+		Get("/users").
+		Reply(200).
+		JSON([]interface{}{
+			map[string]interface{}{"id": 1, "active": true},
+			map[string]interface{}{"id": 2, "active": false},
+		})
+
+	f, err := ioutil.TempFile("", "restish-filter")
+	assert.NoError(t, err)
+	defer os.Remove(f.Name())
+
+	f.WriteString("# Active user IDs\nbody[?active]\n  # Just the ID\n  .id\n")
+	f.Close()
+
+	captured := run("http://example.com/users --rsh-filter-file " + f.Name())
+	assert.JSONEq(t, "[1]", captured)
+}
+
+func TestStreamArray(t *testing.T) {
+	defer gock.Off()
+
+	gock.New("http://example.com").Get("/items").Reply(200).JSON([]interface{}{
+		map[string]interface{}{"id": "a", "value": 1},
+		map[string]interface{}{"id": "b", "value": 2},
+	})
+
+	captured := run("http://example.com/items --rsh-stream -f id")
+	assert.JSONEq(t, `["a", "b"]`, captured)
+
+	gock.New("http://example.com").Get("/items").Reply(200).JSON([]interface{}{
+		map[string]interface{}{"id": "a", "value": 1},
+		map[string]interface{}{"id": "b", "value": 2},
+	})
+
+	captured = run("http://example.com/items --rsh-stream -f id -r")
+	assert.Equal(t, "a\nb\n", captured)
+}
+
+func TestStreamFallback(t *testing.T) {
+	defer gock.Off()
+
+	gock.New("http://example.com").Get("/item").Reply(200).JSON(map[string]interface{}{
+		"id": "a",
+	})
+
+	captured := run("http://example.com/item --rsh-stream -o json -f body")
+	assert.JSONEq(t, `{"id": "a"}`, captured)
+}
+
+func TestStreamNDJSON(t *testing.T) {
+	defer gock.Off()
+
+	gock.New("http://example.com").Get("/events").Reply(200).
+		SetHeader("Content-Type", "application/x-ndjson").
+		BodyString("{\"id\": \"a\"}\n\n{\"id\": \"b\"}\n")
+
+	captured := run("http://example.com/events --rsh-stream")
+	assert.Equal(t, "{\"id\":\"a\"}\n{\"id\":\"b\"}\n", captured)
+
+	gock.New("http://example.com").Get("/events").Reply(200).
+		SetHeader("Content-Type", "application/x-ndjson").
+		BodyString("{\"id\": \"a\"}\n{\"id\": \"b\"}\n")
+
+	captured = run("http://example.com/events --rsh-stream -o json -f id")
+	assert.JSONEq(t, `["a", "b"]`, captured)
+}
+
+func TestBufferNDJSON(t *testing.T) {
+	defer gock.Off()
+
+	gock.New("http://example.com").Get("/events").Reply(200).
+		SetHeader("Content-Type", "application/x-ndjson").
+		BodyString("{\"id\": \"a\"}\n{\"id\": \"b\"}\n")
+
+	// Without streaming the items are buffered into an array so the usual
+	// filters and output formats apply.
+	expectJSON(t, "http://example.com/events", `[{"id": "a"}, {"id": "b"}]`)
+
+	gock.New("http://example.com").Get("/events").Reply(200).
+		SetHeader("Content-Type", "application/x-ndjson").
+		BodyString("{\"id\": \"a\"}\n{\"id\": \"b\"}\n")
+
+	captured := run("http://example.com/events -o yaml -f body[1].id")
+	assert.Equal(t, "b\n", captured)
+
+	gock.New("http://example.com").Get("/events").Reply(200).
+		SetHeader("Content-Type", "application/x-ndjson").
+		BodyString("{\"id\": \"a\"}\n{\"id\": \"b\"}\n")
+
+	run("http://example.com/events --rsh-fail-if [0].id=='a'")
+	assert.Equal(t, 1, exitCode)
+}
+
+func TestRepeatedHeaderOutput(t *testing.T) {
+	defer gock.Off()
+
+	gock.New("http://example.com").Get("/foo").Reply(204).AddHeader("Set-Cookie", "a=1").AddHeader("Set-Cookie", "b=2")
+
+	captured := run("http://example.com/foo")
+	assert.Contains(t, captured, "Set-Cookie: a=1\nSet-Cookie: b=2\n")
+}
+
+func TestOptimisticRetry(t *testing.T) {
+	defer gock.Off()
+
+	gock.New("http://example.com").Patch("/items/1").MatchHeader("If-Match", "v1").Reply(http.StatusPreconditionFailed)
+	gock.New("http://example.com").Get("/items/1").Reply(200).SetHeader("ETag", "v2").JSON(map[string]interface{}{
+		"value": 0,
+	})
+	gock.New("http://example.com").Patch("/items/1").MatchHeader("If-Match", "v2").MatchHeader("Content-Type", `^application/merge-patch\+json$`).AddMatcher(matchBody([]byte(`{"value":1}`))).Reply(200).JSON(map[string]interface{}{
+		"value": 1,
+	})
+
+	captured := run("-o json -f body patch http://example.com/items/1 -H If-Match:v1 -H Content-Type:application/merge-patch+json --rsh-optimistic-retries 1 value: 1")
+
+	// The warning is logged to stderr, which is captured along with stdout.
+	lines := strings.SplitN(captured, "\n", 2)
+	assert.Contains(t, lines[0], "WARN: Got 412 for conditional write")
+	assert.JSONEq(t, `{"value": 1}`, lines[1])
+}
+
+func TestEndOfFlags(t *testing.T) {
+	defer gock.Off()
+
+	gock.New("http://example.com").
+		Post("/items").
+		JSON(map[string]interface{}{"name": "-x", "flag": "--rsh-verbose"}).
+		Reply(200)
+
+	captured := run("post http://example.com/items -- name: -x, flag: --rsh-verbose")
+	assert.NotContains(t, captured, "DEBUG")
+	assert.True(t, gock.IsDone())
+
+	// A query param which looks like the `-v` flag is part of the URL.
+	gock.New("http://example.com").
+		Get("/items").
+		MatchParam("-v", "1").
+		Reply(200)
+
+	captured = run("get -- http://example.com/items?-v=1")
+	assert.NotContains(t, captured, "DEBUG")
+	assert.True(t, gock.IsDone())
+}
+
+func TestDryRun(t *testing.T) {
+	defer gock.Off()
+
+	// No request is made, so this mock should remain pending.
+	gock.New("http://example.com").Post("/items").Reply(200)
+
+	captured := run("post http://example.com/items?q=1&token=secret -H Authorization:secret --rsh-dry-run name: test")
+	assert.Contains(t, captured, "POST /items?q=1&token=REDACTED HTTP/1.1\r\nHost: example.com\r\n")
+	assert.Contains(t, captured, "Authorization: REDACTED")
+	assert.Contains(t, captured, "Content-Type: application/json")
+	assert.Contains(t, captured, `"name"`)
+	assert.NotContains(t, captured, "secret")
+	assert.False(t, gock.IsDone())
+}
+
+func TestOptimisticRetryCannotMerge(t *testing.T) {
+	defer gock.Off()
+
+	gock.New("http://example.com").Put("/items/1").MatchHeader("If-Match", "v1").Reply(http.StatusPreconditionFailed)
+
+	captured := run("put http://example.com/items/1 -H If-Match:v1 --rsh-optimistic-retries 1 value: 1")
+	assert.Contains(t, captured, "cannot be re-applied automatically")
+
+	// Plain JSON patches are not assumed to be merge patches.
+	gock.New("http://example.com").Patch("/items/1").MatchHeader("If-Match", "v1").Reply(http.StatusPreconditionFailed)
+
+	captured = run("patch http://example.com/items/1 -H If-Match:v1 --rsh-optimistic-retries 1 value: 1")
+	assert.Contains(t, captured, "a PATCH with application/json; charset=utf-8 cannot be re-applied automatically")
+}
+
+func TestFirstLastFlags(t *testing.T) {
+	defer gock.Off()
+
+	gock.New("http://example.com").
+		Get("/items").
+		Reply(200).
+		JSON([]interface{}{
+			map[string]interface{}{"id": 1},
+			map[string]interface{}{"id": 2},
+			map[string]interface{}{"id": 3},
+		})
+
+	expectJSON(t, "http://example.com/items --rsh-last 1", `[{"id": 3}]`)
+
+	gock.New("http://example.com").
+		Get("/items").
+		Reply(200).
+		JSON(map[string]interface{}{
+			"data": map[string]interface{}{
+				"items": []interface{}{
+					map[string]interface{}{"id": 1},
+					map[string]interface{}{"id": 2},
+					map[string]interface{}{"id": 3},
+				},
+			},
+		})
+
+	expectJSON(t, "http://example.com/items --rsh-first 2 --rsh-items-path data.items", `{"data": {"items": [{"id": 1}, {"id": 2}]}}`)
+}
+
+func TestCount(t *testing.T) {
+	defer gock.Off()
+
+	gock.New("http://example.com").
+		Get("/items").
+		Reply(200).
+		JSON([]interface{}{
+			map[string]interface{}{"id": 1, "active": true},
+			map[string]interface{}{"id": 2, "active": false},
+			map[string]interface{}{"id": 3, "active": true},
+		})
+
+	assert.Equal(t, "3\n", run("http://example.com/items --rsh-count"))
+
+	gock.New("http://example.com").
+		Get("/items").
+		Reply(200).
+		JSON([]interface{}{
+			map[string]interface{}{"id": 1, "active": true},
+			map[string]interface{}{"id": 2, "active": false},
+		})
+
+	assert.Equal(t, "1\n", run("http://example.com/items --rsh-count -f body[?active]"))
+
+	gock.New("http://example.com").
+		Get("/items").
+		Reply(200).
+		JSON(map[string]interface{}{
+			"data": map[string]interface{}{
+				"items": []interface{}{1, 2},
+			},
+		})
+
+	assert.Equal(t, "2\n", run("http://example.com/items --rsh-count --rsh-items-path data.items"))
+}
+
+func TestNoEnvArg(t *testing.T) {
+	assert.True(t, noEnvArg([]string{"get", "--rsh-no-env", "example.com"}))
+	assert.True(t, noEnvArg([]string{"--rsh-no-env=true"}))
+	assert.False(t, noEnvArg([]string{"--rsh-no-env=false"}))
+	assert.False(t, noEnvArg([]string{"get", "--", "--rsh-no-env"}))
+	assert.False(t, noEnvArg([]string{"get", "example.com"}))
+}
+
+func TestNoEnvOverride(t *testing.T) {
+	os.Setenv("RSH_OUTPUT_FORMAT", "yaml")
+	defer os.Unsetenv("RSH_OUTPUT_FORMAT")
+
+	args := os.Args
+	defer func() { os.Args = args }()
+
+	os.Args = []string{"restish", "get", "example.com"}
+	reset(false)
+	assert.Equal(t, "yaml", viper.GetString("rsh-output-format"))
+
+	os.Args = []string{"restish", "--rsh-no-env", "get", "example.com"}
+	reset(false)
+	assert.Equal(t, "auto", viper.GetString("rsh-output-format"))
+}
+
+func TestRequestError(t *testing.T) {
+	defer gock.Off()
+	defer func() { exitCode = 0 }()
+
+	gock.New("http://example.com").Get("/foo").ReplyError(errors.New("connection refused"))
+
+	exitCode = 0
+	captured := run("http://example.com/foo")
+	assert.Equal(t, 1, exitCode)
+	assert.Contains(t, captured, "connection refused")
+	assert.NotContains(t, captured, "Caught error")
+	assert.NotContains(t, captured, "goroutine")
+
+	exitCode = 0
+	captured = run("cert")
+	assert.Equal(t, 1, exitCode)
+	assert.Contains(t, captured, "accepts 1 arg(s), received 0")
+}
@@ -0,0 +1,109 @@
+package cli
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/spf13/viper"
+)
+
+// userAgent returns the `User-Agent` header sent with requests to the API,
+// which is set via `rsh-user-agent` or the API config, falling back to the
+// Restish version.
+func userAgent(config *APIConfig) string {
+	if ua := viper.GetString("rsh-user-agent"); ua != "" {
+		return ua
+	}
+
+	if config != nil && config.UserAgent != "" {
+		return config.UserAgent
+	}
+
+	if Root == nil || Root.Version == "" {
+		return "restish"
+	}
+	return "restish-" + Root.Version
+}
+
+// Client sends requests through the same pipeline as the CLI, applying the
+// configured API profiles and auth, content negotiation, content encodings,
+// caching, retries and auto-pagination, then parsing the response body and
+// links. It lets Go programs use Restish as a library:
+//
+//	cli.Setup("restish")
+//	cli.Defaults()
+//
+//	client := cli.NewClient()
+//	resp, err := client.Get("https://api.example.com/items")
+//	if err != nil {
+//		return err
+//	}
+//	fmt.Println(resp.Status, resp.Body)
+//
+// Settings like `rsh-profile` or `rsh-retry` are read from the global
+// configuration and can be changed via `viper.Set`.
+type Client struct {
+	// HTTPClient overrides the client used to send requests, which otherwise
+	// uses the response cache.
+	HTTPClient *http.Client
+}
+
+// NewClient creates a new client. `Setup` and `Defaults` must be called
+// first so that configuration and content types are loaded.
+func NewClient() *Client {
+	return &Client{}
+}
+
+// Do sends the request and returns the parsed response. Paginated responses
+// are merged together unless `rsh-no-paginate` is set.
+func (c *Client) Do(req *http.Request) (Response, error) {
+	options := []requestOption{}
+	if c.HTTPClient != nil {
+		options = append(options, WithClient(c.HTTPClient))
+	}
+
+	return GetParsedResponse(req, options...)
+}
+
+// Request creates a request, marshalling the body based on the content type
+// if it isn't a string, byte slice or reader. A nil body sends no body.
+func (c *Client) Request(method, uri, contentType string, body interface{}) (Response, error) {
+	var reader io.Reader
+	switch v := body.(type) {
+	case nil:
+	case io.Reader:
+		reader = v
+	case string:
+		reader = strings.NewReader(v)
+	case []byte:
+		reader = bytes.NewReader(v)
+	default:
+		if contentType == "" {
+			contentType = "application/json"
+		}
+
+		data, err := Marshal(contentType, v)
+		if err != nil {
+			return Response{}, err
+		}
+		reader = bytes.NewReader(data)
+	}
+
+	req, err := http.NewRequest(method, fixAddress(uri), reader)
+	if err != nil {
+		return Response{}, err
+	}
+
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+
+	return c.Do(req)
+}
+
+// Get fetches the URI, which may use an API short name like `example/items`.
+func (c *Client) Get(uri string) (Response, error) {
+	return c.Request(http.MethodGet, uri, "", nil)
+}
@@ -0,0 +1,82 @@
+package cli
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/h2non/gock.v1"
+)
+
+func TestClient(t *testing.T) {
+	root := Root
+	defer func() { Root = root }()
+
+	// Use the pipeline without setting up any commands.
+	viper.Reset()
+	viper.Set("nocolor", true)
+	Root = nil
+	Setup("test")
+	Defaults()
+
+	configs["client"] = &APIConfig{
+		Base: "https://client.example.com",
+		Profiles: map[string]*APIProfile{
+			"default": {Headers: map[string]string{"X-Tenant": "acme"}},
+		},
+	}
+	defer delete(configs, "client")
+
+	defer gock.Off()
+	gock.New("https://client.example.com").
+		Get("/items").
+		MatchHeader("X-Tenant", "acme").
+		MatchHeader("User-Agent", "restish").
+		Reply(http.StatusOK).
+		SetHeader("Link", `</items?page=2>; rel="next"`).
+		JSON([]interface{}{1, 2})
+	gock.New("https://client.example.com").
+		Get("/items").
+		MatchParam("page", "2").
+		Reply(http.StatusOK).
+		JSON([]interface{}{3})
+
+	client := NewClient()
+	resp, err := client.Get("client/items")
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusOK, resp.Status)
+	assert.Equal(t, []interface{}{1.0, 2.0, 3.0}, resp.Body)
+
+	gock.New("https://client.example.com").
+		Post("/items").
+		MatchHeader("Content-Type", "application/json").
+		JSON(map[string]interface{}{"name": "test"}).
+		Reply(http.StatusCreated).
+		JSON(map[string]interface{}{"id": "abc"})
+
+	resp, err = client.Request(http.MethodPost, "https://client.example.com/items", "", map[string]interface{}{"name": "test"})
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusCreated, resp.Status)
+	assert.Equal(t, map[string]interface{}{"id": "abc"}, resp.Body)
+}
+
+func TestUserAgent(t *testing.T) {
+	defer gock.Off()
+	reset(false)
+
+	assert.Equal(t, "restish-1.0.0'", userAgent(nil))
+	assert.Equal(t, "partner/2.0", userAgent(&APIConfig{UserAgent: "partner/2.0"}))
+
+	viper.Set("rsh-user-agent", "custom/1.0")
+	assert.Equal(t, "custom/1.0", userAgent(&APIConfig{UserAgent: "partner/2.0"}))
+
+	gock.New("http://example.com").
+		Get("/items").
+		MatchHeader("User-Agent", "^custom/1.0$").
+		Reply(http.StatusOK).
+		JSON(map[string]interface{}{"ok": true})
+
+	captured := run("http://example.com/items --rsh-user-agent custom/1.0 -f body.ok")
+	assert.Equal(t, "true\n", captured)
+}
@@ -0,0 +1,117 @@
+package cli
+
+import (
+	"bytes"
+	"encoding/json"
+	"sort"
+	"strings"
+)
+
+// isScalarArray returns whether the value is a non-empty array containing
+// only scalars like strings, numbers, booleans, and nulls.
+func isScalarArray(v interface{}) bool {
+	items, ok := v.([]interface{})
+	if !ok || len(items) == 0 {
+		return false
+	}
+
+	for _, item := range items {
+		switch item.(type) {
+		case map[string]interface{}, map[interface{}]interface{}, []interface{}:
+			return false
+		}
+	}
+
+	return true
+}
+
+// marshalJSONCompactArrays works like `json.MarshalIndent` with a two space
+// indent, except arrays of scalars are kept on a single line like
+// `["a", "b", "c"]` to reduce vertical sprawl. Arrays of objects are still
+// expanded. Expects the output of `makeJSONSafe`.
+func marshalJSONCompactArrays(v interface{}) ([]byte, error) {
+	buf := &bytes.Buffer{}
+	if err := writeJSONCompactArrays(buf, "", v); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+func writeJSONCompactArrays(buf *bytes.Buffer, indent string, v interface{}) error {
+	switch t := v.(type) {
+	case map[string]interface{}:
+		if len(t) == 0 {
+			buf.WriteString("{}")
+			return nil
+		}
+
+		keys := make([]string, 0, len(t))
+		for k := range t {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+
+		buf.WriteString("{\n")
+		for i, k := range keys {
+			key, err := json.Marshal(k)
+			if err != nil {
+				return err
+			}
+
+			buf.WriteString(indent + "  ")
+			buf.Write(key)
+			buf.WriteString(": ")
+			if err := writeJSONCompactArrays(buf, indent+"  ", t[k]); err != nil {
+				return err
+			}
+
+			if i < len(keys)-1 {
+				buf.WriteByte(',')
+			}
+			buf.WriteByte('\n')
+		}
+		buf.WriteString(indent + "}")
+	case []interface{}:
+		if len(t) == 0 {
+			buf.WriteString("[]")
+			return nil
+		}
+
+		if isScalarArray(t) {
+			items := make([]string, len(t))
+			for i, item := range t {
+				encoded, err := json.Marshal(item)
+				if err != nil {
+					return err
+				}
+				items[i] = string(encoded)
+			}
+
+			buf.WriteString("[" + strings.Join(items, ", ") + "]")
+			return nil
+		}
+
+		buf.WriteString("[\n")
+		for i, item := range t {
+			buf.WriteString(indent + "  ")
+			if err := writeJSONCompactArrays(buf, indent+"  ", item); err != nil {
+				return err
+			}
+
+			if i < len(t)-1 {
+				buf.WriteByte(',')
+			}
+			buf.WriteByte('\n')
+		}
+		buf.WriteString(indent + "]")
+	default:
+		encoded, err := json.Marshal(t)
+		if err != nil {
+			return err
+		}
+		buf.Write(encoded)
+	}
+
+	return nil
+}
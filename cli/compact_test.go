@@ -0,0 +1,49 @@
+package cli
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/h2non/gock.v1"
+)
+
+func TestMarshalJSONCompactArrays(t *testing.T) {
+	data := map[string]interface{}{
+		"tags":  []interface{}{"a", "b", 1.5, true, nil},
+		"empty": []interface{}{},
+		"items": []interface{}{
+			map[string]interface{}{"id": "one", "ids": []interface{}{1.0, 2.0}},
+		},
+		"nested": []interface{}{[]interface{}{"x"}},
+	}
+
+	encoded, err := marshalJSONCompactArrays(data)
+	assert.NoError(t, err)
+	assert.Equal(t, `{
+  "empty": [],
+  "items": [
+    {
+      "id": "one",
+      "ids": [1, 2]
+    }
+  ],
+  "nested": [
+    ["x"]
+  ],
+  "tags": ["a", "b", 1.5, true, null]
+}`, string(encoded))
+}
+
+func TestCompactJSON(t *testing.T) {
+	defer gock.Off()
+
+	gock.New("http://example.com").
+		Get("/items").
+		Reply(200).
+		JSON([]interface{}{
+			map[string]interface{}{"id": "a", "tags": []interface{}{"x"}},
+		})
+
+	captured := run("http://example.com/items -o json -f body --rsh-compact", true)
+	assert.Equal(t, "[{\"id\":\"a\",\"tags\":[\"x\"]}]\n", captured)
+}
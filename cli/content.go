@@ -1,14 +1,18 @@
 package cli
 
 import (
+	"bytes"
 	"encoding/json"
 	"fmt"
+	"io"
 	"reflect"
 	"strings"
 
 	"github.com/amzn/ion-go/ion"
 	"github.com/fxamacker/cbor/v2"
+	"github.com/pelletier/go-toml"
 	"github.com/shamaton/msgpack"
+	"github.com/spf13/viper"
 	"gopkg.in/yaml.v2"
 )
 
@@ -50,10 +54,64 @@ func buildAcceptHeader() string {
 	return strings.Join(accept, ",")
 }
 
+// contentTypeMarshaler is implemented by content types which need the
+// parameters from the full content type to encode, like a protobuf message
+// type.
+type contentTypeMarshaler interface {
+	MarshalContentType(contentType string, value interface{}) ([]byte, error)
+}
+
+// acceptMediaTypes expands a comma-separated list of media types or format
+// aliases like `yaml` into an `Accept` header value. Aliases are the subtypes
+// of the registered content types without any `x-` prefix, e.g. `cbor` for
+// `application/cbor` or `ndjson` for `application/x-ndjson`.
+func acceptMediaTypes(value string) (string, error) {
+	accept := []string{}
+
+	for _, item := range strings.Split(value, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+
+		if strings.Contains(item, "/") {
+			accept = append(accept, item)
+			continue
+		}
+
+		alias := strings.ToLower(item)
+		found := ""
+		aliases := []string{}
+		for _, entry := range contentTypes {
+			parts := strings.SplitN(entry.name, "/", 2)
+			if len(parts) < 2 || parts[1] == "*" {
+				continue
+			}
+
+			subtype := strings.TrimPrefix(parts[1], "x-")
+			aliases = append(aliases, subtype)
+			if found == "" && subtype == alias {
+				found = entry.name
+			}
+		}
+
+		if found == "" {
+			return "", fmt.Errorf("unknown accept format %s, use a media type or one of %s", item, strings.Join(aliases, ", "))
+		}
+
+		accept = append(accept, found)
+	}
+
+	return strings.Join(accept, ","), nil
+}
+
 // Marshal a value to the given content type if possible.
 func Marshal(contentType string, value interface{}) ([]byte, error) {
 	for _, entry := range contentTypes {
 		if entry.ct.Detect(contentType) {
+			if m, ok := entry.ct.(contentTypeMarshaler); ok {
+				return m.MarshalContentType(contentType, value)
+			}
 			return entry.ct.Marshal(value)
 		}
 	}
@@ -61,11 +119,21 @@ func Marshal(contentType string, value interface{}) ([]byte, error) {
 	return nil, fmt.Errorf("cannot marshal %s", contentType)
 }
 
+// contentTypeUnmarshaler is implemented by content types which need the
+// parameters from the full content type header to decode, like a multipart
+// boundary.
+type contentTypeUnmarshaler interface {
+	UnmarshalContentType(contentType string, data []byte, value interface{}) error
+}
+
 // Unmarshal raw data from the given content type into a value.
 func Unmarshal(contentType string, data []byte, value interface{}) error {
 	for _, entry := range contentTypes {
 		if entry.ct.Detect(contentType) {
 			LogDebug("Unmarshalling from %s", entry.name)
+			if u, ok := entry.ct.(contentTypeUnmarshaler); ok {
+				return u.UnmarshalContentType(contentType, data, value)
+			}
 			return entry.ct.Unmarshal(data, value)
 		}
 	}
@@ -147,11 +215,162 @@ func (j JSON) Marshal(value interface{}) ([]byte, error) {
 	return json.Marshal(value)
 }
 
-// Unmarshal the value from encoded JSON.
+// Unmarshal the value from encoded JSON. If `rsh-precise-numbers` is set
+// then numbers are decoded as `json.Number` so that large integers and high
+// precision decimals are not rounded to a `float64`.
 func (j JSON) Unmarshal(data []byte, value interface{}) error {
+	if viper.GetBool("rsh-precise-numbers") {
+		return unmarshalPrecise(data, value)
+	}
+
 	return json.Unmarshal(data, value)
 }
 
+// unmarshalPrecise decodes JSON using `json.Number` for numbers.
+func unmarshalPrecise(data []byte, value interface{}) error {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	if err := dec.Decode(value); err != nil {
+		return err
+	}
+
+	// Match `json.Unmarshal`, which rejects trailing data.
+	if _, err := dec.Token(); err != io.EOF {
+		return fmt.Errorf("invalid character after top-level value")
+	}
+
+	return nil
+}
+
+// DuplicateKeys returns the paths of any duplicate object keys in a JSON
+// document, for example `body.items[1].id`. The standard decoder silently
+// keeps the last value, so this uses a tokenizing decoder to find them.
+func DuplicateKeys(data []byte) []string {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+
+	dups := []string{}
+
+	var walk func(path string) error
+	walk = func(path string) error {
+		token, err := dec.Token()
+		if err != nil {
+			return err
+		}
+
+		switch token {
+		case json.Delim('{'):
+			seen := map[string]bool{}
+			for dec.More() {
+				keyToken, err := dec.Token()
+				if err != nil {
+					return err
+				}
+
+				key, _ := keyToken.(string)
+				keyPath := path + "." + key
+				if seen[key] {
+					dups = append(dups, keyPath)
+				}
+				seen[key] = true
+
+				if err := walk(keyPath); err != nil {
+					return err
+				}
+			}
+
+			// Consume the closing brace.
+			_, err = dec.Token()
+			return err
+		case json.Delim('['):
+			for i := 0; dec.More(); i++ {
+				if err := walk(fmt.Sprintf("%s[%d]", path, i)); err != nil {
+					return err
+				}
+			}
+
+			// Consume the closing bracket.
+			_, err = dec.Token()
+			return err
+		}
+
+		return nil
+	}
+
+	walk("body")
+
+	return dups
+}
+
+// NDJSON describes newline-delimited JSON content types like
+// `application/x-ndjson`, also known as JSON Lines. Each value is decoded into
+// an item of an array. http://ndjson.org/
+type NDJSON struct{}
+
+// Detect if the content type is NDJSON.
+func (n NDJSON) Detect(contentType string) bool {
+	first := strings.Split(contentType, ";")[0]
+	switch first {
+	case "application/x-ndjson", "application/ndjson", "application/jsonl", "application/x-jsonlines", "application/jsonlines":
+		return true
+	}
+
+	return false
+}
+
+// Marshal the value to encoded NDJSON. Arrays are written one item per line.
+func (n NDJSON) Marshal(value interface{}) ([]byte, error) {
+	items, ok := value.([]interface{})
+	if !ok {
+		items = []interface{}{value}
+	}
+
+	buf := &bytes.Buffer{}
+	for _, item := range items {
+		encoded, err := json.Marshal(item)
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(encoded)
+		buf.WriteByte('\n')
+	}
+
+	return buf.Bytes(), nil
+}
+
+// Unmarshal the value from encoded NDJSON into an array of items.
+func (n NDJSON) Unmarshal(data []byte, value interface{}) error {
+	v := reflect.ValueOf(value)
+
+	if v.Kind() != reflect.Ptr {
+		return fmt.Errorf("value must be pointer but found %s", v.Kind())
+	}
+
+	if !v.Elem().CanSet() {
+		return fmt.Errorf("interface value cannot be set")
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(data))
+	if viper.GetBool("rsh-precise-numbers") {
+		dec.UseNumber()
+	}
+
+	items := []interface{}{}
+	for {
+		var item interface{}
+		if err := dec.Decode(&item); err != nil {
+			if err == io.EOF {
+				break
+			}
+			return err
+		}
+		items = append(items, item)
+	}
+
+	v.Elem().Set(reflect.ValueOf(items))
+	return nil
+}
+
 // YAML describes content types like `application/yaml` or
 // `application/foo+yaml`.
 type YAML struct{}
@@ -246,3 +465,59 @@ func (i Ion) Marshal(value interface{}) ([]byte, error) {
 func (i Ion) Unmarshal(data []byte, value interface{}) error {
 	return ion.Unmarshal(data, value)
 }
+
+// TOML describes content types like `application/toml` or `text/toml`.
+// https://toml.io/
+type TOML struct{}
+
+// Detect if the content type is TOML.
+func (t TOML) Detect(contentType string) bool {
+	first := strings.Split(contentType, ";")[0]
+	if first == "application/toml" || first == "text/toml" || strings.HasSuffix(first, "+toml") {
+		return true
+	}
+
+	return false
+}
+
+// Marshal the value to encoded TOML. Only objects can be encoded since a TOML
+// document is always a table.
+func (t TOML) Marshal(value interface{}) ([]byte, error) {
+	m, ok := makeJSONSafe(value).(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("TOML can only encode objects, not %T", value)
+	}
+
+	tree, err := toml.TreeFromMap(m)
+	if err != nil {
+		return nil, err
+	}
+
+	s, err := tree.ToTomlString()
+	if err != nil {
+		return nil, err
+	}
+
+	return []byte(s), nil
+}
+
+// Unmarshal the value from encoded TOML.
+func (t TOML) Unmarshal(data []byte, value interface{}) error {
+	v := reflect.ValueOf(value)
+
+	if v.Kind() != reflect.Ptr {
+		return fmt.Errorf("value must be pointer but found %s", v.Kind())
+	}
+
+	if !v.Elem().CanSet() {
+		return fmt.Errorf("interface value cannot be set")
+	}
+
+	tree, err := toml.LoadBytes(data)
+	if err != nil {
+		return err
+	}
+
+	v.Elem().Set(reflect.ValueOf(tree.ToMap()))
+	return nil
+}
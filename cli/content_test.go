@@ -1,8 +1,11 @@
 package cli
 
 import (
+	"encoding/json"
+	"strings"
 	"testing"
 
+	"github.com/spf13/viper"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -14,10 +17,13 @@ var contentTests = []struct {
 }{
 	{"text", []string{"text/plain", "text/html"}, &Text{}, []byte("hello world")},
 	{"json", []string{"application/json", "foo+json"}, &JSON{}, []byte(`{"hello":"world"}`)},
+	{"ndjson", []string{"application/x-ndjson", "application/jsonl"}, &NDJSON{}, []byte("{\"a\":1}\n{\"b\":2}\n")},
 	{"yaml", []string{"application/yaml", "foo+yaml"}, &YAML{}, []byte("hello: world\n")},
 	{"cbor", []string{"application/cbor", "foo+cbor"}, &CBOR{}, []byte("\xf6")},
 	{"msgpack", []string{"application/msgpack", "application/x-msgpack", "application/vnd.msgpack", "foo+msgpack"}, &MsgPack{}, []byte("\x81\xa5\x68\x65\x6c\x6c\x6f\xa5\x77\x6f\x72\x6c\x64")},
 	{"ion", []string{"application/ion", "foo+ion"}, &Ion{}, []byte("\xe0\x01\x00\xea\x0f")},
+	{"csv", []string{"text/csv", "application/csv"}, &CSV{}, []byte("id,name\n1,\"a, \"\"quoted\"\"\nname\"\n")},
+	{"xml", []string{"application/xml", "text/xml", "foo+xml"}, &XML{}, []byte("<hello>world</hello>")},
 }
 
 func TestContentTypes(parent *testing.T) {
@@ -40,3 +46,176 @@ func TestContentTypes(parent *testing.T) {
 		})
 	}
 }
+
+func TestJSONPreciseNumbers(t *testing.T) {
+	viper.Set("rsh-precise-numbers", true)
+	defer viper.Set("rsh-precise-numbers", false)
+
+	var data interface{}
+	err := JSON{}.Unmarshal([]byte(`{"id": 1234567890123456789, "value": 1.10}`), &data)
+	assert.NoError(t, err)
+	assert.Equal(t, json.Number("1234567890123456789"), data.(map[string]interface{})["id"])
+
+	encoded, err := MarshalReadable(data)
+	assert.NoError(t, err)
+	assert.Equal(t, "{\n  id: 1234567890123456789\n  value: 1.10\n}", string(encoded))
+
+	err = JSON{}.Unmarshal([]byte(`{"id": 1} trailing`), &data)
+	assert.Error(t, err)
+}
+
+func TestJSONDuplicateKeys(t *testing.T) {
+	assert.Empty(t, DuplicateKeys([]byte(`{"id": 1, "items": [{"id": 2}]}`)))
+
+	assert.Equal(t, []string{"body.items[1].name", "body.id"}, DuplicateKeys([]byte(`{
+		"id": 1,
+		"items": [
+			{"name": "a"},
+			{"name": "b", "name": "c"}
+		],
+		"id": 2
+	}`)))
+}
+
+func TestXMLAttributes(t *testing.T) {
+	var data interface{}
+	err := XML{}.Unmarshal([]byte(`<?xml version="1.0"?>
+<items xmlns="urn:example" count="2">
+  <item id="1">One</item>
+  <item id="2"><name>Two</name></item>
+</items>`), &data)
+	assert.NoError(t, err)
+
+	assert.Equal(t, map[string]interface{}{
+		"items": map[string]interface{}{
+			"@count": "2",
+			"item": []interface{}{
+				map[string]interface{}{"@id": "1", "#text": "One"},
+				map[string]interface{}{"@id": "2", "name": "Two"},
+			},
+		},
+	}, data)
+
+	encoded, err := XML{}.Marshal(data)
+	assert.NoError(t, err)
+	assert.Equal(t, `<items count="2"><item id="1">One</item><item id="2"><name>Two</name></item></items>`, string(encoded))
+}
+
+func TestMultipart(t *testing.T) {
+	ct := Multipart{}
+	contentType := "multipart/mixed; boundary=batch"
+	assert.True(t, ct.Detect(contentType))
+	assert.False(t, ct.Detect("multipart/form-data; boundary=batch"))
+
+	body := strings.Join([]string{
+		"--batch",
+		"Content-Type: application/json",
+		"",
+		`{"id": 1}`,
+		"--batch",
+		"Content-Type: application/http",
+		"Content-ID: 2",
+		"",
+		"HTTP/1.1 404 Not Found",
+		"Content-Type: text/plain",
+		"",
+		"missing",
+		"--batch--",
+		"",
+	}, "\r\n")
+
+	var data interface{}
+	assert.NoError(t, Unmarshal(contentType, []byte(body), &data))
+	assert.Equal(t, []interface{}{
+		map[string]interface{}{
+			"headers": map[string]interface{}{"Content-Type": "application/json"},
+			"body":    map[string]interface{}{"id": 1.0},
+		},
+		map[string]interface{}{
+			"headers": map[string]interface{}{"Content-Type": "application/http", "Content-Id": "2"},
+			"body": map[string]interface{}{
+				"status":  404,
+				"headers": map[string]interface{}{"Content-Type": "text/plain"},
+				"body":    "missing",
+			},
+		},
+	}, data)
+
+	assert.Error(t, ct.Unmarshal([]byte(body), &data))
+}
+
+func TestTOML(t *testing.T) {
+	ct := TOML{}
+	assert.True(t, ct.Detect("application/toml"))
+	assert.True(t, ct.Detect("text/toml; charset=utf-8"))
+	assert.False(t, ct.Detect("application/json"))
+
+	var data interface{}
+	err := ct.Unmarshal([]byte("name = \"test\"\n\n[server]\nport = 8080\n"), &data)
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{
+		"name": "test",
+		"server": map[string]interface{}{
+			"port": int64(8080),
+		},
+	}, data)
+
+	encoded, err := ct.Marshal(data)
+	assert.NoError(t, err)
+
+	var decoded interface{}
+	assert.NoError(t, ct.Unmarshal(encoded, &decoded))
+	assert.Equal(t, data, decoded)
+
+	_, err = ct.Marshal([]interface{}{1, 2})
+	assert.Error(t, err)
+}
+
+func TestCSVUnionAndNested(t *testing.T) {
+	encoded, err := CSV{}.Marshal([]interface{}{
+		map[string]interface{}{"id": "a", "tags": []interface{}{"x", "y"}},
+		map[string]interface{}{"id": "b", "owner": map[string]interface{}{"name": "Alice"}},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, "id,owner,tags\na,,\"[\"\"x\"\",\"\"y\"\"]\"\nb,\"{\"\"name\"\":\"\"Alice\"\"}\",\n", string(encoded))
+}
+
+func TestCSVDelimiter(t *testing.T) {
+	viper.Set("rsh-csv-delimiter", "tab")
+	defer viper.Set("rsh-csv-delimiter", ",")
+
+	var data interface{}
+	err := CSV{}.Unmarshal([]byte("id\tname\n1\ttest\n2\n"), &data)
+	assert.NoError(t, err)
+	assert.Equal(t, []interface{}{
+		map[string]interface{}{"id": "1", "name": "test"},
+		map[string]interface{}{"id": "2", "name": ""},
+	}, data)
+
+	encoded, err := CSV{}.Marshal(data)
+	assert.NoError(t, err)
+	assert.Equal(t, "id\tname\n1\ttest\n2\t\n", string(encoded))
+
+	viper.Set("rsh-csv-delimiter", "ab")
+	assert.Error(t, CSV{}.Unmarshal([]byte("id\n1\n"), &data))
+}
+
+func TestAcceptMediaTypes(t *testing.T) {
+	reset(false)
+
+	accept, err := acceptMediaTypes("yaml")
+	assert.NoError(t, err)
+	assert.Equal(t, "application/yaml", accept)
+
+	accept, err = acceptMediaTypes("CBOR, msgpack, ndjson")
+	assert.NoError(t, err)
+	assert.Equal(t, "application/cbor,application/msgpack,application/x-ndjson", accept)
+
+	accept, err = acceptMediaTypes("application/vnd.api+json;q=0.9,json")
+	assert.NoError(t, err)
+	assert.Equal(t, "application/vnd.api+json;q=0.9,application/json", accept)
+
+	_, err = acceptMediaTypes("bogus")
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "yaml")
+}
@@ -0,0 +1,168 @@
+package cli
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/spf13/viper"
+)
+
+// csvDelimiter returns the field delimiter set via `rsh-csv-delimiter`, which
+// defaults to a comma. Use `\t` or `tab` for tab separated values.
+func csvDelimiter() (rune, error) {
+	value := viper.GetString("rsh-csv-delimiter")
+
+	switch value {
+	case "":
+		return ',', nil
+	case `\t`, "tab":
+		return '\t', nil
+	}
+
+	r, size := utf8.DecodeRuneInString(value)
+	if size != len(value) || r == '"' || r == '\r' || r == '\n' {
+		return 0, fmt.Errorf("invalid CSV delimiter %q", value)
+	}
+
+	return r, nil
+}
+
+// CSV describes content types like `text/csv`. Rows are decoded into an array
+// of objects using the header row as keys, so they can be filtered and shown
+// as a table.
+type CSV struct{}
+
+// Detect if the content type is CSV.
+func (c CSV) Detect(contentType string) bool {
+	first := strings.Split(contentType, ";")[0]
+	if first == "text/csv" || first == "application/csv" {
+		return true
+	}
+
+	return false
+}
+
+// Marshal the value to encoded CSV. The value must be an array of objects.
+// The header row is built from the sorted union of all the objects' keys and
+// nested objects or arrays are encoded as JSON within their cell.
+func (c CSV) Marshal(value interface{}) ([]byte, error) {
+	delimiter, err := csvDelimiter()
+	if err != nil {
+		return nil, err
+	}
+
+	rows, ok := makeJSONSafe(value).([]interface{})
+	if !ok {
+		return nil, fmt.Errorf("CSV can only encode arrays of objects, not %T", value)
+	}
+
+	keySet := map[string]bool{}
+	keys := []string{}
+	for _, row := range rows {
+		m, ok := row.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("CSV can only encode arrays of objects, found %T", row)
+		}
+
+		for k := range m {
+			if !keySet[k] {
+				keySet[k] = true
+				keys = append(keys, k)
+			}
+		}
+	}
+	sort.Strings(keys)
+
+	buf := &bytes.Buffer{}
+	w := csv.NewWriter(buf)
+	w.Comma = delimiter
+
+	if len(keys) > 0 {
+		if err := w.Write(keys); err != nil {
+			return nil, err
+		}
+	}
+
+	for _, row := range rows {
+		m := row.(map[string]interface{})
+
+		record := make([]string, len(keys))
+		for i, k := range keys {
+			switch v := m[k].(type) {
+			case nil:
+				// Missing and null values are left empty.
+			case map[string]interface{}, []interface{}:
+				encoded, err := json.Marshal(v)
+				if err != nil {
+					return nil, err
+				}
+				record[i] = string(encoded)
+			default:
+				record[i] = formatCell(v)
+			}
+		}
+
+		if err := w.Write(record); err != nil {
+			return nil, err
+		}
+	}
+
+	w.Flush()
+	if err := w.Error(); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+// Unmarshal the value from encoded CSV. The first row is used as the header.
+func (c CSV) Unmarshal(data []byte, value interface{}) error {
+	v := reflect.ValueOf(value)
+
+	if v.Kind() != reflect.Ptr {
+		return fmt.Errorf("value must be pointer but found %s", v.Kind())
+	}
+
+	if !v.Elem().CanSet() {
+		return fmt.Errorf("interface value cannot be set")
+	}
+
+	delimiter, err := csvDelimiter()
+	if err != nil {
+		return err
+	}
+
+	r := csv.NewReader(bytes.NewReader(data))
+	r.Comma = delimiter
+	r.FieldsPerRecord = -1
+
+	records, err := r.ReadAll()
+	if err != nil {
+		return err
+	}
+
+	rows := []interface{}{}
+	if len(records) > 0 {
+		header := records[0]
+		for _, record := range records[1:] {
+			row := map[string]interface{}{}
+			for i, key := range header {
+				value := ""
+				if i < len(record) {
+					value = record[i]
+				}
+				row[key] = value
+			}
+			rows = append(rows, row)
+		}
+	}
+
+	v.Elem().Set(reflect.ValueOf(rows))
+	return nil
+}
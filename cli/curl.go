@@ -0,0 +1,365 @@
+package cli
+
+import (
+	"encoding/base64"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/spf13/viper"
+)
+
+// curlSkipHeaders are set by curl itself or handled with other options.
+var curlSkipHeaders = map[string]bool{
+	"Accept-Encoding": true,
+	"Content-Length":  true,
+	"Host":            true,
+}
+
+// shellQuote quotes a value for POSIX shells. Values which aren't valid
+// UTF-8 or contain control characters use ANSI-C quoting with escapes.
+func shellQuote(value string) string {
+	binary := !utf8.ValidString(value)
+	if !binary {
+		for _, r := range value {
+			if (r < 0x20 && r != '\n' && r != '\t') || r == 0x7f {
+				binary = true
+				break
+			}
+		}
+	}
+
+	if binary {
+		sb := strings.Builder{}
+		sb.WriteString("$'")
+		for i := 0; i < len(value); i++ {
+			c := value[i]
+			switch {
+			case c == '\'' || c == '\\':
+				sb.WriteByte('\\')
+				sb.WriteByte(c)
+			case c >= 0x20 && c < 0x7f:
+				sb.WriteByte(c)
+			default:
+				fmt.Fprintf(&sb, "\\x%02x", c)
+			}
+		}
+		sb.WriteString("'")
+		return sb.String()
+	}
+
+	return "'" + strings.Replace(value, "'", `'\''`, -1) + "'"
+}
+
+// curlCommand returns an equivalent `curl` command for the prepared request.
+// Credentials are redacted unless `rsh-show-secrets` is set.
+func curlCommand(req *http.Request) (string, error) {
+	args := []string{"curl"}
+
+	switch req.Method {
+	case http.MethodGet:
+		// This is the default.
+	case http.MethodHead:
+		args = append(args, "--head")
+	default:
+		args = append(args, "-X", req.Method)
+	}
+
+	header := redactHeaders(req.Header)
+	names := []string{}
+	for name := range header {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		if curlSkipHeaders[name] {
+			continue
+		}
+		for _, value := range header[name] {
+			args = append(args, "-H", shellQuote(name+": "+value))
+		}
+	}
+
+	if req.Header.Get("Accept-Encoding") != "" {
+		// Let curl negotiate encodings it can decode.
+		args = append(args, "--compressed")
+	}
+
+	if req.Body != nil && req.Body != http.NoBody {
+		if err := bufferBody(req); err != nil {
+			return "", err
+		}
+
+		body, err := req.GetBody()
+		if err != nil {
+			return "", err
+		}
+		data, err := ioutil.ReadAll(body)
+		body.Close()
+		if err != nil {
+			return "", err
+		}
+
+		if len(data) > 0 {
+			args = append(args, "--data-binary", shellQuote(string(data)))
+		}
+	}
+
+	if viper.GetBool("rsh-insecure") {
+		args = append(args, "--insecure")
+	}
+
+	if cert := viper.GetString("rsh-client-cert"); cert != "" {
+		args = append(args, "--cert", shellQuote(cert))
+	}
+
+	if key := viper.GetString("rsh-client-key"); key != "" {
+		args = append(args, "--key", shellQuote(key))
+	}
+
+	if caCert := viper.GetString("rsh-ca-cert"); caCert != "" {
+		args = append(args, "--cacert", shellQuote(caCert))
+	}
+
+	args = append(args, shellQuote(req.URL.String()))
+
+	return strings.Join(args, " "), nil
+}
+
+// splitShellWords splits a command line into words using POSIX-like quoting
+// rules, including line continuations as found in pasted docs.
+func splitShellWords(line string) ([]string, error) {
+	words := []string{}
+	current := strings.Builder{}
+	inWord := false
+
+	for i := 0; i < len(line); i++ {
+		c := line[i]
+		switch {
+		case c == '\\' && i+1 < len(line):
+			i++
+			if line[i] == '\n' || line[i] == '\r' {
+				// Line continuation.
+				continue
+			}
+			current.WriteByte(line[i])
+			inWord = true
+		case c == '\'':
+			end := strings.IndexByte(line[i+1:], '\'')
+			if end == -1 {
+				return nil, fmt.Errorf("unterminated single quote")
+			}
+			current.WriteString(line[i+1 : i+1+end])
+			i += end + 1
+			inWord = true
+		case c == '"':
+			i++
+			for ; i < len(line) && line[i] != '"'; i++ {
+				if line[i] == '\\' && i+1 < len(line) && strings.IndexByte("\"\\$`\n", line[i+1]) != -1 {
+					i++
+				}
+				current.WriteByte(line[i])
+			}
+			if i >= len(line) {
+				return nil, fmt.Errorf("unterminated double quote")
+			}
+			inWord = true
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			if inWord {
+				words = append(words, current.String())
+				current.Reset()
+				inWord = false
+			}
+		default:
+			current.WriteByte(c)
+			inWord = true
+		}
+	}
+
+	if inWord {
+		words = append(words, current.String())
+	}
+
+	return words, nil
+}
+
+// curlIgnoredOptions don't change the request, or are already how restish
+// behaves, e.g. following redirects and decompressing responses.
+var curlIgnoredOptions = map[string]bool{
+	"-s": true, "--silent": true, "-S": true, "--show-error": true,
+	"-L": true, "--location": true, "--compressed": true, "-v": true,
+	"--verbose": true, "-i": true, "--include": true, "-f": true, "--fail": true,
+}
+
+// parseCurl builds a request from a curl command line, supporting the most
+// common options used in API docs.
+func parseCurl(command string) (*http.Request, error) {
+	words, err := splitShellWords(command)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(words) > 0 && words[0] == "curl" {
+		words = words[1:]
+	}
+
+	method := ""
+	uri := ""
+	header := http.Header{}
+	data := []string{}
+	get := false
+
+	for i := 0; i < len(words); i++ {
+		word := words[i]
+
+		if len(word) > 2 && word[0] == '-' && word[1] != '-' && strings.Trim(word[1:], "sSLvifkIG") == "" {
+			// Expand combined boolean short options like `-sSL`.
+			expanded := []string{}
+			for _, c := range word[1:] {
+				expanded = append(expanded, "-"+string(c))
+			}
+			words = append(words[:i], append(expanded, words[i+1:]...)...)
+			word = words[i]
+		}
+
+		// Support `--option=value` as well as `--option value`.
+		value := ""
+		hasValue := false
+		if strings.HasPrefix(word, "--") {
+			if parts := strings.SplitN(word, "=", 2); len(parts) == 2 {
+				word, value, hasValue = parts[0], parts[1], true
+			}
+		} else if strings.HasPrefix(word, "-") && len(word) > 2 && strings.Contains("XHdu", word[1:2]) {
+			// Short options may have the value attached, e.g. `-XPOST`.
+			word, value, hasValue = word[:2], word[2:], true
+		}
+
+		next := func() (string, error) {
+			if hasValue {
+				return value, nil
+			}
+			if i+1 >= len(words) {
+				return "", fmt.Errorf("curl option %s requires a value", word)
+			}
+			i++
+			return words[i], nil
+		}
+
+		switch word {
+		case "-X", "--request":
+			if method, err = next(); err != nil {
+				return nil, err
+			}
+		case "-H", "--header":
+			h, err := next()
+			if err != nil {
+				return nil, err
+			}
+			parts := strings.SplitN(h, ":", 2)
+			if len(parts) != 2 {
+				return nil, fmt.Errorf("invalid curl header %s", h)
+			}
+			header.Add(strings.TrimSpace(parts[0]), strings.TrimSpace(parts[1]))
+		case "-d", "--data", "--data-raw", "--data-binary", "--data-ascii", "--json":
+			d, err := next()
+			if err != nil {
+				return nil, err
+			}
+			if strings.HasPrefix(d, "@") && word != "--data-raw" {
+				contents, err := ioutil.ReadFile(d[1:])
+				if err != nil {
+					return nil, err
+				}
+				d = string(contents)
+				if word != "--data-binary" {
+					// Like curl, strip newlines from files unless sent as binary.
+					d = strings.NewReplacer("\r", "", "\n", "").Replace(d)
+				}
+			}
+			data = append(data, d)
+			if word == "--json" {
+				if header.Get("Content-Type") == "" {
+					header.Set("Content-Type", "application/json")
+				}
+				if header.Get("Accept") == "" {
+					header.Set("Accept", "application/json")
+				}
+			}
+		case "-u", "--user":
+			user, err := next()
+			if err != nil {
+				return nil, err
+			}
+			header.Set("Authorization", "Basic "+base64.StdEncoding.EncodeToString([]byte(user)))
+		case "-A", "--user-agent":
+			ua, err := next()
+			if err != nil {
+				return nil, err
+			}
+			header.Set("User-Agent", ua)
+		case "-I", "--head":
+			method = http.MethodHead
+		case "-G", "--get":
+			get = true
+		case "-k", "--insecure":
+			viper.Set("rsh-insecure", true)
+		case "--url":
+			if uri, err = next(); err != nil {
+				return nil, err
+			}
+		default:
+			if curlIgnoredOptions[word] {
+				continue
+			}
+			if strings.HasPrefix(word, "-") {
+				return nil, fmt.Errorf("unsupported curl option %s", word)
+			}
+			if uri != "" {
+				return nil, fmt.Errorf("only one URL is supported, got %s and %s", uri, word)
+			}
+			uri = word
+		}
+	}
+
+	if uri == "" {
+		return nil, fmt.Errorf("no URL found in curl command")
+	}
+
+	var body io.Reader
+	if len(data) > 0 {
+		joined := strings.Join(data, "&")
+		if get {
+			// Data is sent as query params instead of the body.
+			if strings.Contains(uri, "?") {
+				uri += "&" + joined
+			} else {
+				uri += "?" + joined
+			}
+		} else {
+			body = strings.NewReader(joined)
+			if method == "" {
+				method = http.MethodPost
+			}
+			if header.Get("Content-Type") == "" {
+				header.Set("Content-Type", "application/x-www-form-urlencoded")
+			}
+		}
+	}
+
+	if method == "" {
+		method = http.MethodGet
+	}
+
+	req, err := http.NewRequest(method, fixAddress(uri), body)
+	if err != nil {
+		return nil, err
+	}
+	req.Header = header
+
+	return req, nil
+}
@@ -0,0 +1,99 @@
+package cli
+
+import (
+	"io/ioutil"
+	"net/http"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/h2non/gock.v1"
+)
+
+func TestShellQuote(t *testing.T) {
+	assert.Equal(t, `'hello world'`, shellQuote("hello world"))
+	assert.Equal(t, `'it'\''s'`, shellQuote("it's"))
+	assert.Equal(t, `$'\x00\xff\'a'`, shellQuote("\x00\xff'a"))
+}
+
+func TestCurlCommand(t *testing.T) {
+	viper.Set("rsh-insecure", true)
+	defer viper.Set("rsh-insecure", false)
+
+	req, _ := http.NewRequest(http.MethodPost, "https://example.com/items?q=a", strings.NewReader(`{"name":"it's"}`))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept-Encoding", "gzip")
+	req.Header.Set("Authorization", "Bearer abc123")
+
+	curl, err := curlCommand(req)
+	assert.NoError(t, err)
+	assert.Equal(t, `curl -X POST -H 'Authorization: Bearer REDACTED' -H 'Content-Type: application/json' --compressed --data-binary '{"name":"it'\''s"}' --insecure 'https://example.com/items?q=a'`, curl)
+}
+
+func TestCurlDryRun(t *testing.T) {
+	defer gock.Off()
+
+	gock.New("http://example.com").Get("/items").Reply(200)
+
+	captured := run("http://example.com/items --rsh-curl --rsh-dry-run")
+	assert.True(t, strings.HasPrefix(captured, "curl -H 'Accept: "))
+	assert.Contains(t, captured, "--compressed 'http://example.com/items'\n")
+	assert.False(t, gock.IsDone())
+}
+
+func TestSplitShellWords(t *testing.T) {
+	words, err := splitShellWords("curl 'a b' \"c \\\"d\\\"\" e\\ f \\\n  g")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"curl", "a b", `c "d"`, "e f", "g"}, words)
+
+	_, err = splitShellWords("curl 'oops")
+	assert.Error(t, err)
+}
+
+func TestParseCurl(t *testing.T) {
+	req, err := parseCurl(`curl -sSL -XPUT 'https://example.com/items/1' -H 'Content-Type: application/json' --data '{"name": "test"}' -u user:pass`)
+	assert.NoError(t, err)
+	assert.Equal(t, http.MethodPut, req.Method)
+	assert.Equal(t, "https://example.com/items/1", req.URL.String())
+	assert.Equal(t, "application/json", req.Header.Get("Content-Type"))
+	assert.Equal(t, "Basic dXNlcjpwYXNz", req.Header.Get("Authorization"))
+
+	body, _ := ioutil.ReadAll(req.Body)
+	assert.Equal(t, `{"name": "test"}`, string(body))
+
+	req, err = parseCurl(`curl -G https://example.com/search -d q=test -d page=2`)
+	assert.NoError(t, err)
+	assert.Equal(t, http.MethodGet, req.Method)
+	assert.Equal(t, "https://example.com/search?q=test&page=2", req.URL.String())
+
+	req, err = parseCurl(`curl https://example.com/form -d a=1`)
+	assert.NoError(t, err)
+	assert.Equal(t, http.MethodPost, req.Method)
+	assert.Equal(t, "application/x-www-form-urlencoded", req.Header.Get("Content-Type"))
+
+	_, err = parseCurl(`curl --proxy http://proxy https://example.com`)
+	assert.EqualError(t, err, "unsupported curl option --proxy")
+}
+
+func TestCurlImport(t *testing.T) {
+	defer gock.Off()
+
+	gock.New("http://example.com").
+		Get("/items").
+		MatchHeader("X-Test", "yes").
+		Reply(200).
+		JSON(map[string]interface{}{"id": 1})
+
+	// The command is passed as a single argument, which the `run` helper can't
+	// do since it splits on spaces.
+	reset(false)
+	captured := &strings.Builder{}
+	Stdout = captured
+	Stderr = captured
+	os.Args = []string{"restish", "curl-import", "curl -H 'X-Test: yes' http://example.com/items"}
+	Run()
+
+	assert.Contains(t, captured.String(), "id: 1")
+}
@@ -0,0 +1,61 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+)
+
+// truncated is a placeholder for a structure which was nested deeper than the
+// `rsh-max-depth` limit. It is output as-is in readable mode and as a string
+// in other formats.
+type truncated string
+
+// MarshalJSON encodes the placeholder as a JSON string.
+func (t truncated) MarshalJSON() ([]byte, error) {
+	return json.Marshal(string(t))
+}
+
+// truncateDepth returns a copy of the value where objects and arrays nested
+// more than `depth` levels deep are replaced with a placeholder describing how
+// many keys or items were left out. The original value is not modified.
+func truncateDepth(v interface{}, depth int) interface{} {
+	if _, ok := v.([]byte); ok {
+		// Binary data is displayed as a scalar.
+		return v
+	}
+
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Slice:
+		if rv.Len() == 0 {
+			return v
+		}
+
+		if depth < 1 {
+			return truncated(fmt.Sprintf("[… %d items]", rv.Len()))
+		}
+
+		out := make([]interface{}, rv.Len())
+		for i := 0; i < rv.Len(); i++ {
+			out[i] = truncateDepth(rv.Index(i).Interface(), depth-1)
+		}
+		return out
+	case reflect.Map:
+		if rv.Len() == 0 {
+			return v
+		}
+
+		if depth < 1 {
+			return truncated(fmt.Sprintf("{… %d keys}", rv.Len()))
+		}
+
+		out := make(map[string]interface{}, rv.Len())
+		for _, k := range rv.MapKeys() {
+			out[fmt.Sprintf("%v", k.Interface())] = truncateDepth(rv.MapIndex(k).Interface(), depth-1)
+		}
+		return out
+	}
+
+	return v
+}
@@ -0,0 +1,41 @@
+package cli
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTruncateDepth(t *testing.T) {
+	data := map[string]interface{}{
+		"id": "item1",
+		"owner": map[string]interface{}{
+			"name":  "Alice",
+			"roles": []interface{}{"admin"},
+		},
+		"tags":  []interface{}{"a", "b"},
+		"empty": []interface{}{},
+	}
+
+	out, err := MarshalReadable(truncateDepth(data, 1))
+	assert.NoError(t, err)
+	assert.Equal(t, `{
+  empty: []
+  id: "item1"
+  owner: {… 2 keys}
+  tags: [… 2 items]
+}`, string(out))
+
+	encoded, err := json.Marshal(truncateDepth(data, 2))
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{
+		"empty": [],
+		"id": "item1",
+		"owner": {"name": "Alice", "roles": "[… 1 items]"},
+		"tags": ["a", "b"]
+	}`, string(encoded))
+
+	// The original must be left untouched.
+	assert.Equal(t, []interface{}{"admin"}, data["owner"].(map[string]interface{})["roles"])
+}
@@ -0,0 +1,237 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+
+	jmespath "github.com/danielgtaylor/go-jmespath-plus"
+	"github.com/spf13/viper"
+)
+
+// Difference is a single change between two documents.
+type Difference struct {
+	// Op is one of `add`, `remove` or `change`.
+	Op   string      `json:"op"`
+	Path string      `json:"path"`
+	Old  interface{} `json:"old"`
+	New  interface{} `json:"new"`
+}
+
+// identifier matches object keys which can be used in a path without quotes.
+var identifier = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// joinPath appends an object key to a JMESPath-style path.
+func joinPath(path, key string) string {
+	if !identifier.MatchString(key) {
+		key = strconv.Quote(key)
+	}
+
+	if path == "" {
+		return key
+	}
+
+	return path + "." + key
+}
+
+// diffValues recursively compares two decoded documents, returning the
+// differences in a stable order. Arrays are compared item by item.
+func diffValues(path string, a, b interface{}) []Difference {
+	switch av := a.(type) {
+	case map[string]interface{}:
+		if bv, ok := b.(map[string]interface{}); ok {
+			keys := map[string]bool{}
+			for k := range av {
+				keys[k] = true
+			}
+			for k := range bv {
+				keys[k] = true
+			}
+
+			sorted := make([]string, 0, len(keys))
+			for k := range keys {
+				sorted = append(sorted, k)
+			}
+			sort.Strings(sorted)
+
+			diffs := []Difference{}
+			for _, k := range sorted {
+				va, inA := av[k]
+				vb, inB := bv[k]
+				p := joinPath(path, k)
+
+				switch {
+				case !inA:
+					diffs = append(diffs, Difference{Op: "add", Path: p, New: vb})
+				case !inB:
+					diffs = append(diffs, Difference{Op: "remove", Path: p, Old: va})
+				default:
+					diffs = append(diffs, diffValues(p, va, vb)...)
+				}
+			}
+			return diffs
+		}
+	case []interface{}:
+		if bv, ok := b.([]interface{}); ok {
+			diffs := []Difference{}
+			for i := 0; i < len(av) || i < len(bv); i++ {
+				p := fmt.Sprintf("%s[%d]", path, i)
+
+				switch {
+				case i >= len(av):
+					diffs = append(diffs, Difference{Op: "add", Path: p, New: bv[i]})
+				case i >= len(bv):
+					diffs = append(diffs, Difference{Op: "remove", Path: p, Old: av[i]})
+				default:
+					diffs = append(diffs, diffValues(p, av[i], bv[i])...)
+				}
+			}
+			return diffs
+		}
+	}
+
+	if reflect.DeepEqual(a, b) {
+		return nil
+	}
+
+	if path == "" {
+		path = "@"
+	}
+
+	return []Difference{{Op: "change", Path: path, Old: a, New: b}}
+}
+
+// normalize converts a decoded document into plain JSON types so that the
+// same data decoded from different formats compares as equal.
+func normalize(v interface{}) (interface{}, error) {
+	encoded, err := json.Marshal(makeJSONSafe(v))
+	if err != nil {
+		return nil, err
+	}
+
+	var result interface{}
+	err = json.Unmarshal(encoded, &result)
+	return result, err
+}
+
+// loadDiffSource returns the body of a saved file if one exists at the given
+// path, otherwise it fetches the URI.
+func loadDiffSource(source string) (interface{}, error) {
+	if _, err := os.Stat(source); err == nil {
+		data, err := ioutil.ReadFile(source)
+		if err != nil {
+			return nil, err
+		}
+
+		ct := bodyFileContentType(source)
+		var body interface{}
+		if err := Unmarshal(ct, data, &body); err != nil {
+			// YAML is a superset of JSON so it handles unknown extensions.
+			if err := Unmarshal("application/yaml", data, &body); err != nil {
+				return nil, fmt.Errorf("unable to decode %s: %w", source, err)
+			}
+		}
+		return body, nil
+	}
+
+	req, err := http.NewRequest(http.MethodGet, fixAddress(source), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := GetParsedResponse(req)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.Status >= 400 {
+		return nil, fmt.Errorf("%s returned HTTP %d", source, resp.Status)
+	}
+
+	return resp.Body, nil
+}
+
+// loadDiffBody loads and normalizes a body, applying the filter if set.
+func loadDiffBody(source, filter string) (interface{}, error) {
+	body, err := loadDiffSource(source)
+	if err != nil {
+		return nil, err
+	}
+
+	if body, err = normalize(body); err != nil {
+		return nil, err
+	}
+
+	if filter != "" {
+		if body, err = jmespath.Search(filter, body); err != nil {
+			return nil, err
+		}
+	}
+
+	return body, nil
+}
+
+// formatDiffValue renders a value compactly on a single line.
+func formatDiffValue(v interface{}) string {
+	encoded, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprintf("%v", v)
+	}
+	return string(encoded)
+}
+
+// diff compares the bodies of two URIs or saved files and prints the
+// differences. Sets a non-zero exit code if any were found.
+func diff(a, b string) error {
+	filter := viper.GetString("rsh-filter")
+
+	bodyA, err := loadDiffBody(a, filter)
+	if err != nil {
+		return err
+	}
+
+	bodyB, err := loadDiffBody(b, filter)
+	if err != nil {
+		return err
+	}
+
+	diffs := diffValues("", bodyA, bodyB)
+	if len(diffs) > 0 {
+		exitCode = 1
+	}
+
+	if viper.GetString("rsh-output-format") == "json" {
+		encoded, err := json.MarshalIndent(diffs, "", "  ")
+		if err != nil {
+			return err
+		}
+
+		if tty {
+			if encoded, err = Highlight("json", encoded); err != nil {
+				return err
+			}
+		}
+
+		fmt.Fprintln(Stdout, string(encoded))
+		return nil
+	}
+
+	for _, d := range diffs {
+		switch d.Op {
+		case "add":
+			fmt.Fprintln(Stdout, au.Green(fmt.Sprintf("+ %s: %s", d.Path, formatDiffValue(d.New))))
+		case "remove":
+			fmt.Fprintln(Stdout, au.Red(fmt.Sprintf("- %s: %s", d.Path, formatDiffValue(d.Old))))
+		case "change":
+			fmt.Fprintf(Stdout, "%s %s: %s → %s\n", au.Yellow("~"), d.Path, au.Red(formatDiffValue(d.Old)), au.Green(formatDiffValue(d.New)))
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,78 @@
+package cli
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/h2non/gock.v1"
+)
+
+func TestDiffValues(t *testing.T) {
+	a := map[string]interface{}{
+		"name":    "one",
+		"removed": true,
+		"tags":    []interface{}{"a", "b"},
+		"x-id":    1.0,
+	}
+	b := map[string]interface{}{
+		"name":  "two",
+		"added": false,
+		"tags":  []interface{}{"a"},
+		"x-id":  1.0,
+	}
+
+	assert.Equal(t, []Difference{
+		{Op: "add", Path: "added", New: false},
+		{Op: "change", Path: "name", Old: "one", New: "two"},
+		{Op: "remove", Path: "removed", Old: true},
+		{Op: "remove", Path: "tags[1]", Old: "b"},
+	}, diffValues("", a, b))
+
+	assert.Empty(t, diffValues("", a, a))
+	assert.Equal(t, []Difference{{Op: "change", Path: "@", Old: 1.0, New: "1"}}, diffValues("", 1.0, "1"))
+	assert.Equal(t, `"x-id".value`, joinPath(joinPath("", "x-id"), "value"))
+}
+
+func TestDiffCommand(t *testing.T) {
+	defer gock.Off()
+	defer func() { exitCode = 0 }()
+
+	gock.New("http://example.com").Get("/a").Reply(200).JSON(map[string]interface{}{
+		"id":     1,
+		"status": "running",
+	})
+	gock.New("http://example.com").Get("/b").Reply(200).JSON(map[string]interface{}{
+		"id":     1,
+		"status": "done",
+	})
+
+	captured := run("diff http://example.com/a http://example.com/b")
+	assert.Equal(t, "~ status: \"running\" → \"done\"\n", captured)
+	assert.Equal(t, 1, exitCode)
+}
+
+func TestDiffFile(t *testing.T) {
+	defer gock.Off()
+	defer func() { exitCode = 0 }()
+
+	dir, err := ioutil.TempDir("", "restish-diff")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	saved := filepath.Join(dir, "saved.yaml")
+	assert.NoError(t, ioutil.WriteFile(saved, []byte("items:\n  - id: 1\n  - id: 2\n"), 0600))
+
+	gock.New("http://example.com").Get("/items").Reply(200).JSON(map[string]interface{}{
+		"items": []interface{}{
+			map[string]interface{}{"id": 1, "extra": true},
+			map[string]interface{}{"id": 2},
+		},
+	})
+
+	captured := run("diff http://example.com/items " + saved + " -f items[].id")
+	assert.Equal(t, "", captured)
+	assert.Equal(t, 0, exitCode)
+}
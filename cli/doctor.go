@@ -0,0 +1,200 @@
+package cli
+
+import (
+	"crypto/x509"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"os/exec"
+	"path"
+	"sort"
+	"time"
+
+	"github.com/spf13/viper"
+)
+
+// Doctor check statuses.
+const (
+	doctorPass = "pass"
+	doctorWarn = "warn"
+	doctorFail = "fail"
+)
+
+// doctorCheck is the result of a single environment check.
+type doctorCheck struct {
+	Name    string `json:"name"`
+	Status  string `json:"status"`
+	Message string `json:"message"`
+}
+
+// checkConfigFiles validates the config and API config files can be parsed.
+func checkConfigFiles() []doctorCheck {
+	checks := []doctorCheck{}
+
+	if filename := viper.ConfigFileUsed(); filename != "" {
+		v := viper.New()
+		v.SetConfigFile(filename)
+		if err := v.ReadInConfig(); err != nil {
+			checks = append(checks, doctorCheck{"Config file", doctorFail, fmt.Sprintf("%s: %v", filename, err)})
+		} else {
+			checks = append(checks, doctorCheck{"Config file", doctorPass, filename})
+		}
+	} else {
+		checks = append(checks, doctorCheck{"Config file", doctorPass, "none found, using defaults"})
+	}
+
+	filename := path.Join(viper.GetString("config-directory"), "apis.json")
+	data, err := ioutil.ReadFile(filename)
+	if err == nil {
+		var parsed map[string]*APIConfig
+		err = json.Unmarshal(data, &parsed)
+	}
+	if err != nil {
+		checks = append(checks, doctorCheck{"API config", doctorFail, fmt.Sprintf("%s: %v", filename, err)})
+	} else {
+		checks = append(checks, doctorCheck{"API config", doctorPass, filename})
+	}
+
+	return checks
+}
+
+// checkConfigDir ensures files like the cache can be written.
+func checkConfigDir() doctorCheck {
+	dir := viper.GetString("config-directory")
+
+	f, err := ioutil.TempFile(dir, "doctor")
+	if err != nil {
+		return doctorCheck{"Cache directory", doctorFail, fmt.Sprintf("%s is not writable: %v", dir, err)}
+	}
+	f.Close()
+	os.Remove(f.Name())
+
+	return doctorCheck{"Cache directory", doctorPass, dir + " is writable"}
+}
+
+// checkSystemRoots ensures the system's trusted TLS root certificates load.
+func checkSystemRoots() doctorCheck {
+	pool, err := x509.SystemCertPool()
+	if err != nil || pool == nil {
+		return doctorCheck{"System TLS roots", doctorWarn, fmt.Sprintf("unable to load system roots: %v", err)}
+	}
+
+	if len(pool.Subjects()) == 0 {
+		return doctorCheck{"System TLS roots", doctorFail, "no trusted root certificates found"}
+	}
+
+	return doctorCheck{"System TLS roots", doctorPass, fmt.Sprintf("%d trusted root certificates", len(pool.Subjects()))}
+}
+
+// checkAPIs ensures each configured API's base URL is reachable. Any HTTP
+// response counts, since auth isn't applied.
+func checkAPIs() []doctorCheck {
+	checks := []doctorCheck{}
+
+	names := []string{}
+	for name := range configs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	client := &http.Client{Transport: http.DefaultTransport, Timeout: 10 * time.Second}
+	for _, name := range names {
+		base := configs[name].Base
+		check := doctorCheck{Name: "API " + name}
+
+		resp, err := client.Head(base)
+		if err != nil {
+			check.Status = doctorFail
+			check.Message = fmt.Sprintf("%s: %v", base, err)
+		} else {
+			resp.Body.Close()
+			check.Status = doctorPass
+			check.Message = fmt.Sprintf("%s: %s", base, resp.Status)
+		}
+
+		checks = append(checks, check)
+	}
+
+	return checks
+}
+
+// checkTool looks for an optional external program, preferring the one set
+// in the given environment variable.
+func checkTool(name, envVar, fallback string) doctorCheck {
+	program := fallback
+	if envVar != "" && os.Getenv(envVar) != "" {
+		program = os.Getenv(envVar)
+	}
+
+	found, err := exec.LookPath(program)
+	if err != nil {
+		return doctorCheck{name, doctorWarn, program + " not found"}
+	}
+
+	return doctorCheck{name, doctorPass, found}
+}
+
+// doctorChecks runs all the environment checks.
+func doctorChecks() []doctorCheck {
+	checks := checkConfigFiles()
+	checks = append(checks, checkConfigDir(), checkSystemRoots())
+	checks = append(checks, checkAPIs()...)
+
+	editor := "EDITOR"
+	if os.Getenv("VISUAL") != "" {
+		editor = "VISUAL"
+	}
+	checks = append(checks,
+		checkTool("Editor", editor, "vi"),
+		checkTool("Pager", "PAGER", "less"),
+		checkTool("jq", "", "jq"),
+	)
+
+	return checks
+}
+
+// runDoctor prints a report of the environment checks and sets a non-zero
+// exit code if any failed.
+func runDoctor() error {
+	checks := doctorChecks()
+
+	for _, check := range checks {
+		if check.Status == doctorFail {
+			exitCode = 1
+		}
+	}
+
+	if viper.GetString("rsh-output-format") == "json" {
+		encoded, err := json.MarshalIndent(checks, "", "  ")
+		if err != nil {
+			return err
+		}
+
+		if tty {
+			if encoded, err = Highlight("json", encoded); err != nil {
+				return err
+			}
+		}
+
+		fmt.Fprintln(Stdout, string(encoded))
+		return nil
+	}
+
+	for _, check := range checks {
+		var status interface{}
+		switch check.Status {
+		case doctorPass:
+			status = au.Index(78, "PASS")
+		case doctorWarn:
+			status = au.Index(222, "WARN")
+		default:
+			status = au.Index(204, "FAIL")
+		}
+
+		fmt.Fprintf(Stdout, "[%s] %s: %s\n", status, check.Name, check.Message)
+	}
+
+	return nil
+}
@@ -0,0 +1,48 @@
+package cli
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDoctor(t *testing.T) {
+	reset(false)
+	defer func() { exitCode = 0 }()
+
+	up := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusUnauthorized)
+	}))
+	defer up.Close()
+
+	down := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	down.Close()
+
+	configs["doctor-up"] = &APIConfig{Base: up.URL}
+	configs["doctor-down"] = &APIConfig{Base: down.URL}
+	defer delete(configs, "doctor-up")
+	defer delete(configs, "doctor-down")
+
+	checks := map[string]doctorCheck{}
+	for _, check := range doctorChecks() {
+		checks[check.Name] = check
+	}
+
+	assert.Equal(t, doctorPass, checks["API config"].Status)
+	assert.Equal(t, doctorPass, checks["Cache directory"].Status)
+	assert.Equal(t, doctorPass, checks["API doctor-up"].Status)
+	assert.Contains(t, checks["API doctor-up"].Message, "401")
+	assert.Equal(t, doctorFail, checks["API doctor-down"].Status)
+	assert.Contains(t, checks, "jq")
+
+	captured := &strings.Builder{}
+	Stdout = captured
+	exitCode = 0
+	assert.NoError(t, runDoctor())
+	assert.Equal(t, 1, exitCode)
+	assert.Contains(t, captured.String(), "[PASS] API doctor-up")
+	assert.Contains(t, captured.String(), "[FAIL] API doctor-down")
+}
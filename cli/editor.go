@@ -0,0 +1,317 @@
+package cli
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"os/exec"
+	"reflect"
+	"strings"
+
+	"github.com/mattn/go-isatty"
+	"github.com/spf13/viper"
+)
+
+// editorCommand returns the user's preferred editor.
+func editorCommand() string {
+	if editor := os.Getenv("VISUAL"); editor != "" {
+		return editor
+	}
+
+	if editor := os.Getenv("EDITOR"); editor != "" {
+		return editor
+	}
+
+	return "vi"
+}
+
+// runEditor opens the file in the user's editor and waits for it to exit. It
+// can be overridden in tests.
+var runEditor = func(filename string) error {
+	// The editor may include arguments, e.g. `code --wait`.
+	parts := strings.Fields(editorCommand())
+	cmd := exec.Command(parts[0], append(parts[1:], filename)...)
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	return cmd.Run()
+}
+
+// editText lets the user edit some text in their editor, using a temporary
+// file with the given extension so the editor can highlight it.
+func editText(text, ext string) (string, error) {
+	f, err := ioutil.TempFile("", "restish-*"+ext)
+	if err != nil {
+		return "", err
+	}
+	defer os.Remove(f.Name())
+
+	if _, err := f.WriteString(text); err != nil {
+		f.Close()
+		return "", err
+	}
+	f.Close()
+
+	if err := runEditor(f.Name()); err != nil {
+		return "", fmt.Errorf("editor failed: %w", err)
+	}
+
+	data, err := ioutil.ReadFile(f.Name())
+	if err != nil {
+		return "", err
+	}
+
+	return string(data), nil
+}
+
+// editorExtension returns a file extension for editing the content type.
+func editorExtension(contentType string) string {
+	switch {
+	case strings.Contains(contentType, "json"):
+		return ".json"
+	case strings.Contains(contentType, "yaml"):
+		return ".yaml"
+	}
+
+	return ".txt"
+}
+
+// commentLines prefixes each line of the message with `# `.
+func commentLines(message string) string {
+	lines := strings.Split(strings.TrimRight(message, "\n"), "\n")
+	for i, line := range lines {
+		lines[i] = strings.TrimRight("# "+line, " ")
+	}
+	return strings.Join(lines, "\n") + "\n"
+}
+
+// stripComments removes the leading `#` comment lines added by commentLines.
+func stripComments(text string) string {
+	lines := strings.Split(text, "\n")
+	for len(lines) > 0 && strings.HasPrefix(lines[0], "#") {
+		lines = lines[1:]
+	}
+	return strings.Join(lines, "\n")
+}
+
+// isBodyError returns whether the status code likely means the request body
+// was invalid and could be fixed by editing it.
+func isBodyError(status int) bool {
+	return status == http.StatusBadRequest || status == http.StatusUnprocessableEntity
+}
+
+// canEditBodyOnError returns whether a failed request body can be edited and
+// resent, which requires `rsh-body-edit-on-error` and an interactive terminal.
+func canEditBodyOnError(body string) bool {
+	return viper.GetBool("rsh-body-edit-on-error") && body != "" && (isatty.IsTerminal(os.Stdin.Fd()) || isatty.IsCygwinTerminal(os.Stdin.Fd()))
+}
+
+// bodyEditAsker asks whether to edit the body and can be overridden in tests.
+var bodyEditAsker asker = defaultAsker{}
+
+// makeRequestWithBodyEdit sends the request and, if the server rejects the
+// body, offers to open it in the user's editor with the error as a comment
+// so it can be fixed and resent.
+func makeRequestWithBodyEdit(template *http.Request, body string) error {
+	contentType := template.Header.Get("Content-Type")
+	if contentType == "" {
+		contentType = "application/json"
+	}
+
+	for {
+		req, err := http.NewRequest(template.Method, template.URL.String(), strings.NewReader(body))
+		if err != nil {
+			return err
+		}
+		req.Header = template.Header.Clone()
+
+		resp, err := GetParsedResponse(req)
+		if err != nil {
+			return err
+		}
+
+		if err := Formatter.Format(resp); err != nil {
+			return err
+		}
+
+		if !isBodyError(resp.Status) {
+			return checkFailIf(resp)
+		}
+
+		if !bodyEditAsker.askConfirm("Edit the body and resend?", true, "Opens the request body in your editor with the server's error as a comment. Exit without changes to stop.") {
+			return checkFailIf(resp)
+		}
+
+		message := fmt.Sprintf("HTTP %d %s\n", resp.Status, http.StatusText(resp.Status))
+		if resp.Body != nil {
+			details, _ := json.MarshalIndent(makeJSONSafe(resp.Body), "", "  ")
+			message += string(details) + "\n"
+		}
+
+		for {
+			edited, err := editText(commentLines(message+"\nLines starting with # are removed.")+body, editorExtension(contentType))
+			if err != nil {
+				return err
+			}
+			edited = stripComments(edited)
+
+			if strings.TrimSpace(edited) == strings.TrimSpace(body) || strings.TrimSpace(edited) == "" {
+				LogInfo("Body unchanged, not resending")
+				return checkFailIf(resp)
+			}
+
+			if strings.Contains(contentType, "json") {
+				if err := validateJSON("editor", []byte(edited)); err != nil {
+					// Let the user fix it before sending.
+					message = err.Error() + "\n"
+					body = edited
+					continue
+				}
+			}
+
+			body = edited
+			break
+		}
+	}
+}
+
+// mergePatch returns a JSON merge patch (RFC 7386) which turns the original
+// document into the edited one. Removed keys are set to null and arrays are
+// replaced as a whole.
+func mergePatch(original, edited interface{}) interface{} {
+	o, ok1 := original.(map[string]interface{})
+	e, ok2 := edited.(map[string]interface{})
+	if !ok1 || !ok2 {
+		return edited
+	}
+
+	patch := map[string]interface{}{}
+	for k, v := range e {
+		if ov, ok := o[k]; !ok || !reflect.DeepEqual(ov, v) {
+			if ok {
+				patch[k] = mergePatch(ov, v)
+			} else {
+				patch[k] = v
+			}
+		}
+	}
+
+	for k := range o {
+		if _, ok := e[k]; !ok {
+			patch[k] = nil
+		}
+	}
+
+	return patch
+}
+
+// editResource fetches the resource, opens it in the user's editor and sends
+// the edited result back using the method, i.e. the "fetch, edit, save"
+// workflow. YAML is used for editing if it is the output format, otherwise
+// JSON. Nothing is sent if the resource was not changed. A `PUT` sends the
+// whole edited resource while a `PATCH` sends a merge patch of the changes.
+// The `ETag` of the fetched resource is sent as `If-Match` so that changes
+// made by someone else in the meantime aren't overwritten.
+func editResource(method, addr string) error {
+	uri := fixAddress(addr)
+
+	get, err := http.NewRequest(http.MethodGet, uri, nil)
+	if err != nil {
+		return err
+	}
+
+	current, err := GetParsedResponse(get)
+	if err != nil {
+		return err
+	}
+
+	if current.Status >= 400 {
+		return fmt.Errorf("unable to fetch %s for editing: got HTTP %d", uri, current.Status)
+	}
+
+	original, err := normalize(current.Body)
+	if err != nil {
+		return err
+	}
+
+	contentType := "application/json"
+	if viper.GetString("rsh-output-format") == "yaml" {
+		contentType = "application/yaml"
+	}
+
+	var text string
+	if contentType == "application/json" {
+		encoded, err := json.MarshalIndent(original, "", "  ")
+		if err != nil {
+			return err
+		}
+		text = string(encoded) + "\n"
+	} else {
+		encoded, err := Marshal(contentType, original)
+		if err != nil {
+			return err
+		}
+		text = string(encoded)
+	}
+
+	message := fmt.Sprintf("Editing %s %s\nLines starting with # are removed. Exit without changes to cancel.", method, uri)
+	body := text
+
+	var edited interface{}
+	for {
+		result, err := editText(commentLines(message)+body, editorExtension(contentType))
+		if err != nil {
+			return err
+		}
+		result = stripComments(result)
+
+		if strings.TrimSpace(result) == strings.TrimSpace(text) || strings.TrimSpace(result) == "" {
+			LogInfo("No changes, not sending")
+			return nil
+		}
+
+		var parsed interface{}
+		if err := Unmarshal(contentType, []byte(result), &parsed); err != nil {
+			// Let the user fix it before sending.
+			message = fmt.Sprintf("Unable to parse the edited resource: %v", err)
+			body = result
+			continue
+		}
+
+		if edited, err = normalize(parsed); err != nil {
+			return err
+		}
+		break
+	}
+
+	if reflect.DeepEqual(original, edited) {
+		LogInfo("No changes, not sending")
+		return nil
+	}
+
+	sendType := "application/json"
+	if method == http.MethodPatch {
+		sendType = "application/merge-patch+json"
+		edited = mergePatch(original, edited)
+	}
+
+	encoded, err := json.Marshal(edited)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequest(method, uri, bytes.NewReader(encoded))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", sendType)
+
+	if etag := current.Headers["Etag"]; etag != "" {
+		req.Header.Set("If-Match", etag)
+	}
+
+	return makeRequestAndFormat(req)
+}
@@ -0,0 +1,139 @@
+package cli
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCommentLines(t *testing.T) {
+	text := commentLines("HTTP 400 Bad Request\n\nmissing name\n") + "{}"
+	assert.Equal(t, "# HTTP 400 Bad Request\n#\n# missing name\n{}", text)
+	assert.Equal(t, "{}", stripComments(text))
+}
+
+func TestBodyEditOnError(t *testing.T) {
+	reset(false)
+
+	received := []string{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data, _ := ioutil.ReadAll(r.Body)
+		received = append(received, string(data))
+
+		var body map[string]interface{}
+		json.Unmarshal(data, &body)
+		w.Header().Set("Content-Type", "application/json")
+		if body["name"] == nil {
+			w.WriteHeader(http.StatusUnprocessableEntity)
+			w.Write([]byte(`{"detail": "name is required"}`))
+			return
+		}
+		w.Write([]byte(`{"ok": true}`))
+	}))
+	defer server.Close()
+
+	Stdout = &strings.Builder{}
+	bodyEditAsker = &mockAsker{t: t, responses: []string{"y"}}
+	defer func() { bodyEditAsker = defaultAsker{} }()
+
+	edits := []string{}
+	origEditor := runEditor
+	runEditor = func(filename string) error {
+		data, _ := ioutil.ReadFile(filename)
+		edits = append(edits, string(data))
+		return ioutil.WriteFile(filename, []byte(strings.Replace(string(data), `{"title": "x"}`, `{"title": "x", "name": "y"}`, 1)), 0600)
+	}
+	defer func() { runEditor = origEditor }()
+
+	req, _ := http.NewRequest(http.MethodPost, server.URL, nil)
+	req.Header.Set("Content-Type", "application/json")
+	assert.NoError(t, makeRequestWithBodyEdit(req, `{"title": "x"}`))
+
+	assert.Equal(t, []string{`{"title": "x"}`, `{"title": "x", "name": "y"}`}, received)
+	assert.Len(t, edits, 1)
+	assert.Contains(t, edits[0], "# HTTP 422 Unprocessable Entity")
+	assert.Contains(t, edits[0], `#   "detail": "name is required"`)
+}
+
+func TestMergePatch(t *testing.T) {
+	original := map[string]interface{}{
+		"name":   "a",
+		"nested": map[string]interface{}{"x": 1.0, "y": 2.0},
+		"old":    true,
+		"tags":   []interface{}{"a"},
+	}
+	edited := map[string]interface{}{
+		"name":   "a",
+		"nested": map[string]interface{}{"x": 1.0, "y": 3.0},
+		"tags":   []interface{}{"a", "b"},
+	}
+
+	assert.Equal(t, map[string]interface{}{
+		"nested": map[string]interface{}{"y": 3.0},
+		"old":    nil,
+		"tags":   []interface{}{"a", "b"},
+	}, mergePatch(original, edited))
+}
+
+func TestEditResource(t *testing.T) {
+	reset(false)
+
+	var method, contentType, ifMatch, sent string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if r.Method == http.MethodGet {
+			w.Header().Set("ETag", `"v1"`)
+			w.Write([]byte(`{"name": "old", "enabled": true}`))
+			return
+		}
+
+		data, _ := ioutil.ReadAll(r.Body)
+		method, contentType, ifMatch, sent = r.Method, r.Header.Get("Content-Type"), r.Header.Get("If-Match"), string(data)
+		w.Write([]byte(`{"ok": true}`))
+	}))
+	defer server.Close()
+
+	Stdout = &strings.Builder{}
+	Stderr = &strings.Builder{}
+
+	origEditor := runEditor
+	defer func() { runEditor = origEditor }()
+
+	// Exiting without changes doesn't send anything.
+	runEditor = func(filename string) error { return nil }
+	assert.NoError(t, editResource(http.MethodPut, server.URL+"/settings"))
+	assert.Empty(t, method)
+
+	// Invalid content reopens the editor with the error.
+	edits := []string{}
+	runEditor = func(filename string) error {
+		data, _ := ioutil.ReadFile(filename)
+		edits = append(edits, string(data))
+		if len(edits) == 1 {
+			return ioutil.WriteFile(filename, []byte(`{"name": `), 0600)
+		}
+		return ioutil.WriteFile(filename, []byte(`{"name": "new"}`), 0600)
+	}
+	assert.NoError(t, editResource(http.MethodPatch, server.URL+"/settings"))
+	assert.Len(t, edits, 2)
+	assert.Contains(t, edits[0], "# Editing PATCH "+server.URL+"/settings")
+	assert.Contains(t, edits[1], "# Unable to parse the edited resource")
+	assert.Equal(t, http.MethodPatch, method)
+	assert.Equal(t, "application/merge-patch+json", contentType)
+	assert.Equal(t, `"v1"`, ifMatch)
+	assert.JSONEq(t, `{"name": "new", "enabled": null}`, sent)
+
+	// A PUT sends the whole resource.
+	runEditor = func(filename string) error {
+		return ioutil.WriteFile(filename, []byte(`{"name": "new", "enabled": true}`), 0600)
+	}
+	assert.NoError(t, editResource(http.MethodPut, server.URL+"/settings"))
+	assert.Equal(t, http.MethodPut, method)
+	assert.Equal(t, "application/json", contentType)
+	assert.JSONEq(t, `{"name": "new", "enabled": true}`, sent)
+}
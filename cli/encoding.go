@@ -1,6 +1,7 @@
 package cli
 
 import (
+	"bytes"
 	"compress/gzip"
 	"fmt"
 	"io"
@@ -9,6 +10,7 @@ import (
 	"strings"
 
 	"github.com/andybalholm/brotli"
+	"github.com/klauspost/compress/zstd"
 )
 
 // ContentEncoding is used to encode/decode content for transfer over the wire,
@@ -17,6 +19,12 @@ type ContentEncoding interface {
 	Reader(stream io.Reader) (io.Reader, error)
 }
 
+// ContentEncoder is implemented by content encodings which can also be used
+// to compress outgoing request bodies.
+type ContentEncoder interface {
+	Writer(w io.Writer) io.WriteCloser
+}
+
 // contentTypes is a list of acceptable content types
 var encodings = map[string]ContentEncoding{}
 
@@ -36,11 +44,12 @@ func buildAcceptEncodingHeader() string {
 }
 
 // DecodeResponse will replace the response body with a decoding reader if needed.
-// Assumes the original body will be closed outside of this function.
+// Assumes the original body will be closed outside of this function. Calling
+// it again on the same response is a no-op.
 func DecodeResponse(resp *http.Response) error {
 	contentEncoding := resp.Header.Get("content-encoding")
 
-	if contentEncoding == "" {
+	if contentEncoding == "" || resp.Uncompressed {
 		// Nothing to do!
 		return nil
 	}
@@ -60,6 +69,58 @@ func DecodeResponse(resp *http.Response) error {
 
 	resp.Body = ioutil.NopCloser(reader)
 
+	// Mark the response as decoded so it isn't decoded twice.
+	resp.Uncompressed = true
+
+	return nil
+}
+
+// CompressRequest encodes the request body with the named content encoding
+// if it is at least `threshold` bytes long. Bodies which are already encoded
+// are left as-is.
+func CompressRequest(req *http.Request, name string, threshold int) error {
+	if req.Body == nil || req.Header.Get("content-encoding") != "" {
+		return nil
+	}
+
+	encoder, ok := encodings[name].(ContentEncoder)
+	if !ok {
+		return fmt.Errorf("content-encoding %s cannot encode requests", name)
+	}
+
+	data, err := ioutil.ReadAll(req.Body)
+	if err != nil {
+		return err
+	}
+	req.Body.Close()
+
+	if len(data) < threshold {
+		req.Body = ioutil.NopCloser(bytes.NewReader(data))
+		req.GetBody = func() (io.ReadCloser, error) {
+			return ioutil.NopCloser(bytes.NewReader(data)), nil
+		}
+		return nil
+	}
+
+	buf := &bytes.Buffer{}
+	w := encoder.Writer(buf)
+	if _, err := w.Write(data); err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+
+	LogDebug("Compressed request body from %d to %d bytes with %s", len(data), buf.Len(), name)
+
+	compressed := buf.Bytes()
+	req.Body = ioutil.NopCloser(bytes.NewReader(compressed))
+	req.GetBody = func() (io.ReadCloser, error) {
+		return ioutil.NopCloser(bytes.NewReader(compressed)), nil
+	}
+	req.ContentLength = int64(len(compressed))
+	req.Header.Set("content-encoding", name)
+
 	return nil
 }
 
@@ -71,6 +132,11 @@ func (g GzipEncoding) Reader(stream io.Reader) (io.Reader, error) {
 	return gzip.NewReader(stream)
 }
 
+// Writer returns a new writer which gzip-encodes data written to it.
+func (g GzipEncoding) Writer(w io.Writer) io.WriteCloser {
+	return gzip.NewWriter(w)
+}
+
 // BrotliEncoding supports RFC 7932 Brotli content encoding.
 type BrotliEncoding struct{}
 
@@ -78,3 +144,32 @@ type BrotliEncoding struct{}
 func (b BrotliEncoding) Reader(stream io.Reader) (io.Reader, error) {
 	return io.Reader(brotli.NewReader(stream)), nil
 }
+
+// ZstdEncoding supports RFC 8878 Zstandard content encoding.
+type ZstdEncoding struct{}
+
+// Reader returns a new reader for the stream that removes the zstd encoding.
+func (z ZstdEncoding) Reader(stream io.Reader) (io.Reader, error) {
+	dec, err := zstd.NewReader(stream, zstd.WithDecoderConcurrency(1))
+	if err != nil {
+		return nil, fmt.Errorf("zstd: %w", err)
+	}
+
+	return zstdReader{dec}, nil
+}
+
+// zstdReader wraps decoding errors so that corrupt streams are easy to spot.
+type zstdReader struct {
+	dec *zstd.Decoder
+}
+
+func (z zstdReader) Read(p []byte) (int, error) {
+	n, err := z.dec.Read(p)
+	if err == io.EOF {
+		// Release the decoder's resources once the stream is done.
+		z.dec.Close()
+	} else if err != nil {
+		err = fmt.Errorf("zstd: %w", err)
+	}
+	return n, err
+}
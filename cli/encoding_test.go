@@ -5,9 +5,11 @@ import (
 	"compress/gzip"
 	"io/ioutil"
 	"net/http"
+	"strings"
 	"testing"
 
 	"github.com/andybalholm/brotli"
+	"github.com/klauspost/compress/zstd"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -27,6 +29,14 @@ func brEnc(data string) []byte {
 	return b.Bytes()
 }
 
+func zstdEnc(data string) []byte {
+	b := bytes.NewBuffer(nil)
+	w, _ := zstd.NewWriter(b)
+	w.Write([]byte(data))
+	w.Close()
+	return b.Bytes()
+}
+
 var encodingTests = []struct {
 	name   string
 	header string
@@ -35,6 +45,7 @@ var encodingTests = []struct {
 	{"none", "", []byte("hello world")},
 	{"gzip", "gzip", gzipEnc("hello world")},
 	{"brotli", "br", brEnc("hello world")},
+	{"zstd", "zstd", zstdEnc("hello world")},
 }
 
 func TestEncodings(parent *testing.T) {
@@ -56,3 +67,44 @@ func TestEncodings(parent *testing.T) {
 		})
 	}
 }
+
+func TestZstdCorrupt(t *testing.T) {
+	resp := &http.Response{
+		Header: http.Header{
+			"Content-Encoding": []string{"zstd"},
+		},
+		Body: ioutil.NopCloser(bytes.NewReader([]byte("not zstd data"))),
+	}
+
+	_, err := ParseResponse(resp)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "zstd")
+}
+
+func TestAcceptEncodingZstd(t *testing.T) {
+	reset(false)
+	assert.Contains(t, buildAcceptEncodingHeader(), "zstd")
+}
+
+func TestCompressRequest(t *testing.T) {
+	reset(false)
+
+	large := strings.Repeat("hello world ", 200)
+	req, _ := http.NewRequest(http.MethodPost, "http://example.com/", strings.NewReader(large))
+	assert.NoError(t, CompressRequest(req, "gzip", 1024))
+	assert.Equal(t, "gzip", req.Header.Get("Content-Encoding"))
+	assert.Less(t, req.ContentLength, int64(len(large)))
+
+	r, err := gzip.NewReader(req.Body)
+	assert.NoError(t, err)
+	data, err := ioutil.ReadAll(r)
+	assert.NoError(t, err)
+	assert.Equal(t, large, string(data))
+
+	// Small bodies are sent as-is.
+	req, _ = http.NewRequest(http.MethodPost, "http://example.com/", strings.NewReader("small"))
+	assert.NoError(t, CompressRequest(req, "gzip", 1024))
+	assert.Equal(t, "", req.Header.Get("Content-Encoding"))
+	data, _ = ioutil.ReadAll(req.Body)
+	assert.Equal(t, "small", string(data))
+}
@@ -0,0 +1,79 @@
+package cli
+
+import (
+	jmespath "github.com/danielgtaylor/go-jmespath-plus"
+	"github.com/spf13/viper"
+)
+
+// exitCode is the process exit code to use once the command has finished, for
+// checks like `rsh-fail-if` which fail without stopping the output.
+var exitCode int
+
+// statusExitCode maps an HTTP response status to a process exit code so that
+// scripts can detect failed requests: `4` for 4xx client errors, `5` for 5xx
+// server errors and `0` for everything else.
+func statusExitCode(status int) int {
+	switch {
+	case status >= 400 && status < 500:
+		return 4
+	case status >= 500 && status < 600:
+		return 5
+	}
+
+	return 0
+}
+
+// checkStatus sets the exit code for an error response status unless disabled
+// via `rsh-ignore-status`. An exit code set by an earlier check is kept.
+func checkStatus(status int) {
+	if viper.GetBool("rsh-ignore-status") || exitCode != 0 {
+		return
+	}
+
+	exitCode = statusExitCode(status)
+}
+
+// isTruthy returns whether a JMESPath result is truthy. Like JMESPath itself,
+// `false`, `null` and empty strings, arrays and objects are false while
+// everything else, including the number zero, is true.
+func isTruthy(v interface{}) bool {
+	switch t := v.(type) {
+	case nil:
+		return false
+	case bool:
+		return t
+	case string:
+		return t != ""
+	case []interface{}:
+		return len(t) > 0
+	case map[string]interface{}:
+		return len(t) > 0
+	}
+
+	return true
+}
+
+// checkFailIf evaluates the `rsh-fail-if` JMESPath expression against the
+// response body and sets a non-zero exit code if the result is truthy, e.g.
+// for APIs which return errors in the body of a `200 OK` response. The exit
+// code for the response status is set first, see `checkStatus`.
+func checkFailIf(resp Response) error {
+	checkStatus(resp.Status)
+
+	expr := viper.GetString("rsh-fail-if")
+	if expr == "" {
+		return nil
+	}
+
+	result, err := jmespath.Search(expr, makeJSONSafe(resp.Body))
+	if err != nil {
+		return err
+	}
+
+	if isTruthy(result) {
+		LogError("Response matched --rsh-fail-if %s", expr)
+		exitCode = 1
+	}
+
+	return nil
+}
@@ -0,0 +1,82 @@
+package cli
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/h2non/gock.v1"
+)
+
+func TestIsTruthy(t *testing.T) {
+	assert.False(t, isTruthy(nil))
+	assert.False(t, isTruthy(false))
+	assert.False(t, isTruthy(""))
+	assert.False(t, isTruthy([]interface{}{}))
+	assert.False(t, isTruthy(map[string]interface{}{}))
+	assert.True(t, isTruthy(true))
+	assert.True(t, isTruthy(0.0))
+	assert.True(t, isTruthy("error"))
+	assert.True(t, isTruthy([]interface{}{1}))
+}
+
+func TestFailIf(t *testing.T) {
+	defer gock.Off()
+	defer func() { exitCode = 0 }()
+
+	gock.New("http://example.com").
+		Get("/ok").
+		Reply(200).
+		JSON(map[string]interface{}{"data": "foo", "errors": nil})
+
+	exitCode = 0
+	run("http://example.com/ok --rsh-fail-if errors")
+	assert.Equal(t, 0, exitCode)
+
+	gock.New("http://example.com").
+		Get("/fail").
+		Reply(200).
+		JSON(map[string]interface{}{"errors": []interface{}{"boom"}})
+
+	captured := run("http://example.com/fail --rsh-fail-if errors")
+	assert.Equal(t, 1, exitCode)
+	assert.Contains(t, captured, "boom")
+}
+
+func TestStatusExitCode(t *testing.T) {
+	assert.Equal(t, 0, statusExitCode(200))
+	assert.Equal(t, 0, statusExitCode(304))
+	assert.Equal(t, 4, statusExitCode(404))
+	assert.Equal(t, 4, statusExitCode(429))
+	assert.Equal(t, 5, statusExitCode(500))
+	assert.Equal(t, 5, statusExitCode(503))
+}
+
+func TestStatusExit(t *testing.T) {
+	defer gock.Off()
+	defer func() { exitCode = 0 }()
+
+	gock.New("http://example.com").
+		Get("/missing").
+		Reply(404).
+		JSON(map[string]interface{}{"title": "Not Found"})
+
+	captured := run("http://example.com/missing")
+	assert.Equal(t, 4, exitCode)
+	assert.Contains(t, captured, "Not Found")
+
+	gock.New("http://example.com").
+		Get("/broken").
+		Reply(503).
+		JSON(map[string]interface{}{"title": "Unavailable"})
+
+	run("http://example.com/broken")
+	assert.Equal(t, 5, exitCode)
+
+	gock.New("http://example.com").
+		Get("/missing").
+		Reply(404).
+		JSON(map[string]interface{}{"title": "Not Found"})
+
+	run("http://example.com/missing --rsh-ignore-status")
+	assert.Equal(t, 0, exitCode)
+}
@@ -2,19 +2,22 @@ package cli
 
 import (
 	"bytes"
+	"encoding/hex"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"image/color"
+	"io/ioutil"
 	"net/http"
+	"os"
 	"reflect"
+	"regexp"
 	"sort"
 	"strings"
 
-	"github.com/alecthomas/chroma"
 	"github.com/alecthomas/chroma/quick"
-	"github.com/alecthomas/chroma/styles"
 	jmespath "github.com/danielgtaylor/go-jmespath-plus"
+	"github.com/mattn/go-isatty"
 	"github.com/spf13/viper"
 	"golang.org/x/crypto/ssh/terminal"
 	"gopkg.in/yaml.v2"
@@ -23,35 +26,6 @@ import (
 	"github.com/eliukblau/pixterm/pkg/ansimage"
 )
 
-func init() {
-	// Simple 256-color theme for JSON/YAML output in a terminal.
-	styles.Register(chroma.MustNewStyle("cli-dark", chroma.StyleEntries{
-		// Used for JSON/YAML/Readable
-		chroma.Comment:      "#9e9e9e",
-		chroma.Keyword:      "#ff5f87",
-		chroma.Punctuation:  "#9e9e9e",
-		chroma.NameTag:      "#5fafd7",
-		chroma.Number:       "#d78700",
-		chroma.String:       "#afd787",
-		chroma.StringSymbol: "italic #D6FFB7",
-		chroma.Date:         "#af87af",
-		chroma.NumberHex:    "#ffd7d7",
-
-		// Used for HTTP
-		chroma.Name:          "#5fafd7",
-		chroma.NameFunction:  "#ff5f87",
-		chroma.NameNamespace: "#b2b2b2",
-
-		// Used for Markdown
-		chroma.GenericHeading:    "#5fafd7",
-		chroma.GenericSubheading: "#5fafd7",
-		chroma.GenericEmph:       "italic #ffd7d7",
-		chroma.GenericStrong:     "bold #af87af",
-		chroma.GenericDeleted:    "#3a3a3a",
-		chroma.NameAttribute:     "underline",
-	}))
-}
-
 // makeJSONSafe walks an interface to ensure all maps use string keys so that
 // encoding to JSON (or YAML) works. Some unmarshallers (e.g. CBOR) will
 // create map[interface{}]interface{} which causes problems marshalling.
@@ -96,10 +70,35 @@ func makeJSONSafe(obj interface{}) interface{} {
 	return obj
 }
 
+// yamlNumbers converts precise `json.Number` values into native numbers,
+// since YAML would otherwise quote them as strings. Expects the output of
+// `makeJSONSafe`.
+func yamlNumbers(obj interface{}) interface{} {
+	switch v := obj.(type) {
+	case json.Number:
+		if i, err := v.Int64(); err == nil {
+			return i
+		}
+		if f, err := v.Float64(); err == nil {
+			return f
+		}
+	case []interface{}:
+		for i := range v {
+			v[i] = yamlNumbers(v[i])
+		}
+	case map[string]interface{}:
+		for k := range v {
+			v[k] = yamlNumbers(v[k])
+		}
+	}
+
+	return obj
+}
+
 // Highlight a block of data with the given lexer.
 func Highlight(lexer string, data []byte) ([]byte, error) {
 	sb := &strings.Builder{}
-	if err := quick.Highlight(sb, string(data), lexer, "terminal256", "cli-dark"); err != nil {
+	if err := quick.Highlight(sb, string(data), lexer, "terminal256", highlightStyle); err != nil {
 		return nil, err
 	}
 	return []byte(sb.String()), nil
@@ -110,6 +109,64 @@ type ResponseFormatter interface {
 	Format(Response) error
 }
 
+// hiddenHeaders returns a function which reports whether a response header
+// should be left out of the output because its name matches one of the
+// `rsh-hide-header` patterns.
+func hiddenHeaders() (func(string) bool, error) {
+	patterns := []*regexp.Regexp{}
+	for _, p := range viper.GetStringSlice("rsh-hide-header") {
+		re, err := regexp.Compile("(?i)" + p)
+		if err != nil {
+			return nil, fmt.Errorf("invalid header pattern %s: %w", p, err)
+		}
+		patterns = append(patterns, re)
+	}
+
+	return func(name string) bool {
+		for _, re := range patterns {
+			if re.MatchString(name) {
+				return true
+			}
+		}
+		return false
+	}, nil
+}
+
+// loadFilterFile sets `rsh-filter` from the file given by `rsh-filter-file`
+// so that long expressions can be version controlled. Lines starting with `#`
+// are comments and are ignored.
+func loadFilterFile() error {
+	filename := viper.GetString("rsh-filter-file")
+	if filename == "" {
+		return nil
+	}
+
+	if viper.GetString("rsh-filter") != "" {
+		return errors.New("cannot use both --rsh-filter and --rsh-filter-file")
+	}
+
+	data, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return err
+	}
+
+	lines := []string{}
+	for _, line := range strings.Split(string(data), "\n") {
+		if strings.HasPrefix(strings.TrimSpace(line), "#") {
+			continue
+		}
+		lines = append(lines, line)
+	}
+
+	filter := strings.TrimSpace(strings.Join(lines, "\n"))
+	if filter == "" {
+		return fmt.Errorf("filter file %s is empty", filename)
+	}
+
+	viper.Set("rsh-filter", filter)
+	return nil
+}
+
 // DefaultFormatter can apply JMESPath queries and can output prettyfied JSON
 // and YAML output. If Stdout is a TTY, then colorized output is provided. The
 // default formatter uses the `rsh-filter` and `rsh-output-format` configuration
@@ -130,6 +187,15 @@ func NewDefaultFormatter(tty bool) *DefaultFormatter {
 func (f *DefaultFormatter) Format(resp Response) error {
 	outFormat := viper.GetString("rsh-output-format")
 
+	// Decode stringified JSON before filtering so it can be queried too.
+	if err := UnwrapResponseJSON(&resp, viper.GetString("rsh-unwrap-json"), viper.GetBool("rsh-unwrap-json-auto")); err != nil {
+		return err
+	}
+
+	if err := InflateResponse(&resp, viper.GetString("rsh-inflate")); err != nil {
+		return err
+	}
+
 	var data interface{} = resp.Map()
 
 	filter := viper.GetString("rsh-filter")
@@ -149,12 +215,150 @@ func (f *DefaultFormatter) Format(resp Response) error {
 		}
 
 		if result == nil {
+			if viper.GetBool("rsh-count") {
+				fmt.Fprintln(Stdout, 0)
+			}
 			return nil
 		}
 
 		data = result
 	}
 
+	itemsPath := viper.GetString("rsh-items-path")
+
+	if first, last := viper.GetInt("rsh-first"), viper.GetInt("rsh-last"); first > 0 || last > 0 {
+		// Without a filter the top-level array is the body.
+		if filter == "" {
+			body := resp.Body
+			if itemsPath != "" {
+				body = makeJSONSafe(body)
+			}
+			sliced, err := sliceItemsAt(body, itemsPath, first, last)
+			if err != nil {
+				return err
+			}
+			resp.Body = sliced
+			data = resp.Map()
+		} else {
+			sliced, err := sliceItemsAt(data, itemsPath, first, last)
+			if err != nil {
+				return err
+			}
+			data = sliced
+		}
+	}
+
+	if viper.GetBool("rsh-count") {
+		counted := data
+		if filter == "" {
+			// Count the body rather than the response metadata.
+			counted = resp.Body
+			if itemsPath != "" {
+				counted = makeJSONSafe(counted)
+			}
+		}
+
+		counted, err := itemsAt(counted, itemsPath)
+		if err != nil {
+			return err
+		}
+
+		items, ok := counted.([]interface{})
+		if !ok {
+			return errors.New("cannot count result: not an array")
+		}
+
+		fmt.Fprintln(Stdout, len(items))
+		return nil
+	}
+
+	if viper.GetBool("rsh-summary") {
+		if filter == "" {
+			// Summarize the body rather than the response metadata.
+			data = resp.Body
+		}
+
+		size := 0
+		if encoded, err := json.Marshal(makeJSONSafe(data)); err == nil {
+			size = len(encoded)
+		}
+
+		fmt.Fprintf(Stdout, "%sTotal size: %d bytes (as JSON)\n", Summarize(data, viper.GetInt("rsh-summary-depth")), size)
+		return nil
+	}
+
+	if maxDepth := viper.GetInt("rsh-max-depth"); maxDepth > 0 {
+		// Truncation only affects display, so it happens after filtering. Without
+		// a filter the depth is counted from the body.
+		if filter == "" {
+			resp.Body = truncateDepth(resp.Body, maxDepth)
+			data = resp.Map()
+		} else {
+			data = truncateDepth(data, maxDepth)
+		}
+	}
+
+	highlight := f.tty
+
+	if viper.GetBool("rsh-binary") {
+		// Write the raw bytes as-is, e.g. for piping into another program.
+		raw := data
+		if filter == "" {
+			raw = resp.Body
+		}
+
+		switch v := raw.(type) {
+		case []byte:
+			_, err := Stdout.Write(v)
+			return err
+		case string:
+			_, err := Stdout.Write([]byte(v))
+			return err
+		}
+	}
+
+	outFile := viper.GetString("rsh-output-file")
+	if outFile != "" {
+		// Files get just the body without headers or highlighting, and binary
+		// or text bodies are written as-is.
+		if filter == "" {
+			data = resp.Body
+		}
+
+		switch v := data.(type) {
+		case []byte:
+			return writeOutputFile(outFile, resp, v)
+		case string:
+			return writeOutputFile(outFile, resp, []byte(v))
+		}
+
+		if outFormat == "auto" {
+			outFormat = "json"
+		}
+
+		highlight = false
+	}
+
+	if outFormat == "cbor" || outFormat == "msgpack" {
+		// Re-serialize to a binary format using the registered content type
+		// marshallers, e.g. for storage or format conversion pipelines.
+		if outFile == "" && (isatty.IsTerminal(os.Stdout.Fd()) || isatty.IsCygwinTerminal(os.Stdout.Fd())) {
+			return fmt.Errorf("refusing to write binary %s output to a terminal, use --rsh-output-file or redirect the output", outFormat)
+		}
+
+		encoded, err := Marshal("application/"+outFormat, yamlNumbers(makeJSONSafe(data)))
+		if err != nil {
+			return err
+		}
+
+		if outFile != "" {
+			return writeOutputFile(outFile, resp, encoded)
+		}
+
+		_, err = Stdout.Write(encoded)
+		return err
+	}
+
 	// Encode to the requested output format using nice formatting.
 	var encoded []byte
 	var err error
@@ -163,6 +367,10 @@ func (f *DefaultFormatter) Format(resp Response) error {
 	handled := false
 	kind := reflect.ValueOf(data).Kind()
 
+	if err := checkNumberFormat(); err != nil {
+		return err
+	}
+
 	// Handle table formatting
 	if viper.GetBool("rsh-table") && kind == reflect.Slice {
 		d, ok := data.([]interface{})
@@ -180,7 +388,7 @@ func (f *DefaultFormatter) Format(resp Response) error {
 
 	if viper.GetBool("rsh-raw") && kind == reflect.String {
 		handled = true
-		dStr := data.(string)
+		dStr := reflect.ValueOf(data).String()
 		encoded = []byte(dStr)
 		lexer = ""
 
@@ -193,7 +401,7 @@ func (f *DefaultFormatter) Format(resp Response) error {
 
 		for _, item := range data.([]interface{}) {
 			switch item.(type) {
-			case nil, bool, int, int64, float64, string:
+			case nil, bool, int, int64, float64, string, json.Number:
 				// The above are scalars used by decoders
 			default:
 				scalars = false
@@ -216,20 +424,39 @@ func (f *DefaultFormatter) Format(resp Response) error {
 		if outFormat == "auto" {
 			text := fmt.Sprintf("%s %d %s\n", resp.Proto, resp.Status, http.StatusText(resp.Status))
 
+			hidden, err := hiddenHeaders()
+			if err != nil {
+				return err
+			}
+
 			headerNames := []string{}
 			for k := range resp.Headers {
+				if hidden(k) {
+					continue
+				}
 				headerNames = append(headerNames, k)
 			}
 			sort.Strings(headerNames)
 
 			for _, name := range headerNames {
-				text += name + ": " + resp.Headers[name] + "\n"
+				// Repeated headers like `Set-Cookie` get one line per occurrence.
+				for _, value := range resp.HeaderList(name) {
+					text += name + ": " + value + "\n"
+				}
 			}
 
 			var e []byte
 
 			ct := resp.Headers["Content-Type"]
-			if ct == "image/png" || ct == "image/jpeg" || ct == "image/webp" || ct == "image/gif" {
+			if body, ok := resp.Body.([]byte); ok && viper.GetBool("rsh-show-image") && strings.HasPrefix(ct, "image/") {
+				// Use the terminal's native image protocol when available, falling
+				// back to saving the image to a temp file.
+				e, err = renderImage(strings.TrimSpace(strings.Split(ct, ";")[0]), body, f.tty)
+				if err != nil {
+					return err
+				}
+				handled = true
+			} else if ct == "image/png" || ct == "image/jpeg" || ct == "image/webp" || ct == "image/gif" {
 				// This is likely an image. Let's display it if we can! Get the window
 				// size, read and scale the image, and display it using unicode.
 				w, h, err := terminal.GetSize(0)
@@ -250,6 +477,10 @@ func (f *DefaultFormatter) Format(resp Response) error {
 			if !handled {
 				if s, ok := resp.Body.(string); ok {
 					text += "\n" + s
+				} else if b, ok := resp.Body.([]byte); ok && f.tty {
+					// Binary content would corrupt the terminal, so show a canonical
+					// hexdump instead. Use `--rsh-binary` to get the raw bytes.
+					e = []byte(hex.Dump(b))
 				} else if reflect.ValueOf(resp.Body).Kind() != reflect.Invalid {
 					e, err = MarshalReadable(resp.Body)
 					if err != nil {
@@ -273,7 +504,7 @@ func (f *DefaultFormatter) Format(resp Response) error {
 				}
 			}
 
-			if f.tty {
+			if highlight {
 				encoded, err = Highlight("http", []byte(text))
 				if err != nil {
 					return err
@@ -287,7 +518,7 @@ func (f *DefaultFormatter) Format(resp Response) error {
 				encoded = append(encoded, e...)
 			}
 		} else if outFormat == "yaml" {
-			data = makeJSONSafe(data)
+			data = yamlNumbers(makeJSONSafe(data))
 			encoded, err = yaml.Marshal(data)
 
 			if err != nil {
@@ -295,15 +526,60 @@ func (f *DefaultFormatter) Format(resp Response) error {
 			}
 
 			lexer = "yaml"
+		} else if outFormat == "toml" {
+			if items, ok := data.([]interface{}); ok {
+				// TOML documents are always tables, so wrap arrays in an array of
+				// tables under an `items` key.
+				data = map[string]interface{}{"items": items}
+			}
+
+			encoded, err = TOML{}.Marshal(data)
+
+			if err != nil {
+				return err
+			}
+
+			lexer = "toml"
+		} else if outFormat == "ndjson" {
+			encoded, err = NDJSON{}.Marshal(makeJSONSafe(data))
+
+			if err != nil {
+				return err
+			}
+		} else if outFormat == "template" {
+			encoded, err = renderTemplate(viper.GetString("rsh-template"), makeJSONSafe(data))
+
+			if err != nil {
+				return err
+			}
+		} else if outFormat == "csv" {
+			if _, ok := makeJSONSafe(data).([]interface{}); !ok {
+				return errors.New("CSV output requires an array of objects, use a filter to select one, e.g. -f body")
+			}
+
+			encoded, err = CSV{}.Marshal(data)
+
+			if err != nil {
+				return err
+			}
 		} else {
 			data = makeJSONSafe(data)
-			encoded, err = json.MarshalIndent(data, "", "  ")
+			if viper.GetBool("rsh-compact") {
+				encoded, err = json.Marshal(data)
+			} else if viper.GetBool("rsh-body-compact-arrays") {
+				encoded, err = marshalJSONCompactArrays(data)
+			} else {
+				encoded, err = json.MarshalIndent(data, "", "  ")
+			}
 
 			if err != nil {
 				return err
 			}
 
-			lexer = "json"
+			if !viper.GetBool("rsh-compact") {
+				// Compact output is meant for other tools, so skip highlighting.
+				lexer = "json"
+			}
 		}
 	}
 
@@ -314,7 +590,7 @@ func (f *DefaultFormatter) Format(resp Response) error {
 	}
 
 	// Only colorize if we are a TTY.
-	if f.tty && lexer != "" {
+	if highlight && lexer != "" {
 		encoded, err = Highlight(lexer, encoded)
 		if err != nil {
 			return err
@@ -325,6 +601,10 @@ func (f *DefaultFormatter) Format(resp Response) error {
 		encoded = append(encoded, '\n')
 	}
 
+	if outFile != "" {
+		return writeOutputFile(outFile, resp, encoded)
+	}
+
 	fmt.Fprint(Stdout, string(encoded))
 
 	return nil
@@ -347,6 +627,12 @@ func setTable(data []interface{}) (*[]byte, error) {
 				for k, _ := range mapData {
 					headerCells = append(headerCells, &simpletable.Cell{Align: simpletable.AlignCenter, Text: k})
 				}
+
+				if viper.GetBool("rsh-sort-keys") {
+					sort.Slice(headerCells, func(i, j int) bool {
+						return headerCells[i].Text < headerCells[j].Text
+					})
+				}
 			}
 			defineHeader = false
 
@@ -354,7 +640,7 @@ func setTable(data []interface{}) (*[]byte, error) {
 			// Will gt out of order otherwise
 			for _, cellKey := range headerCells {
 				if val, ok := mapData[cellKey.Text]; ok {
-					bodyCells = append(bodyCells, &simpletable.Cell{Align: simpletable.AlignRight, Text: fmt.Sprintf("%v", val)})
+					bodyCells = append(bodyCells, &simpletable.Cell{Align: simpletable.AlignRight, Text: formatCell(val)})
 				} else {
 					return nil, fmt.Errorf("error building table. Header Key not found in repeating object: %s", cellKey.Text)
 				}
@@ -0,0 +1,296 @@
+package cli
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/spf13/viper"
+)
+
+// maxHistoryEntries caps the number of requests kept in the history log. The
+// oldest entries are dropped first.
+const maxHistoryEntries = 500
+
+// maxHistoryBody is the largest request body which is recorded. Larger or
+// binary bodies are left out and replayed without a body.
+const maxHistoryBody = 64 * 1024
+
+// redactedValue replaces credentials in recorded requests.
+const redactedValue = "REDACTED"
+
+// HistoryEntry is a request recorded in the history log.
+type HistoryEntry struct {
+	Time        time.Time           `json:"time"`
+	Method      string              `json:"method"`
+	URL         string              `json:"url"`
+	Headers     map[string][]string `json:"headers,omitempty"`
+	Body        string              `json:"body,omitempty"`
+	BodyOmitted bool                `json:"body_omitted,omitempty"`
+	Status      int                 `json:"status"`
+	DurationMS  int64               `json:"duration_ms"`
+}
+
+func historyFile() string {
+	return path.Join(viper.GetString("config-directory"), "history.jsonl")
+}
+
+// loadHistory reads the recorded requests, oldest first.
+func loadHistory() ([]HistoryEntry, error) {
+	f, err := os.Open(historyFile())
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	entries := []HistoryEntry{}
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 0, 64*1024), 2*maxHistoryBody)
+	for scanner.Scan() {
+		var entry HistoryEntry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			LogWarning("Skipping invalid history entry: %v", err)
+			continue
+		}
+		entries = append(entries, entry)
+	}
+
+	return entries, scanner.Err()
+}
+
+// saveHistory writes the entries, keeping only the most recent ones.
+func saveHistory(entries []HistoryEntry) error {
+	if len(entries) > maxHistoryEntries {
+		entries = entries[len(entries)-maxHistoryEntries:]
+	}
+
+	buf := &bytes.Buffer{}
+	for _, entry := range entries {
+		encoded, err := json.Marshal(entry)
+		if err != nil {
+			return err
+		}
+		buf.Write(encoded)
+		buf.WriteByte('\n')
+	}
+
+	return ioutil.WriteFile(historyFile(), buf.Bytes(), 0600)
+}
+
+// newHistoryEntry describes a sent request. Credentials in headers and query
+// params are redacted since the log is stored in plain text.
+func newHistoryEntry(req *http.Request, body []byte, status int, duration time.Duration) HistoryEntry {
+	u := *req.URL
+	query := u.Query()
+	for name, values := range query {
+		if sensitiveParam.MatchString(name) {
+			for i := range values {
+				values[i] = redactedValue
+			}
+		}
+	}
+	u.RawQuery = query.Encode()
+
+	headers := map[string][]string{}
+	for name, values := range req.Header {
+		if strings.EqualFold(name, "content-encoding") {
+			// The body is recorded before compression, which is reapplied
+			// when replaying if still configured.
+			continue
+		}
+
+		if sensitiveHeader.MatchString(name) {
+			values = []string{redactedValue}
+		}
+		headers[name] = values
+	}
+
+	entry := HistoryEntry{
+		Time:       time.Now(),
+		Method:     req.Method,
+		URL:        u.String(),
+		Headers:    headers,
+		Status:     status,
+		DurationMS: duration.Milliseconds(),
+	}
+
+	if len(body) > maxHistoryBody || !utf8.Valid(body) {
+		entry.BodyOmitted = true
+	} else {
+		entry.Body = string(body)
+	}
+
+	return entry
+}
+
+// recordHistory appends the request to the history log if `rsh-history` is
+// enabled. Failures are logged rather than failing the request.
+func recordHistory(req *http.Request, body []byte, status int, duration time.Duration) {
+	if !viper.GetBool("rsh-history") {
+		return
+	}
+
+	entries, err := loadHistory()
+	if err == nil {
+		err = saveHistory(append(entries, newHistoryEntry(req, body, status, duration)))
+	}
+
+	if err != nil {
+		LogWarning("Unable to record history: %v", err)
+	}
+}
+
+// historyBody returns the request body to record, leaving the request ready
+// to be sent.
+func historyBody(req *http.Request) []byte {
+	if !viper.GetBool("rsh-history") || req.Body == nil {
+		return nil
+	}
+
+	if err := bufferBody(req); err != nil {
+		return nil
+	}
+
+	body, err := req.GetBody()
+	if err != nil {
+		return nil
+	}
+	defer body.Close()
+
+	data, _ := ioutil.ReadAll(body)
+	return data
+}
+
+// historyEntry returns the nth most recent entry, starting at 1.
+func historyEntry(entries []HistoryEntry, n int) (HistoryEntry, error) {
+	if n < 1 || n > len(entries) {
+		return HistoryEntry{}, fmt.Errorf("no history entry %d, there are %d", n, len(entries))
+	}
+
+	return entries[len(entries)-n], nil
+}
+
+// Request recreates the recorded request. Redacted headers and query params
+// are left out so that the current profile's auth is applied instead.
+func (e HistoryEntry) Request() (*http.Request, error) {
+	var body io.Reader
+	if e.Body != "" {
+		body = strings.NewReader(e.Body)
+	}
+
+	req, err := http.NewRequest(e.Method, e.URL, body)
+	if err != nil {
+		return nil, err
+	}
+
+	query := req.URL.Query()
+	for name, values := range query {
+		if len(values) > 0 && values[0] == redactedValue {
+			query.Del(name)
+		}
+	}
+	req.URL.RawQuery = query.Encode()
+
+	for name, values := range e.Headers {
+		if len(values) > 0 && values[0] == redactedValue {
+			continue
+		}
+		req.Header[name] = values
+	}
+
+	if e.BodyOmitted {
+		LogWarning("The request body was not recorded, replaying without it")
+	}
+
+	return req, nil
+}
+
+// listHistory prints the most recent requests, newest first.
+func listHistory(limit int) error {
+	entries, err := loadHistory()
+	if err != nil {
+		return err
+	}
+
+	if len(entries) == 0 && !viper.GetBool("rsh-history") {
+		LogInfo("No history recorded, enable it by setting rsh-history")
+	}
+
+	if limit > 0 && len(entries) > limit {
+		entries = entries[len(entries)-limit:]
+	}
+
+	// Newest first, numbered so that `history replay 1` is the latest.
+	recent := make([]HistoryEntry, len(entries))
+	for i, entry := range entries {
+		recent[len(entries)-1-i] = entry
+	}
+
+	if viper.GetString("rsh-output-format") == "json" {
+		encoded, err := json.MarshalIndent(recent, "", "  ")
+		if err != nil {
+			return err
+		}
+
+		if tty {
+			if encoded, err = Highlight("json", encoded); err != nil {
+				return err
+			}
+		}
+
+		fmt.Fprintln(Stdout, string(encoded))
+		return nil
+	}
+
+	for i, entry := range recent {
+		status := au.Index(150, entry.Status)
+		if entry.Status == 0 || entry.Status >= 400 {
+			status = au.Index(204, entry.Status)
+		}
+
+		fmt.Fprintf(Stdout, "%4d  %s  %-7s %s %6dms  %s\n", i+1, entry.Time.Local().Format("2006-01-02 15:04:05"), au.Bold(entry.Method), status, entry.DurationMS, entry.URL)
+	}
+
+	return nil
+}
+
+// replayHistory re-sends the nth most recent request.
+func replayHistory(n int) error {
+	entries, err := loadHistory()
+	if err != nil {
+		return err
+	}
+
+	entry, err := historyEntry(entries, n)
+	if err != nil {
+		return err
+	}
+
+	req, err := entry.Request()
+	if err != nil {
+		return err
+	}
+
+	LogInfo("Replaying %s %s", entry.Method, entry.URL)
+	return makeRequestAndFormat(req)
+}
+
+// clearHistory removes all recorded requests.
+func clearHistory() error {
+	if err := os.Remove(historyFile()); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,92 @@
+package cli
+
+import (
+	"io/ioutil"
+	"net/http"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/h2non/gock.v1"
+)
+
+func TestHistoryRedaction(t *testing.T) {
+	req, _ := http.NewRequest(http.MethodPost, "https://example.com/items?api_key=abc&page=2", nil)
+	req.Header.Set("Authorization", "Bearer abc")
+	req.Header.Set("Content-Encoding", "gzip")
+	req.Header.Set("Content-Type", "application/json")
+
+	entry := newHistoryEntry(req, []byte(`{"a": 1}`), 201, 1500*time.Millisecond)
+	assert.Equal(t, "https://example.com/items?api_key=REDACTED&page=2", entry.URL)
+	assert.Equal(t, map[string][]string{
+		"Authorization": {"REDACTED"},
+		"Content-Type":  {"application/json"},
+	}, entry.Headers)
+	assert.Equal(t, `{"a": 1}`, entry.Body)
+	assert.Equal(t, int64(1500), entry.DurationMS)
+
+	replay, err := entry.Request()
+	assert.NoError(t, err)
+	assert.Equal(t, "https://example.com/items?page=2", replay.URL.String())
+	assert.Empty(t, replay.Header.Get("Authorization"))
+	assert.Equal(t, "application/json", replay.Header.Get("Content-Type"))
+
+	binary := newHistoryEntry(req, []byte{0xff, 0xfe}, 201, 0)
+	assert.True(t, binary.BodyOmitted)
+	assert.Empty(t, binary.Body)
+}
+
+func TestHistory(t *testing.T) {
+	defer gock.Off()
+	reset(false)
+
+	dir, err := ioutil.TempDir("", "restish-history")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	viper.Set("config-directory", dir)
+	viper.Set("rsh-history", true)
+	defer viper.Set("rsh-history", false)
+
+	captured := &strings.Builder{}
+	Stdout = captured
+	Stderr = captured
+
+	gock.New("http://example.com").Post("/items").BodyString(`{"name":"one"}`).Reply(201).JSON(map[string]interface{}{"id": 1})
+	gock.New("http://example.com").Get("/items").Reply(200).JSON([]interface{}{})
+
+	req, _ := http.NewRequest(http.MethodPost, "http://example.com/items", strings.NewReader(`{"name":"one"}`))
+	MakeRequestAndFormat(req)
+	req, _ = http.NewRequest(http.MethodGet, "http://example.com/items", nil)
+	MakeRequestAndFormat(req)
+
+	entries, err := loadHistory()
+	assert.NoError(t, err)
+	assert.Len(t, entries, 2)
+	assert.Equal(t, 201, entries[0].Status)
+	assert.Equal(t, `{"name":"one"}`, entries[0].Body)
+
+	captured.Reset()
+	assert.NoError(t, listHistory(0))
+	lines := strings.Split(strings.TrimSpace(captured.String()), "\n")
+	assert.Len(t, lines, 2)
+	assert.Contains(t, lines[0], "GET")
+	assert.Contains(t, lines[1], "POST")
+
+	// Replaying the POST sends the same body again.
+	gock.New("http://example.com").Post("/items").BodyString(`{"name":"one"}`).Reply(201).JSON(map[string]interface{}{"id": 2})
+	assert.NoError(t, replayHistory(2))
+	assert.True(t, gock.IsDone())
+
+	entries, _ = loadHistory()
+	assert.Len(t, entries, 3)
+
+	assert.Error(t, replayHistory(10))
+
+	assert.NoError(t, clearHistory())
+	entries, _ = loadHistory()
+	assert.Empty(t, entries)
+}
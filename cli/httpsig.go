@@ -0,0 +1,226 @@
+package cli
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/sha512"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// signatureNow returns the signature creation time and can be overridden in
+// tests.
+var signatureNow = time.Now
+
+// defaultSignatureComponents are covered by the signature when no components
+// are configured. Headers which are not present on the request are skipped.
+var defaultSignatureComponents = []string{"@method", "@authority", "@path", "@query", "content-type", "content-digest"}
+
+// HTTPMessageSignatureAuth implements RFC 9421 HTTP Message Signatures using
+// an Ed25519 or RSA private key. A `Content-Digest` header (RFC 9530) is added
+// for requests with a body so that the signature can cover the body.
+type HTTPMessageSignatureAuth struct{}
+
+// Parameters define the HTTPMessageSignatureAuth parameter names.
+func (a *HTTPMessageSignatureAuth) Parameters() []AuthParam {
+	return []AuthParam{
+		{Name: "key_id", Required: true, Help: "Key identifier sent as the `keyid` signature parameter"},
+		{Name: "key_file", Required: true, Help: "Path to a PEM encoded Ed25519 or RSA private key"},
+		{Name: "components", Help: "Space separated covered components, defaults to " + strings.Join(defaultSignatureComponents, " ")},
+		{Name: "alg", Help: "Algorithm for RSA keys, rsa-pss-sha512 (default) or rsa-v1_5-sha256"},
+		{Name: "label", Help: "Signature label, defaults to sig1"},
+	}
+}
+
+// OnRequest gets run before the request goes out on the wire.
+func (a *HTTPMessageSignatureAuth) OnRequest(req *http.Request, key string, params map[string]string) error {
+	if params["key_id"] == "" || params["key_file"] == "" {
+		return fmt.Errorf("http-signature auth: key_id and key_file are required")
+	}
+
+	signer, alg, err := loadSigningKey(params["key_file"], params["alg"])
+	if err != nil {
+		return fmt.Errorf("http-signature auth: %w", err)
+	}
+
+	if err := setContentDigest(req); err != nil {
+		return fmt.Errorf("http-signature auth: %w", err)
+	}
+
+	components := strings.Fields(params["components"])
+	optional := false
+	if len(components) == 0 {
+		components = defaultSignatureComponents
+		optional = true
+	}
+
+	covered := []string{}
+	lines := []string{}
+	for _, c := range components {
+		c = strings.ToLower(c)
+		value, ok := signatureComponent(req, c)
+		if !ok {
+			if optional {
+				continue
+			}
+			return fmt.Errorf("http-signature auth: covered component %s is not present in the request", c)
+		}
+
+		covered = append(covered, strconv.Quote(c))
+		lines = append(lines, fmt.Sprintf("%q: %s", c, value))
+	}
+
+	sigParams := fmt.Sprintf("(%s);created=%d;keyid=%q;alg=%q", strings.Join(covered, " "), signatureNow().Unix(), params["key_id"], alg)
+	lines = append(lines, fmt.Sprintf("%q: %s", "@signature-params", sigParams))
+	base := strings.Join(lines, "\n")
+
+	LogDebug("Signature base:\n%s", base)
+
+	sig, err := signer([]byte(base))
+	if err != nil {
+		return fmt.Errorf("http-signature auth: %w", err)
+	}
+
+	label := params["label"]
+	if label == "" {
+		label = "sig1"
+	}
+
+	req.Header.Set("Signature-Input", label+"="+sigParams)
+	req.Header.Set("Signature", label+"=:"+base64.StdEncoding.EncodeToString(sig)+":")
+
+	return nil
+}
+
+// signatureComponent returns the value of a derived component like `@path`
+// or a header field for the signature base.
+func signatureComponent(req *http.Request, name string) (string, bool) {
+	switch name {
+	case "@method":
+		return strings.ToUpper(req.Method), true
+	case "@target-uri":
+		return req.URL.String(), true
+	case "@authority":
+		host := req.Host
+		if host == "" {
+			host = req.URL.Host
+		}
+		return strings.ToLower(host), true
+	case "@scheme":
+		return strings.ToLower(req.URL.Scheme), true
+	case "@request-target":
+		return req.URL.RequestURI(), true
+	case "@path":
+		path := req.URL.EscapedPath()
+		if path == "" {
+			path = "/"
+		}
+		return path, true
+	case "@query":
+		return "?" + req.URL.RawQuery, true
+	}
+
+	if strings.HasPrefix(name, "@") {
+		return "", false
+	}
+
+	values := req.Header.Values(name)
+	if len(values) == 0 {
+		return "", false
+	}
+
+	trimmed := make([]string, len(values))
+	for i, v := range values {
+		trimmed[i] = strings.TrimSpace(v)
+	}
+
+	return strings.Join(trimmed, ", "), true
+}
+
+// setContentDigest sets the RFC 9530 `Content-Digest` header from the
+// buffered request body, if there is one.
+func setContentDigest(req *http.Request) error {
+	if req.Body == nil || req.Header.Get("Content-Digest") != "" {
+		return nil
+	}
+
+	if err := bufferBody(req); err != nil {
+		return err
+	}
+
+	body, err := req.GetBody()
+	if err != nil {
+		return err
+	}
+	defer body.Close()
+
+	data, err := ioutil.ReadAll(body)
+	if err != nil {
+		return err
+	}
+
+	sum := sha256.Sum256(data)
+	req.Header.Set("Content-Digest", "sha-256=:"+base64.StdEncoding.EncodeToString(sum[:])+":")
+
+	return nil
+}
+
+// loadSigningKey loads a PEM encoded private key and returns a function to
+// sign with it along with the algorithm name.
+func loadSigningKey(filename, alg string) (func([]byte) ([]byte, error), string, error) {
+	data, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, "", err
+	}
+
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, "", fmt.Errorf("no PEM data found in %s", filename)
+	}
+
+	var key interface{}
+	if block.Type == "RSA PRIVATE KEY" {
+		key, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	} else {
+		key, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	}
+	if err != nil {
+		return nil, "", err
+	}
+
+	switch k := key.(type) {
+	case ed25519.PrivateKey:
+		if alg != "" && alg != "ed25519" {
+			return nil, "", fmt.Errorf("algorithm %s cannot be used with an Ed25519 key", alg)
+		}
+		return func(base []byte) ([]byte, error) {
+			return ed25519.Sign(k, base), nil
+		}, "ed25519", nil
+	case *rsa.PrivateKey:
+		switch alg {
+		case "", "rsa-pss-sha512":
+			return func(base []byte) ([]byte, error) {
+				sum := sha512.Sum512(base)
+				return rsa.SignPSS(rand.Reader, k, crypto.SHA512, sum[:], &rsa.PSSOptions{SaltLength: 64})
+			}, "rsa-pss-sha512", nil
+		case "rsa-v1_5-sha256":
+			return func(base []byte) ([]byte, error) {
+				sum := sha256.Sum256(base)
+				return rsa.SignPKCS1v15(rand.Reader, k, crypto.SHA256, sum[:])
+			}, alg, nil
+		}
+		return nil, "", fmt.Errorf("algorithm %s cannot be used with an RSA key", alg)
+	}
+
+	return nil, "", fmt.Errorf("unsupported key type %T, expected Ed25519 or RSA", key)
+}
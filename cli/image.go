@@ -0,0 +1,218 @@
+package cli
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"image"
+	"image/png"
+	"io/ioutil"
+	"mime"
+	"os"
+	"strings"
+
+	// Register decoders for converting images to other formats.
+	_ "image/gif"
+	_ "image/jpeg"
+)
+
+// imageProtocol returns the inline image protocol supported by the terminal,
+// one of `iterm`, `kitty` or `sixel`, or an empty string if none is detected.
+func imageProtocol() string {
+	term := os.Getenv("TERM")
+
+	switch {
+	case os.Getenv("TERM_PROGRAM") == "iTerm.app" || os.Getenv("TERM_PROGRAM") == "WezTerm" || os.Getenv("LC_TERMINAL") == "iTerm2":
+		return "iterm"
+	case term == "xterm-kitty" || os.Getenv("KITTY_WINDOW_ID") != "":
+		return "kitty"
+	case strings.Contains(term, "sixel") || term == "foot" || term == "mlterm" || term == "yaft-256color":
+		return "sixel"
+	}
+
+	return ""
+}
+
+// renderImage returns output which shows the image inline in the terminal if
+// it supports an image protocol. Otherwise the image is written to a temp
+// file and a message with its path is returned.
+func renderImage(contentType string, data []byte, tty bool) ([]byte, error) {
+	protocol := ""
+	if tty {
+		protocol = imageProtocol()
+	}
+
+	var rendered []byte
+	var err error
+
+	switch protocol {
+	case "iterm":
+		rendered = itermImage(data)
+	case "kitty":
+		rendered, err = kittyImage(contentType, data)
+	case "sixel":
+		rendered, err = sixelImage(data)
+	}
+
+	if err == nil && rendered != nil {
+		return rendered, nil
+	}
+
+	if err != nil {
+		LogDebug("Unable to display image inline: %v", err)
+	}
+
+	return saveTempImage(contentType, data)
+}
+
+// saveTempImage writes the image to a temp file using an extension matching
+// its content type.
+func saveTempImage(contentType string, data []byte) ([]byte, error) {
+	ext := ""
+	if exts, err := mime.ExtensionsByType(contentType); err == nil && len(exts) > 0 {
+		ext = exts[0]
+	}
+
+	f, err := ioutil.TempFile("", "restish-*"+ext)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	if _, err := f.Write(data); err != nil {
+		return nil, err
+	}
+
+	return []byte(fmt.Sprintf("Image saved to %s\n", f.Name())), nil
+}
+
+// itermImage uses the iTerm2 inline image protocol, which is also supported
+// by terminals like WezTerm. Any image format the terminal can decode works.
+func itermImage(data []byte) []byte {
+	return []byte(fmt.Sprintf("\x1b]1337;File=inline=1;size=%d;preserveAspectRatio=1:%s\a\n", len(data), base64.StdEncoding.EncodeToString(data)))
+}
+
+// kittyImage uses the kitty graphics protocol. Images are sent as PNG in
+// base64 chunks of at most 4096 bytes.
+func kittyImage(contentType string, data []byte) ([]byte, error) {
+	if contentType != "image/png" {
+		img, _, err := image.Decode(bytes.NewReader(data))
+		if err != nil {
+			return nil, err
+		}
+
+		buf := &bytes.Buffer{}
+		if err := png.Encode(buf, img); err != nil {
+			return nil, err
+		}
+		data = buf.Bytes()
+	}
+
+	encoded := base64.StdEncoding.EncodeToString(data)
+	out := &bytes.Buffer{}
+	for i := 0; i < len(encoded); i += 4096 {
+		end := i + 4096
+		more := 1
+		if end >= len(encoded) {
+			end = len(encoded)
+			more = 0
+		}
+
+		if i == 0 {
+			fmt.Fprintf(out, "\x1b_Gf=100,a=T,m=%d;%s\x1b\\", more, encoded[i:end])
+		} else {
+			fmt.Fprintf(out, "\x1b_Gm=%d;%s\x1b\\", more, encoded[i:end])
+		}
+	}
+	out.WriteString("\n")
+
+	return out.Bytes(), nil
+}
+
+// sixelImage encodes the image as sixels using a 6x6x6 color cube palette.
+// Mostly transparent pixels are left blank.
+func sixelImage(data []byte) ([]byte, error) {
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+
+	bounds := img.Bounds()
+	width, height := bounds.Dx(), bounds.Dy()
+
+	// Map each pixel to a palette index, or -1 if transparent.
+	pixels := make([]int, width*height)
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			r, g, b, a := img.At(bounds.Min.X+x, bounds.Min.Y+y).RGBA()
+			if a < 0x8000 {
+				pixels[y*width+x] = -1
+				continue
+			}
+			pixels[y*width+x] = int(r*5/0xffff)*36 + int(g*5/0xffff)*6 + int(b*5/0xffff)
+		}
+	}
+
+	out := &bytes.Buffer{}
+	fmt.Fprintf(out, "\x1bP0;1;0q\"1;1;%d;%d", width, height)
+	for i := 0; i < 216; i++ {
+		fmt.Fprintf(out, "#%d;2;%d;%d;%d", i, i/36*20, i/6%6*20, i%6*20)
+	}
+
+	for band := 0; band < height; band += 6 {
+		// Find the colors used in this band of six rows.
+		used := map[int]bool{}
+		colors := []int{}
+		for y := band; y < band+6 && y < height; y++ {
+			for x := 0; x < width; x++ {
+				if c := pixels[y*width+x]; c != -1 && !used[c] {
+					used[c] = true
+					colors = append(colors, c)
+				}
+			}
+		}
+
+		for i, c := range colors {
+			if i > 0 {
+				// Return to the start of the band to draw the next color.
+				out.WriteByte('$')
+			}
+			fmt.Fprintf(out, "#%d", c)
+
+			run, last := 0, byte(0)
+			flush := func() {
+				if run > 3 {
+					fmt.Fprintf(out, "!%d%c", run, last)
+				} else {
+					for j := 0; j < run; j++ {
+						out.WriteByte(last)
+					}
+				}
+			}
+
+			for x := 0; x < width; x++ {
+				bits := 0
+				for k := 0; k < 6 && band+k < height; k++ {
+					if pixels[(band+k)*width+x] == c {
+						bits |= 1 << k
+					}
+				}
+
+				char := byte(63 + bits)
+				if char == last {
+					run++
+					continue
+				}
+				flush()
+				run, last = 1, char
+			}
+			flush()
+		}
+
+		out.WriteByte('-')
+	}
+
+	out.WriteString("\x1b\\\n")
+
+	return out.Bytes(), nil
+}
@@ -0,0 +1,83 @@
+package cli
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/png"
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/h2non/gock.v1"
+)
+
+func testPNG(t *testing.T) []byte {
+	img := image.NewRGBA(image.Rect(0, 0, 2, 7))
+	for y := 0; y < 7; y++ {
+		img.Set(0, y, color.RGBA{255, 0, 0, 255})
+		img.Set(1, y, color.RGBA{0, 0, 255, 255})
+	}
+
+	buf := &bytes.Buffer{}
+	assert.NoError(t, png.Encode(buf, img))
+	return buf.Bytes()
+}
+
+func TestImageProtocol(t *testing.T) {
+	for _, name := range []string{"TERM", "TERM_PROGRAM", "LC_TERMINAL", "KITTY_WINDOW_ID"} {
+		defer os.Setenv(name, os.Getenv(name))
+		os.Unsetenv(name)
+	}
+
+	assert.Equal(t, "", imageProtocol())
+
+	os.Setenv("TERM", "foot")
+	assert.Equal(t, "sixel", imageProtocol())
+
+	os.Setenv("TERM", "xterm-kitty")
+	assert.Equal(t, "kitty", imageProtocol())
+
+	os.Setenv("TERM_PROGRAM", "iTerm.app")
+	assert.Equal(t, "iterm", imageProtocol())
+}
+
+func TestRenderImage(t *testing.T) {
+	data := testPNG(t)
+
+	assert.True(t, strings.HasPrefix(string(itermImage(data)), "\x1b]1337;File=inline=1;"))
+
+	kitty, err := kittyImage("image/png", data)
+	assert.NoError(t, err)
+	assert.True(t, strings.HasPrefix(string(kitty), "\x1b_Gf=100,a=T,m=0;"))
+
+	sixel, err := sixelImage(data)
+	assert.NoError(t, err)
+	// Two colors in the first band of six rows, then one row in the second.
+	assert.Equal(t, "#180~?$#5?~-#180@?$#5?@-\x1b\\\n", string(sixel[strings.Index(string(sixel), "#180~"):]))
+}
+
+func TestShowImageTempFile(t *testing.T) {
+	defer gock.Off()
+
+	gock.New("http://example.com").
+		Get("/avatar").
+		Reply(200).
+		SetHeader("Content-Type", "image/png").
+		Body(bytes.NewReader(testPNG(t)))
+
+	captured := run("http://example.com/avatar --rsh-show-image")
+
+	idx := strings.Index(captured, "Image saved to ")
+	assert.NotEqual(t, -1, idx)
+
+	filename := strings.TrimSpace(captured[idx+len("Image saved to "):])
+	defer os.Remove(filename)
+	assert.True(t, strings.HasSuffix(filename, ".png"))
+
+	data, err := ioutil.ReadFile(filename)
+	assert.NoError(t, err)
+	assert.Equal(t, testPNG(t), data)
+}
@@ -0,0 +1,122 @@
+package cli
+
+import (
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"io/ioutil"
+	"strings"
+	"unicode/utf8"
+
+	jmespath "github.com/danielgtaylor/go-jmespath-plus"
+)
+
+// inflateString base64-decodes then gzip-inflates the value. The result is
+// decoded if it contains JSON, otherwise it is returned as a string, or as
+// bytes if it is binary.
+func inflateString(s string) (interface{}, error) {
+	s = strings.TrimSpace(s)
+
+	var data []byte
+	var err error
+	for _, enc := range []*base64.Encoding{base64.StdEncoding, base64.URLEncoding, base64.RawStdEncoding, base64.RawURLEncoding} {
+		if data, err = enc.DecodeString(s); err == nil {
+			break
+		}
+	}
+	if err != nil {
+		return nil, errors.New("not valid base64")
+	}
+
+	if len(data) < 2 || data[0] != 0x1f || data[1] != 0x8b {
+		return nil, errors.New("not gzip compressed")
+	}
+
+	reader, err := GzipEncoding{}.Reader(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+
+	inflated, err := ioutil.ReadAll(reader)
+	if err != nil {
+		return nil, err
+	}
+
+	if decoded, ok := parseEmbeddedJSON(string(inflated)); ok {
+		return decoded, nil
+	}
+
+	if utf8.Valid(inflated) {
+		return string(inflated), nil
+	}
+
+	return inflated, nil
+}
+
+// inflateValues walks the value and replaces selected strings with their
+// inflated content. Values which can't be inflated are left as-is.
+func inflateValues(v interface{}, selected map[string]bool) interface{} {
+	switch t := v.(type) {
+	case map[string]interface{}:
+		for k, item := range t {
+			t[k] = inflateValues(item, selected)
+		}
+	case map[interface{}]interface{}:
+		for k, item := range t {
+			t[k] = inflateValues(item, selected)
+		}
+	case []interface{}:
+		for i, item := range t {
+			t[i] = inflateValues(item, selected)
+		}
+	case string:
+		if !selected[t] {
+			return t
+		}
+
+		inflated, err := inflateString(t)
+		if err != nil {
+			LogWarning("Leaving value unchanged, cannot inflate: %v", err)
+			return t
+		}
+		return inflated
+	}
+
+	return v
+}
+
+// InflateResponse decodes base64-encoded gzip payloads embedded in the
+// response body. The JMESPath `expr` is run against the response and any
+// string values it selects are inflated.
+func InflateResponse(resp *Response, expr string) error {
+	if expr == "" {
+		return nil
+	}
+
+	result, err := jmespath.Search(expr, makeJSONSafe(resp.Map()))
+	if err != nil {
+		return err
+	}
+
+	selected := map[string]bool{}
+	var collect func(interface{})
+	collect = func(v interface{}) {
+		switch t := v.(type) {
+		case string:
+			selected[t] = true
+		case []interface{}:
+			for _, item := range t {
+				collect(item)
+			}
+		}
+	}
+	collect(result)
+
+	if len(selected) == 0 {
+		return nil
+	}
+
+	resp.Body = inflateValues(resp.Body, selected)
+
+	return nil
+}
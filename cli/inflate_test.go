@@ -0,0 +1,50 @@
+package cli
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/base64"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func gzipBase64(s string) string {
+	buf := &bytes.Buffer{}
+	w := gzip.NewWriter(buf)
+	w.Write([]byte(s))
+	w.Close()
+	return base64.StdEncoding.EncodeToString(buf.Bytes())
+}
+
+func TestInflateResponse(t *testing.T) {
+	captured := &strings.Builder{}
+	Stderr = captured
+
+	resp := Response{
+		Status:  200,
+		Headers: map[string]string{},
+		Body: map[string]interface{}{
+			"records": []interface{}{
+				map[string]interface{}{"data": gzipBase64(`{"id": 1}`)},
+				map[string]interface{}{"data": gzipBase64("plain text")},
+				map[string]interface{}{"data": "bm90IGd6aXA="},
+			},
+			"other": gzipBase64("untouched"),
+		},
+	}
+	other := resp.Body.(map[string]interface{})["other"]
+
+	assert.NoError(t, InflateResponse(&resp, "body.records[].data"))
+	assert.Equal(t, map[string]interface{}{
+		"records": []interface{}{
+			map[string]interface{}{"data": map[string]interface{}{"id": 1.0}},
+			map[string]interface{}{"data": "plain text"},
+			map[string]interface{}{"data": "bm90IGd6aXA="},
+		},
+		"other": other,
+	}, resp.Body)
+
+	assert.Contains(t, captured.String(), "cannot inflate: not gzip compressed")
+}
@@ -1,13 +1,22 @@
 package cli
 
 import (
+	"bytes"
+	"encoding/base64"
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
 	"io/ioutil"
+	"mime"
+	"mime/multipart"
+	"net/textproto"
+	"net/url"
 	"os"
+	"path/filepath"
 	"strings"
 
 	"github.com/danielgtaylor/openapi-cli-generator/shorthand"
+	"github.com/spf13/viper"
 	yaml "gopkg.in/yaml.v2"
 )
 
@@ -30,11 +39,289 @@ func DeepAssign(target, source map[string]interface{}) {
 	}
 }
 
+// lineCol converts a JSON syntax error offset into a 1-based line and column
+// number, which is easier to find in a text editor. The offset includes the
+// offending byte itself, so the position points at that byte.
+func lineCol(data []byte, offset int64) (int, int) {
+	if offset > int64(len(data)) {
+		offset = int64(len(data))
+	}
+
+	if offset > 0 {
+		offset--
+	}
+
+	line, col := 1, 1
+	for _, b := range data[:offset] {
+		if b == '\n' {
+			line++
+			col = 1
+		} else {
+			col++
+		}
+	}
+
+	return line, col
+}
+
+// validateJSON returns a descriptive error including the line and column of
+// the problem if the data is not well-formed JSON. The source is used to
+// describe where the data came from, e.g. `stdin` or a filename.
+func validateJSON(source string, data []byte) error {
+	var tmp interface{}
+	if err := json.Unmarshal(data, &tmp); err != nil {
+		if se, ok := err.(*json.SyntaxError); ok {
+			line, col := lineCol(data, se.Offset)
+			return fmt.Errorf("invalid JSON body from %s at line %d, column %d: %v", source, line, col, se)
+		}
+
+		return fmt.Errorf("invalid JSON body from %s: %v", source, err)
+	}
+
+	return nil
+}
+
+// hasRawBody returns whether a raw binary body was passed via the hex or
+// base64 body flags.
+func hasRawBody() bool {
+	return viper.GetString("rsh-body-hex") != "" || viper.GetString("rsh-body-base64") != ""
+}
+
+// getRawBody decodes a raw binary body passed via the hex or base64 body
+// flags. Whitespace is ignored, so e.g. `de ad be ef` works.
+func getRawBody() ([]byte, error) {
+	if h := viper.GetString("rsh-body-hex"); h != "" {
+		if viper.GetString("rsh-body-base64") != "" {
+			return nil, fmt.Errorf("cannot use both --rsh-body-hex and --rsh-body-base64")
+		}
+
+		decoded, err := hex.DecodeString(strings.Join(strings.Fields(h), ""))
+		if err != nil {
+			return nil, fmt.Errorf("invalid hex body: %v", err)
+		}
+
+		return decoded, nil
+	}
+
+	decoded, err := base64.StdEncoding.DecodeString(strings.Join(strings.Fields(viper.GetString("rsh-body-base64")), ""))
+	if err != nil {
+		return nil, fmt.Errorf("invalid base64 body: %v", err)
+	}
+
+	return decoded, nil
+}
+
+// flattenForm adds a structured value to form values. Nested objects use
+// bracketed keys like `user[name]` and arrays use their index like
+// `tags[0]`. Existing values for the same key are replaced.
+func flattenForm(values url.Values, prefix string, v interface{}) {
+	switch t := v.(type) {
+	case map[string]interface{}:
+		for k, item := range t {
+			key := k
+			if prefix != "" {
+				key = prefix + "[" + k + "]"
+			}
+			flattenForm(values, key, item)
+		}
+	case []interface{}:
+		for i, item := range t {
+			flattenForm(values, fmt.Sprintf("%s[%d]", prefix, i), item)
+		}
+	case nil:
+		values.Set(prefix, "")
+	default:
+		values.Set(prefix, fmt.Sprintf("%v", t))
+	}
+}
+
+// bodyFileArg returns the filename if the only argument is a reference to a
+// file like `@body.json`, or `-` for `@-` which means stdin.
+func bodyFileArg(args []string) (string, bool) {
+	if len(args) != 1 || len(args[0]) < 2 || !strings.HasPrefix(args[0], "@") {
+		return "", false
+	}
+
+	return args[0][1:], true
+}
+
+// fileTypes maps file extensions to content types for body files, taking
+// precedence over the system's MIME types. Entries in the `file-types` config
+// map override these.
+var fileTypes = map[string]string{
+	".json":    "application/json",
+	".yaml":    "application/yaml",
+	".yml":     "application/yaml",
+	".cbor":    "application/cbor",
+	".msgpack": "application/msgpack",
+	".toml":    "application/toml",
+	".ndjson":  "application/x-ndjson",
+	".csv":     "text/csv",
+	".xml":     "application/xml",
+}
+
+// bodyFileContentType guesses the content type of a body file from its
+// extension. Returns an empty string for stdin.
+func bodyFileContentType(filename string) string {
+	if filename == "-" {
+		return ""
+	}
+
+	ext := strings.ToLower(filepath.Ext(filename))
+
+	for k, v := range viper.GetStringMapString("file-types") {
+		if "."+strings.TrimPrefix(strings.ToLower(k), ".") == ext {
+			return v
+		}
+	}
+
+	if ct, ok := fileTypes[ext]; ok {
+		return ct
+	}
+
+	if ct := mime.TypeByExtension(ext); ct != "" {
+		return ct
+	}
+
+	return "application/octet-stream"
+}
+
+// readBodyFile reads a raw request body from a file, or from stdin if the
+// filename is `-`.
+func readBodyFile(filename string) ([]byte, error) {
+	if filename == "-" {
+		return ioutil.ReadAll(os.Stdin)
+	}
+
+	data, err := ioutil.ReadFile(filename)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, fmt.Errorf("cannot read body: file %s does not exist", filename)
+		}
+		return nil, fmt.Errorf("cannot read body: %w", err)
+	}
+
+	return data, nil
+}
+
+// multipartContentType returns a `multipart/form-data` content type with a
+// new random boundary.
+func multipartContentType() string {
+	return "multipart/form-data; boundary=" + multipart.NewWriter(nil).Boundary()
+}
+
+// quoteEscaper escapes values for quoted strings in multipart headers.
+var quoteEscaper = strings.NewReplacer("\\", "\\\\", `"`, "\\\"")
+
+// getMultipartBody builds a `multipart/form-data` body from arguments like
+// `name=value` for fields and `name@filename` for files. The content type of
+// each file part is inferred from its extension.
+func getMultipartBody(mediaType string, args []string) (string, error) {
+	_, params, err := mime.ParseMediaType(mediaType)
+	if err != nil || params["boundary"] == "" {
+		return "", fmt.Errorf("multipart/form-data requires a boundary")
+	}
+
+	buf := &bytes.Buffer{}
+	writer := multipart.NewWriter(buf)
+	if err := writer.SetBoundary(params["boundary"]); err != nil {
+		return "", err
+	}
+
+	for _, arg := range args {
+		i := strings.IndexAny(arg, "=@")
+		if i < 1 {
+			return "", fmt.Errorf("invalid multipart argument %s, expected name=value or name@filename", arg)
+		}
+
+		name := arg[:i]
+		if arg[i] == '=' {
+			if err := writer.WriteField(name, arg[i+1:]); err != nil {
+				return "", err
+			}
+			continue
+		}
+
+		filename := arg[i+1:]
+		data, err := readBodyFile(filename)
+		if err != nil {
+			return "", err
+		}
+
+		h := textproto.MIMEHeader{}
+		h.Set("Content-Disposition", fmt.Sprintf(`form-data; name="%s"; filename="%s"`, quoteEscaper.Replace(name), quoteEscaper.Replace(filepath.Base(filename))))
+		if ct := bodyFileContentType(filename); ct != "" {
+			h.Set("Content-Type", ct)
+		} else {
+			h.Set("Content-Type", "application/octet-stream")
+		}
+
+		part, err := writer.CreatePart(h)
+		if err != nil {
+			return "", err
+		}
+		part.Write(data)
+		LogDebug("Multipart file %s from %s is %d bytes", name, filename, len(data))
+	}
+
+	if err := writer.Close(); err != nil {
+		return "", err
+	}
+
+	return buf.String(), nil
+}
+
 // GetBody returns the request body if one was passed either as shorthand
-// arguments or via stdin.
+// arguments or via stdin. Shorthand arguments are marshalled based on the
+// media type, e.g. as JSON, YAML, CBOR, MessagePack, or
+// `application/x-www-form-urlencoded`.
+// Raw binary bodies passed via `--rsh-body-hex` or `--rsh-body-base64` or
+// read from a file via `@filename` (`@-` for stdin) are returned as-is.
+// A `multipart/form-data` media type builds the body from `name=value` and
+// `name@filename` arguments instead.
 func GetBody(mediaType string, args []string) (string, error) {
 	var body string
 
+	if hasRawBody() {
+		if len(args) > 0 {
+			return "", fmt.Errorf("cannot combine a raw body with shorthand arguments")
+		}
+
+		raw, err := getRawBody()
+		if err != nil {
+			return "", err
+		}
+
+		LogDebug("Raw body is %d bytes", len(raw))
+		return string(raw), nil
+	}
+
+	if filename, ok := bodyFileArg(args); ok {
+		// Send the file contents as-is, bypassing the shorthand.
+		data, err := readBodyFile(filename)
+		if err != nil {
+			return "", err
+		}
+
+		source := filename
+		if filename == "-" {
+			source = "stdin"
+		}
+
+		if (JSON{}).Detect(mediaType) {
+			if err := validateJSON(source, data); err != nil {
+				return "", err
+			}
+		}
+
+		LogDebug("Body from %s is %d bytes", source, len(data))
+		return string(data), nil
+	}
+
+	if strings.HasPrefix(mediaType, "multipart/form-data") && len(args) > 0 {
+		return getMultipartBody(mediaType, args)
+	}
+
 	info, err := os.Stdin.Stat()
 	if err != nil {
 		return "", err
@@ -48,6 +335,12 @@ func GetBody(mediaType string, args []string) (string, error) {
 
 		body = string(input)
 		LogDebug("Body from stdin is: %s", body)
+
+		if (JSON{}).Detect(mediaType) {
+			if err := validateJSON("stdin", input); err != nil {
+				return "", err
+			}
+		}
 	}
 
 	if len(args) > 0 {
@@ -57,11 +350,12 @@ func GetBody(mediaType string, args []string) (string, error) {
 			return "", err
 		}
 
-		if strings.Contains(mediaType, "json") {
+		if (JSON{}).Detect(mediaType) || (NDJSON{}).Detect(mediaType) {
 			if body != "" {
-				// Have a body from stdin. Should be JSON, so let's merge.
+				// Have a body from stdin. Should be JSON, so let's merge. Numbers are
+				// kept as-is so large integers are not rounded.
 				var curBody map[string]interface{}
-				if err := json.Unmarshal([]byte(body), &curBody); err != nil {
+				if err := unmarshalPrecise([]byte(body), &curBody); err != nil {
 					return "", err
 				}
 
@@ -92,6 +386,36 @@ func GetBody(mediaType string, args []string) (string, error) {
 				return "", err
 			}
 
+			body = string(marshalled)
+		} else if strings.Contains(mediaType, "x-www-form-urlencoded") {
+			values := url.Values{}
+			if body != "" {
+				// Have a body from stdin. Should be form encoded, so let's merge.
+				values, err = url.ParseQuery(strings.TrimSpace(body))
+				if err != nil {
+					return "", err
+				}
+			}
+
+			flattenForm(values, "", result)
+			body = values.Encode()
+		} else if (CBOR{}).Detect(mediaType) || (MsgPack{}).Detect(mediaType) || (Protobuf{}).Detect(mediaType) {
+			if body != "" {
+				// Have a binary body from stdin in the same format, so let's merge.
+				var curBody map[string]interface{}
+				if err := Unmarshal(mediaType, []byte(body), &curBody); err != nil {
+					return "", err
+				}
+
+				DeepAssign(curBody, result)
+				result = curBody
+			}
+
+			marshalled, err := Marshal(mediaType, result)
+			if err != nil {
+				return "", err
+			}
+
 			body = string(marshalled)
 		} else {
 			return "", fmt.Errorf("Not sure how to marshal %s", mediaType)
@@ -0,0 +1,136 @@
+package cli
+
+import (
+	"io/ioutil"
+	"mime"
+	"mime/multipart"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidateJSON(t *testing.T) {
+	assert.NoError(t, validateJSON("stdin", []byte(`{"hello": "world"}`)))
+
+	err := validateJSON("stdin", []byte("{\n  \"hello\": \"world\",\n  \"bad\": \n}"))
+	assert.EqualError(t, err, "invalid JSON body from stdin at line 4, column 1: invalid character '}' looking for beginning of value")
+}
+
+func TestFlattenForm(t *testing.T) {
+	values := url.Values{}
+	values.Set("name", "original")
+
+	flattenForm(values, "", map[string]interface{}{
+		"name": "Kari & co",
+		"role": nil,
+		"user": map[string]interface{}{
+			"id":     1.0,
+			"active": true,
+		},
+		"tags": []interface{}{"a", "b"},
+	})
+
+	assert.Equal(t, "name=Kari+%26+co&role=&tags%5B0%5D=a&tags%5B1%5D=b&user%5Bactive%5D=true&user%5Bid%5D=1", values.Encode())
+}
+
+func TestBodyFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "restish-body")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	filename := filepath.Join(dir, "body.json")
+	assert.NoError(t, ioutil.WriteFile(filename, []byte(`{"hello": "world"}`), 0600))
+
+	body, err := GetBody("application/json", []string{"@" + filename})
+	assert.NoError(t, err)
+	assert.Equal(t, `{"hello": "world"}`, body)
+
+	_, err = GetBody("application/json", []string{"@" + filepath.Join(dir, "missing.json")})
+	assert.EqualError(t, err, "cannot read body: file "+filepath.Join(dir, "missing.json")+" does not exist")
+
+	assert.NoError(t, ioutil.WriteFile(filename, []byte(`{"hello": }`), 0600))
+	_, err = GetBody("application/json", []string{"@" + filename})
+	assert.Error(t, err)
+}
+
+func TestBodyFileNDJSON(t *testing.T) {
+	dir, err := ioutil.TempDir("", "restish-body")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	// Each line is a JSON document, but the file as a whole is not valid JSON
+	// so it must not be validated as such.
+	filename := filepath.Join(dir, "events.ndjson")
+	assert.NoError(t, ioutil.WriteFile(filename, []byte("{\"id\": 1}\n{\"id\": 2}\n"), 0600))
+
+	mediaType := bodyFileContentType(filename)
+	assert.Equal(t, "application/x-ndjson", mediaType)
+
+	body, err := GetBody(mediaType, []string{"@" + filename})
+	assert.NoError(t, err)
+	assert.Equal(t, "{\"id\": 1}\n{\"id\": 2}\n", body)
+}
+
+func TestBodyFileContentType(t *testing.T) {
+	assert.Equal(t, "application/json", bodyFileContentType("body.json"))
+	assert.Equal(t, "application/yaml", bodyFileContentType("body.YML"))
+	assert.Equal(t, "application/octet-stream", bodyFileContentType("body.unknown-ext"))
+	assert.Equal(t, "", bodyFileContentType("-"))
+
+	viper.Set("file-types", map[string]string{".proto": "application/x-protobuf", "json": "application/vnd.custom+json"})
+	defer viper.Set("file-types", nil)
+	assert.Equal(t, "application/x-protobuf", bodyFileContentType("body.proto"))
+	assert.Equal(t, "application/vnd.custom+json", bodyFileContentType("body.json"))
+}
+
+func TestMultipartBody(t *testing.T) {
+	dir, err := ioutil.TempDir("", "restish-multipart")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	filename := filepath.Join(dir, "photo.png")
+	assert.NoError(t, ioutil.WriteFile(filename, []byte("fake-png"), 0600))
+
+	ct := multipartContentType()
+	body, err := GetBody(ct, []string{"title=My photo", "photo@" + filename})
+	assert.NoError(t, err)
+
+	_, params, _ := mime.ParseMediaType(ct)
+	reader := multipart.NewReader(strings.NewReader(body), params["boundary"])
+
+	part, err := reader.NextPart()
+	assert.NoError(t, err)
+	assert.Equal(t, "title", part.FormName())
+	data, _ := ioutil.ReadAll(part)
+	assert.Equal(t, "My photo", string(data))
+
+	part, err = reader.NextPart()
+	assert.NoError(t, err)
+	assert.Equal(t, "photo", part.FormName())
+	assert.Equal(t, "photo.png", part.FileName())
+	assert.Equal(t, "image/png", part.Header.Get("Content-Type"))
+	data, _ = ioutil.ReadAll(part)
+	assert.Equal(t, "fake-png", string(data))
+
+	_, err = GetBody(ct, []string{"invalid"})
+	assert.Error(t, err)
+}
+
+func TestGetBodyBinaryShorthand(t *testing.T) {
+	reset(false)
+
+	for _, ct := range []string{"application/cbor", "application/msgpack"} {
+		body, err := GetBody(ct, []string{"name:", "foo,", "tags[]:", "a"})
+		assert.NoError(t, err)
+
+		var decoded map[string]interface{}
+		assert.NoError(t, Unmarshal(ct, []byte(body), &decoded))
+		assert.Equal(t, "foo", decoded["name"], ct)
+		assert.Len(t, decoded["tags"], 1, ct)
+	}
+}
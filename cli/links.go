@@ -2,8 +2,11 @@ package cli
 
 import (
 	"fmt"
+	"net/http"
 	"net/url"
 	"reflect"
+	"sort"
+	"strings"
 
 	"github.com/mitchellh/mapstructure"
 	link "github.com/tent/http-link-go"
@@ -13,6 +16,25 @@ import (
 type Link struct {
 	Rel string `json:"rel"`
 	URI string `json:"uri"`
+
+	// Templated is set for RFC 6570 URI templates like `/users/{id}`, which
+	// must be expanded before they can be followed.
+	Templated bool `json:"templated,omitempty"`
+}
+
+// Expand a templated link using the given variable values. Returns a new
+// link which is no longer templated. Non-templated links are returned as-is.
+func (l *Link) Expand(values map[string]string) (*Link, error) {
+	if !l.Templated {
+		return l, nil
+	}
+
+	uri, err := ExpandURITemplate(l.URI, values)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Link{Rel: l.Rel, URI: uri}, nil
 }
 
 // Links represents a map of `rel` => list of linke relations.
@@ -41,6 +63,16 @@ func ParseLinks(base *url.URL, resp *Response) error {
 
 	for _, links := range resp.Links {
 		for _, l := range links {
+			if l.Templated || IsURITemplate(l.URI) {
+				l.Templated = true
+				resolved, err := resolveTemplate(base, l.URI)
+				if err != nil {
+					return err
+				}
+				l.URI = resolved
+				continue
+			}
+
 			p, err := url.Parse(l.URI)
 			if err != nil {
 				return err
@@ -54,6 +86,31 @@ func ParseLinks(base *url.URL, resp *Response) error {
 	return nil
 }
 
+// resolveTemplate resolves a URI template against a base URL. Template
+// expressions are swapped out for placeholders while resolving so that their
+// braces and operators are not escaped.
+func resolveTemplate(base *url.URL, template string) (string, error) {
+	exprs := uriTemplateExpr.FindAllString(template, -1)
+
+	i := 0
+	replaced := uriTemplateExpr.ReplaceAllStringFunc(template, func(expr string) string {
+		i++
+		return fmt.Sprintf("rshtpl%dx", i-1)
+	})
+
+	p, err := url.Parse(replaced)
+	if err != nil {
+		return "", err
+	}
+
+	resolved := base.ResolveReference(p).String()
+	for i, expr := range exprs {
+		resolved = strings.Replace(resolved, fmt.Sprintf("rshtpl%dx", i), expr, 1)
+	}
+
+	return resolved, nil
+}
+
 // LinkHeaderParser parses RFC 5988 HTTP link relation headers.
 type LinkHeaderParser struct{}
 
@@ -78,7 +135,8 @@ func (l LinkHeaderParser) ParseLinks(resp *Response) error {
 
 // halLink represents a single link in a HAL response.
 type halLink struct {
-	Href string `mapstructure:"href"`
+	Href      string `mapstructure:"href"`
+	Templated bool   `mapstructure:"templated"`
 }
 
 // halBody represents the top-level HAL response body.
@@ -100,8 +158,9 @@ func (h HALParser) ParseLinks(resp *Response) error {
 			}
 
 			resp.Links[rel] = append(resp.Links[rel], &Link{
-				Rel: rel,
-				URI: link.Href,
+				Rel:       rel,
+				URI:       link.Href,
+				Templated: link.Templated,
 			})
 		}
 	}
@@ -242,3 +301,216 @@ func (j JSONAPIParser) ParseLinks(resp *Response) error {
 
 	return nil
 }
+
+type collectionJSONLink struct {
+	Rel  string `mapstructure:"rel"`
+	Href string `mapstructure:"href"`
+}
+
+type collectionJSONQuery struct {
+	Rel  string `mapstructure:"rel"`
+	Href string `mapstructure:"href"`
+	Data []struct {
+		Name string `mapstructure:"name"`
+	} `mapstructure:"data"`
+}
+
+type collectionJSONItem struct {
+	Href  string               `mapstructure:"href"`
+	Links []collectionJSONLink `mapstructure:"links"`
+}
+
+type collectionJSONBody struct {
+	Collection struct {
+		Href    string                `mapstructure:"href"`
+		Links   []collectionJSONLink  `mapstructure:"links"`
+		Items   []collectionJSONItem  `mapstructure:"items"`
+		Queries []collectionJSONQuery `mapstructure:"queries"`
+	} `mapstructure:"collection"`
+}
+
+// CollectionJSONParser parses Collection+JSON hypermedia links. The collection
+// and item `href` become `self` and `item` links, while queries become
+// templated links with their query params as template variables.
+type CollectionJSONParser struct{}
+
+// ParseLinks processes the links in a parsed response.
+func (c CollectionJSONParser) ParseLinks(resp *Response) error {
+	body := collectionJSONBody{}
+	if err := mapstructure.Decode(resp.Body, &body); err != nil {
+		return nil
+	}
+
+	add := func(rel, href string, templated bool) {
+		if rel == "" || href == "" {
+			return
+		}
+
+		resp.Links[rel] = append(resp.Links[rel], &Link{
+			Rel:       rel,
+			URI:       href,
+			Templated: templated,
+		})
+	}
+
+	collection := body.Collection
+	add("self", collection.Href, false)
+
+	for _, link := range collection.Links {
+		add(link.Rel, link.Href, false)
+	}
+
+	for _, item := range collection.Items {
+		add("item", item.Href, false)
+
+		for _, link := range item.Links {
+			add(link.Rel, link.Href, false)
+		}
+	}
+
+	for _, query := range collection.Queries {
+		names := []string{}
+		for _, d := range query.Data {
+			if d.Name != "" {
+				names = append(names, d.Name)
+			}
+		}
+
+		if len(names) == 0 {
+			add(query.Rel, query.Href, false)
+			continue
+		}
+
+		op := "?"
+		if strings.Contains(query.Href, "?") {
+			op = "&"
+		}
+
+		add(query.Rel, query.Href+"{"+op+strings.Join(names, ",")+"}", true)
+	}
+
+	return nil
+}
+
+// ODataParser parses OData v4 navigation links. Responses are recognized by
+// their `@odata.context`, and entities in the `value` array wrapper used for
+// collections are parsed as well.
+type ODataParser struct{}
+
+// ParseLinks processes the links in a parsed response.
+func (o ODataParser) ParseLinks(resp *Response) error {
+	b, ok := resp.Body.(map[string]interface{})
+	if !ok {
+		return nil
+	}
+
+	if _, ok := b["@odata.context"]; !ok {
+		return nil
+	}
+
+	add := func(rel string, v interface{}) {
+		if uri, ok := v.(string); ok && uri != "" {
+			resp.Links[rel] = append(resp.Links[rel], &Link{
+				Rel: rel,
+				URI: uri,
+			})
+		}
+	}
+
+	add("next", b["@odata.nextLink"])
+	add("delta", b["@odata.deltaLink"])
+	o.entityLinks(b, "self", add)
+
+	if items, ok := b["value"].([]interface{}); ok {
+		for _, item := range items {
+			if m, ok := item.(map[string]interface{}); ok {
+				o.entityLinks(m, "item", add)
+			}
+		}
+	}
+
+	return nil
+}
+
+// entityLinks adds the ID and navigation property links of an entity. The
+// entity's own ID uses the `idRel` relation, while navigation properties like
+// `Orders@odata.navigationLink` use the property name.
+func (o ODataParser) entityLinks(entity map[string]interface{}, idRel string, add func(string, interface{})) {
+	add(idRel, entity["@odata.id"])
+	add("edit", entity["@odata.editLink"])
+
+	for k, v := range entity {
+		if strings.HasSuffix(k, "@odata.navigationLink") {
+			add(strings.TrimSuffix(k, "@odata.navigationLink"), v)
+		}
+	}
+}
+
+// resolveLinks fetches the linked resources concurrently using up to the given
+// number of workers and returns a map of rel to the resolved body, or to a
+// list of bodies if the rel has several links. Only the given rels are
+// resolved if any are passed. Templated links are skipped since they can't
+// be followed as-is. Sets a non-zero exit code if any link fails to resolve.
+func resolveLinks(links Links, rels []string, workers int) map[string]interface{} {
+	if len(rels) == 0 {
+		for rel := range links {
+			rels = append(rels, rel)
+		}
+		sort.Strings(rels)
+	}
+
+	type job struct {
+		rel string
+		uri string
+	}
+
+	jobs := []job{}
+	for _, rel := range rels {
+		for _, l := range links[rel] {
+			if l.Templated {
+				LogWarning("Skipping templated link %s, use --expand to set its variables", l.URI)
+				continue
+			}
+			jobs = append(jobs, job{rel, l.URI})
+		}
+	}
+
+	bodies := make([]interface{}, len(jobs))
+	failed := make([]bool, len(jobs))
+	b := &backoff{}
+
+	forEachParallel(workers, len(jobs), func(i int) {
+		resp, err := b.do(func() (*http.Request, error) {
+			return http.NewRequest(http.MethodGet, jobs[i].uri, nil)
+		})
+		if err != nil {
+			LogError("Unable to resolve %s: %v", jobs[i].uri, err)
+			failed[i] = true
+			return
+		}
+
+		if resp.Status >= 400 {
+			LogError("Unable to resolve %s: got HTTP %d", jobs[i].uri, resp.Status)
+			failed[i] = true
+		}
+
+		bodies[i] = makeJSONSafe(resp.Body)
+	})
+
+	resolved := map[string]interface{}{}
+	for i, j := range jobs {
+		if failed[i] {
+			exitCode = 1
+		}
+
+		if len(links[j.rel]) == 1 {
+			resolved[j.rel] = bodies[i]
+			continue
+		}
+
+		list, _ := resolved[j.rel].([]interface{})
+		resolved[j.rel] = append(list, bodies[i])
+	}
+
+	return resolved
+}
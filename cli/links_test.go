@@ -70,6 +70,36 @@ func TestHALParser(t *testing.T) {
 	assert.Equal(t, r.Links["item"][0].URI, "/item")
 }
 
+func TestHALTemplatedLink(t *testing.T) {
+	r := &Response{
+		Links: Links{},
+		Body: map[string]interface{}{
+			"_links": map[string]interface{}{
+				"user": map[string]interface{}{
+					"href":      "/users/{id}{?fields}",
+					"templated": true,
+				},
+			},
+		},
+	}
+
+	p := HALParser{}
+	err := p.ParseLinks(r)
+	assert.NoError(t, err)
+	assert.True(t, r.Links["user"][0].Templated)
+
+	base, _ := url.Parse("https://example.com/api/")
+	resolved, err := resolveTemplate(base, r.Links["user"][0].URI)
+	assert.NoError(t, err)
+	assert.Equal(t, "https://example.com/users/{id}{?fields}", resolved)
+
+	r.Links["user"][0].URI = resolved
+	expanded, err := r.Links["user"][0].Expand(map[string]string{"id": "a b"})
+	assert.NoError(t, err)
+	assert.Equal(t, "https://example.com/users/a%20b", expanded.URI)
+	assert.False(t, expanded.Templated)
+}
+
 func TestTerrificallySimpleJSONParser(t *testing.T) {
 	r := &Response{
 		Links: Links{},
@@ -156,3 +186,84 @@ func TestJSONAPIParser(t *testing.T) {
 	assert.Equal(t, r.Links["self"][0].URI, "/self")
 	assert.Equal(t, r.Links["item"][0].URI, "/item")
 }
+
+func TestCollectionJSONParser(t *testing.T) {
+	r := &Response{
+		Links: Links{},
+		Body: map[string]interface{}{
+			"collection": map[string]interface{}{
+				"version": "1.0",
+				"href":    "/friends",
+				"links": []interface{}{
+					map[string]interface{}{"rel": "feed", "href": "/friends/rss"},
+				},
+				"items": []interface{}{
+					map[string]interface{}{
+						"href": "/friends/jdoe",
+						"links": []interface{}{
+							map[string]interface{}{"rel": "blog", "href": "/blogs/jdoe"},
+						},
+					},
+				},
+				"queries": []interface{}{
+					map[string]interface{}{
+						"rel":  "search",
+						"href": "/friends/search",
+						"data": []interface{}{
+							map[string]interface{}{"name": "name", "value": ""},
+						},
+					},
+					map[string]interface{}{"rel": "all", "href": "/friends?all=true"},
+				},
+			},
+		},
+	}
+
+	c := CollectionJSONParser{}
+	err := c.ParseLinks(r)
+	assert.NoError(t, err)
+	assert.Equal(t, "/friends", r.Links["self"][0].URI)
+	assert.Equal(t, "/friends/rss", r.Links["feed"][0].URI)
+	assert.Equal(t, "/friends/jdoe", r.Links["item"][0].URI)
+	assert.Equal(t, "/blogs/jdoe", r.Links["blog"][0].URI)
+	assert.Equal(t, "/friends/search{?name}", r.Links["search"][0].URI)
+	assert.True(t, r.Links["search"][0].Templated)
+	assert.Equal(t, "/friends?all=true", r.Links["all"][0].URI)
+	assert.False(t, r.Links["all"][0].Templated)
+}
+
+func TestODataParser(t *testing.T) {
+	r := &Response{
+		Links: Links{},
+		Body: map[string]interface{}{
+			"@odata.context":  "https://example.com/$metadata#People",
+			"@odata.nextLink": "https://example.com/People?$skiptoken=1",
+			"value": []interface{}{
+				map[string]interface{}{
+					"@odata.id":                    "https://example.com/People('russell')",
+					"@odata.editLink":              "People('russell')",
+					"Friends@odata.navigationLink": "People('russell')/Friends",
+					"UserName":                     "russell",
+				},
+			},
+		},
+	}
+
+	o := ODataParser{}
+	err := o.ParseLinks(r)
+	assert.NoError(t, err)
+	assert.Equal(t, "https://example.com/People?$skiptoken=1", r.Links["next"][0].URI)
+	assert.Equal(t, "https://example.com/People('russell')", r.Links["item"][0].URI)
+	assert.Equal(t, "People('russell')", r.Links["edit"][0].URI)
+	assert.Equal(t, "People('russell')/Friends", r.Links["Friends"][0].URI)
+
+	// Non-OData responses are ignored.
+	r = &Response{
+		Links: Links{},
+		Body: map[string]interface{}{
+			"@odata.nextLink": "/next",
+		},
+	}
+	assert.NoError(t, o.ParseLinks(r))
+	assert.Empty(t, r.Links)
+}
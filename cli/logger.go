@@ -2,12 +2,16 @@ package cli
 
 import (
 	"fmt"
+	"io"
 	"net/http"
 	"net/http/httputil"
+	"net/url"
+	"regexp"
 	"strings"
 	"time"
 
 	"github.com/alecthomas/chroma/quick"
+	"github.com/spf13/viper"
 )
 
 var enableVerbose bool
@@ -23,6 +27,10 @@ func LogDebug(format string, values ...interface{}) {
 // is enabled.
 func LogDebugRequest(req *http.Request) {
 	if enableVerbose {
+		original, originalURL := req.Header, req.URL
+		req.Header, req.URL = redactHeaders(original), redactURL(originalURL)
+		defer func() { req.Header, req.URL = original, originalURL }()
+
 		dumped, err := httputil.DumpRequest(req, true)
 		if err != nil {
 			return
@@ -30,7 +38,7 @@ func LogDebugRequest(req *http.Request) {
 
 		if tty {
 			sb := &strings.Builder{}
-			quick.Highlight(sb, string(dumped), "http", "terminal256", "cli-dark")
+			quick.Highlight(sb, string(dumped), "http", "terminal256", highlightStyle)
 			dumped = []byte(sb.String())
 		}
 
@@ -42,19 +50,121 @@ func LogDebugRequest(req *http.Request) {
 // is enabled.
 func LogDebugResponse(start time.Time, resp *http.Response) {
 	if enableVerbose {
-		dumped, err := httputil.DumpResponse(resp, true)
+		// Hidden and redacted headers are only changed in the dump, not the
+		// response.
+		original := resp.Header
+		resp.Header = redactHeaders(original)
+		defer func() { resp.Header = original }()
+
+		if hidden, err := hiddenHeaders(); err == nil {
+			for k := range resp.Header {
+				if hidden(k) {
+					delete(resp.Header, k)
+				}
+			}
+		}
+
+		// Chunked bodies may be unbounded streams, so rather than reading
+		// them up front each chunk is logged as it is read.
+		chunked := len(resp.TransferEncoding) > 0 && resp.TransferEncoding[0] == "chunked"
+
+		dumped, err := httputil.DumpResponse(resp, !chunked)
 		if err != nil {
 			return
 		}
 
 		if tty {
 			sb := &strings.Builder{}
-			quick.Highlight(sb, string(dumped), "http", "terminal256", "cli-dark")
+			quick.Highlight(sb, string(dumped), "http", "terminal256", highlightStyle)
 			dumped = []byte(sb.String())
 		}
 
 		LogDebug("Got response from server in %s:\n%s", time.Since(start), string(dumped))
+
+		if chunked {
+			resp.Body = &chunkRecorder{ReadCloser: resp.Body, start: time.Now()}
+		}
+	}
+}
+
+// sensitiveHeader matches the names of headers which usually contain
+// credentials, e.g. `Authorization` or `X-API-Key`.
+var sensitiveHeader = regexp.MustCompile(`(?i)^(authorization|proxy-authorization|cookie|set-cookie)$|api[-_]?key|token|secret`)
+
+// redactHeaders returns a copy of the headers with credentials replaced so
+// they don't end up in logs, unless `rsh-show-secrets` is set. The auth
+// scheme, like `Bearer`, is kept to help debug auth problems.
+func redactHeaders(header http.Header) http.Header {
+	redacted := http.Header{}
+	for name, values := range header {
+		if viper.GetBool("rsh-show-secrets") || !sensitiveHeader.MatchString(name) {
+			redacted[name] = values
+			continue
+		}
+
+		for _, v := range values {
+			if parts := strings.SplitN(v, " ", 2); len(parts) == 2 && strings.EqualFold(name, "authorization") {
+				v = parts[0] + " REDACTED"
+			} else {
+				v = "REDACTED"
+			}
+			redacted[name] = append(redacted[name], v)
+		}
+	}
+	return redacted
+}
+
+// redactURL returns the URL with the values of query params which usually
+// contain credentials, e.g. `api_key`, replaced unless `rsh-show-secrets` is
+// set. The original URL is returned as-is if nothing needs redacting.
+func redactURL(u *url.URL) *url.URL {
+	if u == nil || viper.GetBool("rsh-show-secrets") {
+		return u
+	}
+
+	query := u.Query()
+	redacted := false
+	for name, values := range query {
+		if sensitiveParam.MatchString(name) {
+			for i := range values {
+				values[i] = redactedValue
+			}
+			redacted = true
+		}
+	}
+
+	if !redacted {
+		return u
+	}
+
+	copied := *u
+	copied.RawQuery = query.Encode()
+	return &copied
+}
+
+// chunkRecorder logs the size, arrival time and contents of each piece of a
+// chunked response body as it is read, passing the data through unchanged.
+// Go's transport removes the chunked framing, so each piece is the data the
+// transport had available at the time, which for streaming servers usually
+// corresponds to a single chunk.
+type chunkRecorder struct {
+	io.ReadCloser
+	start  time.Time
+	chunks int
+	done   bool
+}
+
+func (c *chunkRecorder) Read(p []byte) (int, error) {
+	n, err := c.ReadCloser.Read(p)
+	if n > 0 {
+		c.chunks++
+		LogDebug("Received chunk %d: %d bytes after %s\n%s", c.chunks, n, time.Since(c.start), string(p[:n]))
+	}
+	if err == io.EOF && !c.done {
+		c.done = true
+		LogDebug("Received chunked body in %d chunks after %s", c.chunks, time.Since(c.start))
 	}
+	return n, err
 }
 
 // LogInfo logs an info message.
@@ -0,0 +1,210 @@
+package cli
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/spf13/viper"
+)
+
+// latencyBuckets are the histogram upper bounds in seconds, matching the
+// Prometheus client library defaults.
+var latencyBuckets = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10}
+
+// RequestMetrics collects the status and latency of repeated requests. It is
+// safe to record from multiple goroutines.
+type RequestMetrics struct {
+	mu        sync.Mutex
+	statuses  map[string]int
+	latencies []time.Duration
+}
+
+// Record the result of a single request. A status of zero means the request
+// failed without a response.
+func (m *RequestMetrics) Record(status int, latency time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.statuses == nil {
+		m.statuses = map[string]int{}
+	}
+
+	code := "error"
+	if status != 0 {
+		code = strconv.Itoa(status)
+	}
+
+	m.statuses[code]++
+	m.latencies = append(m.latencies, latency)
+}
+
+// Count returns the number of recorded requests.
+func (m *RequestMetrics) Count() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return len(m.latencies)
+}
+
+// Statuses returns the number of requests by status code, with `error` for
+// requests which failed without a response.
+func (m *RequestMetrics) Statuses() map[string]int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	statuses := make(map[string]int, len(m.statuses))
+	for code, count := range m.statuses {
+		statuses[code] = count
+	}
+	return statuses
+}
+
+// Percentile returns the latency which the given percentage of requests were
+// at or below, using the nearest-rank method.
+func (m *RequestMetrics) Percentile(p float64) time.Duration {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if len(m.latencies) == 0 {
+		return 0
+	}
+
+	sorted := append([]time.Duration{}, m.latencies...)
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i] < sorted[j]
+	})
+
+	rank := int(math.Ceil(p / 100 * float64(len(sorted))))
+	if rank < 1 {
+		rank = 1
+	}
+	if rank > len(sorted) {
+		rank = len(sorted)
+	}
+
+	return sorted[rank-1]
+}
+
+// WritePrometheus writes the collected metrics in the Prometheus text
+// exposition format, with a counter of requests by status code and a
+// histogram of request latencies.
+func (m *RequestMetrics) WritePrometheus(w io.Writer) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	buf := &bytes.Buffer{}
+
+	codes := make([]string, 0, len(m.statuses))
+	for code := range m.statuses {
+		codes = append(codes, code)
+	}
+	sort.Strings(codes)
+
+	fmt.Fprintln(buf, "# HELP restish_requests_total Total number of requests by response status code.")
+	fmt.Fprintln(buf, "# TYPE restish_requests_total counter")
+	for _, code := range codes {
+		fmt.Fprintf(buf, "restish_requests_total{code=%q} %d\n", code, m.statuses[code])
+	}
+
+	var sum time.Duration
+	counts := make([]int, len(latencyBuckets))
+	for _, latency := range m.latencies {
+		seconds := latency.Seconds()
+		sum += latency
+		for i, bucket := range latencyBuckets {
+			if seconds <= bucket {
+				counts[i]++
+			}
+		}
+	}
+
+	fmt.Fprintln(buf, "# HELP restish_request_duration_seconds Request latency in seconds.")
+	fmt.Fprintln(buf, "# TYPE restish_request_duration_seconds histogram")
+	for i, bucket := range latencyBuckets {
+		fmt.Fprintf(buf, "restish_request_duration_seconds_bucket{le=\"%s\"} %d\n", strconv.FormatFloat(bucket, 'g', -1, 64), counts[i])
+	}
+	fmt.Fprintf(buf, "restish_request_duration_seconds_bucket{le=\"+Inf\"} %d\n", len(m.latencies))
+	fmt.Fprintf(buf, "restish_request_duration_seconds_sum %s\n", strconv.FormatFloat(sum.Seconds(), 'g', -1, 64))
+	fmt.Fprintf(buf, "restish_request_duration_seconds_count %d\n", len(m.latencies))
+
+	_, err := w.Write(buf.Bytes())
+	return err
+}
+
+// writeMetricsFile writes the metrics to `rsh-metrics-out` in the Prometheus
+// text format, if set.
+func writeMetricsFile(metrics *RequestMetrics) error {
+	path := viper.GetString("rsh-metrics-out")
+	if path == "" {
+		return nil
+	}
+
+	buf := &bytes.Buffer{}
+	if err := metrics.WritePrometheus(buf); err != nil {
+		return err
+	}
+
+	return ioutil.WriteFile(path, buf.Bytes(), 0644)
+}
+
+// makeRepeatedRequest sends the request `count` times in a row, recording the
+// status and latency of each, then formats the last successful response. If
+// `rsh-metrics-out` is set, the results are written to that path in the
+// Prometheus text format.
+func makeRepeatedRequest(req *http.Request, count int) error {
+	if count < 1 {
+		count = 1
+	}
+
+	if err := bufferBody(req); err != nil {
+		return err
+	}
+
+	metrics := &RequestMetrics{}
+	var parsed *Response
+	var lastErr error
+
+	for i := 0; i < count; i++ {
+		// Each attempt gets a fresh copy since headers, query params and auth
+		// are added to the request as it is sent.
+		r := req.Clone(req.Context())
+		if err := resetBody(r); err != nil {
+			return err
+		}
+
+		start := time.Now()
+		resp, err := GetParsedResponse(r)
+		metrics.Record(resp.Status, time.Since(start))
+
+		if err != nil {
+			LogDebug("Request %d of %d failed: %v", i+1, count, err)
+			lastErr = err
+			continue
+		}
+
+		parsed = &resp
+	}
+
+	if err := writeMetricsFile(metrics); err != nil {
+		return err
+	}
+
+	if parsed == nil {
+		return lastErr
+	}
+
+	if err := Formatter.Format(*parsed); err != nil {
+		return err
+	}
+
+	checkStatus(parsed.Status)
+	return nil
+}
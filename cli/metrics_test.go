@@ -0,0 +1,59 @@
+package cli
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMetricsPrometheus(t *testing.T) {
+	m := &RequestMetrics{}
+	m.Record(200, 20*time.Millisecond)
+	m.Record(200, 300*time.Millisecond)
+	m.Record(503, 3*time.Second)
+	m.Record(0, 10*time.Millisecond)
+
+	buf := &bytes.Buffer{}
+	assert.NoError(t, m.WritePrometheus(buf))
+
+	assert.Equal(t, `# HELP restish_requests_total Total number of requests by response status code.
+# TYPE restish_requests_total counter
+restish_requests_total{code="200"} 2
+restish_requests_total{code="503"} 1
+restish_requests_total{code="error"} 1
+# HELP restish_request_duration_seconds Request latency in seconds.
+# TYPE restish_request_duration_seconds histogram
+restish_request_duration_seconds_bucket{le="0.005"} 0
+restish_request_duration_seconds_bucket{le="0.01"} 1
+restish_request_duration_seconds_bucket{le="0.025"} 2
+restish_request_duration_seconds_bucket{le="0.05"} 2
+restish_request_duration_seconds_bucket{le="0.1"} 2
+restish_request_duration_seconds_bucket{le="0.25"} 2
+restish_request_duration_seconds_bucket{le="0.5"} 3
+restish_request_duration_seconds_bucket{le="1"} 3
+restish_request_duration_seconds_bucket{le="2.5"} 3
+restish_request_duration_seconds_bucket{le="5"} 4
+restish_request_duration_seconds_bucket{le="10"} 4
+restish_request_duration_seconds_bucket{le="+Inf"} 4
+restish_request_duration_seconds_sum 3.33
+restish_request_duration_seconds_count 4
+`, buf.String())
+}
+
+func TestMetricsPercentile(t *testing.T) {
+	m := &RequestMetrics{}
+	assert.Equal(t, time.Duration(0), m.Percentile(50))
+
+	for i := 100; i >= 1; i-- {
+		m.Record(200, time.Duration(i)*time.Millisecond)
+	}
+
+	assert.Equal(t, 100, m.Count())
+	assert.Equal(t, 50*time.Millisecond, m.Percentile(50))
+	assert.Equal(t, 90*time.Millisecond, m.Percentile(90))
+	assert.Equal(t, 99*time.Millisecond, m.Percentile(99))
+	assert.Equal(t, 100*time.Millisecond, m.Percentile(100))
+	assert.Equal(t, map[string]int{"200": 100}, m.Statuses())
+}
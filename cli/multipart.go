@@ -0,0 +1,148 @@
+package cli
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/textproto"
+	"reflect"
+	"strings"
+	"unicode/utf8"
+)
+
+// Multipart describes `multipart/mixed` responses like those returned from
+// batch endpoints, e.g. OData `$batch`.
+//
+// Responses are decoded into an array with one object per part, containing
+// the part's `headers` and its `body` decoded using the part's own content
+// type. Parts which are HTTP responses (`application/http`) have a body with
+// the embedded response's `status`, `headers` and decoded `body`.
+type Multipart struct{}
+
+// Detect if the content type is multipart/mixed.
+func (m Multipart) Detect(contentType string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+
+	return mediaType == "multipart/mixed"
+}
+
+// Marshal is not supported for multipart/mixed content.
+func (m Multipart) Marshal(value interface{}) ([]byte, error) {
+	return nil, errors.New("cannot marshal multipart/mixed")
+}
+
+// Unmarshal is not supported without the `boundary` parameter from the
+// content type header, see `UnmarshalContentType`.
+func (m Multipart) Unmarshal(data []byte, value interface{}) error {
+	return errors.New("multipart/mixed requires a boundary")
+}
+
+// UnmarshalContentType decodes each part of the body using the boundary from
+// the full content type header value.
+func (m Multipart) UnmarshalContentType(contentType string, data []byte, value interface{}) error {
+	v := reflect.ValueOf(value)
+
+	if v.Kind() != reflect.Ptr {
+		return fmt.Errorf("value must be pointer but found %s", v.Kind())
+	}
+
+	if !v.Elem().CanSet() {
+		return fmt.Errorf("interface value cannot be set")
+	}
+
+	_, params, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return err
+	}
+
+	if params["boundary"] == "" {
+		return errors.New("multipart/mixed requires a boundary")
+	}
+
+	parts := []interface{}{}
+	reader := multipart.NewReader(bytes.NewReader(data), params["boundary"])
+	for {
+		part, err := reader.NextPart()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+
+		body, err := ioutil.ReadAll(part)
+		if err != nil {
+			return err
+		}
+
+		parsed := map[string]interface{}{
+			"headers": partHeaders(part.Header),
+		}
+
+		ct := part.Header.Get("Content-Type")
+		parsed["body"] = decodePart(ct, body)
+
+		if strings.HasPrefix(ct, "application/http") {
+			if resp, err := http.ReadResponse(bufio.NewReader(bytes.NewReader(body)), nil); err == nil {
+				embedded, err := ioutil.ReadAll(resp.Body)
+				resp.Body.Close()
+				if err != nil {
+					return err
+				}
+
+				parsed["body"] = map[string]interface{}{
+					"status":  resp.StatusCode,
+					"headers": partHeaders(textproto.MIMEHeader(resp.Header)),
+					"body":    decodePart(resp.Header.Get("Content-Type"), embedded),
+				}
+			}
+		}
+
+		parts = append(parts, parsed)
+	}
+
+	v.Elem().Set(reflect.ValueOf(parts))
+	return nil
+}
+
+// partHeaders flattens the headers of a part, joining repeated values.
+func partHeaders(header textproto.MIMEHeader) map[string]interface{} {
+	headers := map[string]interface{}{}
+	for name, values := range header {
+		headers[http.CanonicalHeaderKey(name)] = strings.Join(values, ", ")
+	}
+	return headers
+}
+
+// decodePart decodes a part body with the registered content types, falling
+// back to a string or raw bytes if it cannot be decoded.
+func decodePart(contentType string, body []byte) interface{} {
+	if len(body) == 0 {
+		return nil
+	}
+
+	if contentType == "" {
+		// Parts without a content type default to plain text.
+		contentType = "text/plain"
+	}
+
+	var parsed interface{}
+	if err := Unmarshal(contentType, body, &parsed); err == nil {
+		return parsed
+	}
+
+	if utf8.Valid(body) {
+		return string(body)
+	}
+
+	return body
+}
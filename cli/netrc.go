@@ -0,0 +1,115 @@
+package cli
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+)
+
+// netrcEntry is a single `machine` or `default` entry in a netrc file.
+type netrcEntry struct {
+	machine  string
+	login    string
+	password string
+}
+
+// netrcPath returns the path to the user's netrc file, respecting the `NETRC`
+// environment variable override.
+func netrcPath() string {
+	if p := os.Getenv("NETRC"); p != "" {
+		return p
+	}
+
+	name := ".netrc"
+	if runtime.GOOS == "windows" {
+		name = "_netrc"
+	}
+
+	return filepath.Join(userHomeDir(), name)
+}
+
+// parseNetrc parses the contents of a netrc file. The `default` entry, if
+// present, is returned with an empty machine name. Macro definitions are
+// skipped.
+func parseNetrc(data string) []netrcEntry {
+	entries := []netrcEntry{}
+	var current *netrcEntry
+
+	lines := strings.Split(data, "\n")
+	for i := 0; i < len(lines); i++ {
+		line := lines[i]
+
+		if strings.HasPrefix(strings.TrimSpace(line), "#") {
+			continue
+		}
+
+		fields := strings.Fields(line)
+		for j := 0; j < len(fields); j++ {
+			next := ""
+			if j+1 < len(fields) {
+				next = fields[j+1]
+			}
+
+			switch fields[j] {
+			case "machine":
+				entries = append(entries, netrcEntry{machine: next})
+				current = &entries[len(entries)-1]
+				j++
+			case "default":
+				entries = append(entries, netrcEntry{})
+				current = &entries[len(entries)-1]
+			case "login":
+				if current != nil {
+					current.login = next
+				}
+				j++
+			case "password":
+				if current != nil {
+					current.password = next
+				}
+				j++
+			case "account":
+				j++
+			case "macdef":
+				// Macros run until the next blank line.
+				current = nil
+				for i+1 < len(lines) && strings.TrimSpace(lines[i+1]) != "" {
+					i++
+				}
+				j = len(fields)
+			}
+		}
+	}
+
+	return entries
+}
+
+// netrcCredentials returns the login and password for a host from the user's
+// netrc file, falling back to the `default` entry. Returns empty strings if
+// the file or a matching entry is missing.
+func netrcCredentials(host string) (string, string) {
+	data, err := ioutil.ReadFile(netrcPath())
+	if err != nil {
+		return "", ""
+	}
+
+	var fallback *netrcEntry
+	entries := parseNetrc(string(data))
+	for i, entry := range entries {
+		if entry.machine == host {
+			return entry.login, entry.password
+		}
+
+		if entry.machine == "" && fallback == nil {
+			fallback = &entries[i]
+		}
+	}
+
+	if fallback != nil {
+		return fallback.login, fallback.password
+	}
+
+	return "", ""
+}
@@ -0,0 +1,104 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/spf13/viper"
+)
+
+// numberStyle describes how a locale separates groups of thousands and the
+// decimal part of a number.
+type numberStyle struct {
+	group   string
+	decimal string
+}
+
+// numberStyles are the supported `rsh-number-format` locale styles.
+var numberStyles = map[string]numberStyle{
+	"en": {group: ",", decimal: "."},
+	"de": {group: ".", decimal: ","},
+	"fr": {group: " ", decimal: ","},
+	"ch": {group: "'", decimal: "."},
+}
+
+// groupDigits formats a number string like `-1234567.89` with the style's
+// thousands separators and decimal mark.
+func groupDigits(s string, style numberStyle) string {
+	sign := ""
+	if strings.HasPrefix(s, "-") {
+		sign, s = "-", s[1:]
+	}
+
+	whole, frac := s, ""
+	if i := strings.IndexByte(s, '.'); i != -1 {
+		whole, frac = s[:i], s[i+1:]
+	}
+
+	sb := strings.Builder{}
+	for i, c := range whole {
+		if i > 0 && (len(whole)-i)%3 == 0 {
+			sb.WriteString(style.group)
+		}
+		sb.WriteRune(c)
+	}
+
+	if frac != "" {
+		sb.WriteString(style.decimal)
+		sb.WriteString(frac)
+	}
+
+	return sign + sb.String()
+}
+
+// formatCell formats a value for display in table or CSV output. Numbers use
+// the `rsh-number-format` locale style if one is set.
+func formatCell(v interface{}) string {
+	style, ok := numberStyles[viper.GetString("rsh-number-format")]
+	if !ok {
+		switch n := v.(type) {
+		case float64:
+			// Avoid exponents like `1.234567e+06` for large numbers.
+			return strconv.FormatFloat(n, 'f', -1, 64)
+		case float32:
+			return strconv.FormatFloat(float64(n), 'f', -1, 32)
+		}
+		return fmt.Sprintf("%v", v)
+	}
+
+	var s string
+	switch n := v.(type) {
+	case float64:
+		s = strconv.FormatFloat(n, 'f', -1, 64)
+	case float32:
+		s = strconv.FormatFloat(float64(n), 'f', -1, 32)
+	case int:
+		s = strconv.Itoa(n)
+	case int64:
+		s = strconv.FormatInt(n, 10)
+	case uint64:
+		s = strconv.FormatUint(n, 10)
+	case json.Number:
+		s = n.String()
+		if strings.ContainsAny(s, "eE") {
+			return s
+		}
+	default:
+		return fmt.Sprintf("%v", v)
+	}
+
+	return groupDigits(s, style)
+}
+
+// checkNumberFormat returns an error if `rsh-number-format` is not a known
+// locale style.
+func checkNumberFormat() error {
+	if f := viper.GetString("rsh-number-format"); f != "" {
+		if _, ok := numberStyles[f]; !ok {
+			return fmt.Errorf("unknown number format %s, expected one of en, de, fr, ch", f)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,47 @@
+package cli
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGroupDigits(t *testing.T) {
+	assert.Equal(t, "1", groupDigits("1", numberStyles["en"]))
+	assert.Equal(t, "123", groupDigits("123", numberStyles["en"]))
+	assert.Equal(t, "1,234", groupDigits("1234", numberStyles["en"]))
+	assert.Equal(t, "-1,234,567.89", groupDigits("-1234567.89", numberStyles["en"]))
+	assert.Equal(t, "1.234.567,89", groupDigits("1234567.89", numberStyles["de"]))
+	assert.Equal(t, "1 234 567,89", groupDigits("1234567.89", numberStyles["fr"]))
+	assert.Equal(t, "123'456", groupDigits("123456", numberStyles["ch"]))
+}
+
+func TestFormatCell(t *testing.T) {
+	reset(false)
+	defer viper.Set("rsh-number-format", "")
+
+	assert.Equal(t, "1234567", formatCell(1234567.0))
+
+	viper.Set("rsh-number-format", "en")
+	assert.Equal(t, "1,234,567", formatCell(1234567.0))
+	assert.Equal(t, "1,234.5", formatCell(json.Number("1234.5")))
+	assert.Equal(t, "12345", formatCell("12345"))
+	assert.NoError(t, checkNumberFormat())
+
+	viper.Set("rsh-number-format", "xx")
+	assert.Error(t, checkNumberFormat())
+}
+
+func TestNumberFormatCSV(t *testing.T) {
+	reset(false)
+	viper.Set("rsh-number-format", "en")
+	defer viper.Set("rsh-number-format", "")
+
+	out, err := CSV{}.Marshal([]interface{}{
+		map[string]interface{}{"id": "a", "total": 1234567.0},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, "id,total\na,\"1,234,567\"\n", string(out))
+}
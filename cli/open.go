@@ -0,0 +1,99 @@
+package cli
+
+import (
+	"fmt"
+	"net/url"
+	"os/exec"
+	"runtime"
+
+	"github.com/spf13/cobra"
+)
+
+// swaggerUIURL is a hosted Swagger UI which can render any public spec.
+const swaggerUIURL = "https://petstore.swagger.io/?url="
+
+// openBrowser opens the URL in the default browser regardless of OS. It can be
+// overridden in tests.
+var openBrowser = func(url string) error {
+	var cmd string
+	var args []string
+
+	switch runtime.GOOS {
+	case "windows":
+		cmd = "cmd"
+		args = []string{"/c", "start"}
+	case "darwin":
+		cmd = "open"
+	default:
+		cmd = "xdg-open"
+	}
+	args = append(args, url)
+	return exec.Command(cmd, args...).Start()
+}
+
+// apiOpenURL returns the URL to open for an API description. The target is
+// one of `docs`, `terms`, `spec` or `swagger-ui`. Without a target the docs
+// are preferred, then the terms of service.
+func apiOpenURL(api API, target string) (string, error) {
+	switch target {
+	case "":
+		if api.DocsURL != "" {
+			return api.DocsURL, nil
+		}
+		if api.TermsURL != "" {
+			return api.TermsURL, nil
+		}
+		if api.SpecURL != "" {
+			return "", fmt.Errorf("no documentation URL found, try opening the `spec` or `swagger-ui` instead")
+		}
+		return "", fmt.Errorf("no documentation URL found")
+	case "docs":
+		if api.DocsURL != "" {
+			return api.DocsURL, nil
+		}
+	case "terms":
+		if api.TermsURL != "" {
+			return api.TermsURL, nil
+		}
+	case "spec":
+		if api.SpecURL != "" {
+			return api.SpecURL, nil
+		}
+	case "swagger-ui":
+		if api.SpecURL != "" {
+			return swaggerUIURL + url.QueryEscape(api.SpecURL), nil
+		}
+	default:
+		return "", fmt.Errorf("unknown target %s, expected one of docs, terms, spec, swagger-ui", target)
+	}
+
+	return "", fmt.Errorf("no %s URL found", target)
+}
+
+func apiOpen(cmd *cobra.Command, args []string) {
+	config := configs[args[0]]
+	if config == nil {
+		panic("API " + args[0] + " not found")
+	}
+
+	// Use a throwaway command so the API's operations aren't registered.
+	api, err := Load(config.Base, &cobra.Command{})
+	if err != nil {
+		panic(err)
+	}
+
+	target := ""
+	if len(args) > 1 {
+		target = args[1]
+	}
+
+	uri, err := apiOpenURL(api, target)
+	if err != nil {
+		panic(err)
+	}
+
+	fmt.Fprintln(Stdout, "Opening", uri)
+	if err := openBrowser(uri); err != nil {
+		panic(err)
+	}
+}
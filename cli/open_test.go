@@ -0,0 +1,40 @@
+package cli
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAPIOpenURL(t *testing.T) {
+	api := API{
+		DocsURL:  "https://example.com/docs",
+		TermsURL: "https://example.com/terms",
+		SpecURL:  "https://example.com/openapi.json",
+	}
+
+	uri, err := apiOpenURL(api, "")
+	assert.NoError(t, err)
+	assert.Equal(t, "https://example.com/docs", uri)
+
+	uri, err = apiOpenURL(api, "terms")
+	assert.NoError(t, err)
+	assert.Equal(t, "https://example.com/terms", uri)
+
+	uri, err = apiOpenURL(api, "spec")
+	assert.NoError(t, err)
+	assert.Equal(t, "https://example.com/openapi.json", uri)
+
+	uri, err = apiOpenURL(api, "swagger-ui")
+	assert.NoError(t, err)
+	assert.Equal(t, "https://petstore.swagger.io/?url=https%3A%2F%2Fexample.com%2Fopenapi.json", uri)
+
+	_, err = apiOpenURL(api, "bad")
+	assert.Error(t, err)
+
+	_, err = apiOpenURL(API{SpecURL: "https://example.com/openapi.json"}, "")
+	assert.EqualError(t, err, "no documentation URL found, try opening the `spec` or `swagger-ui` instead")
+
+	_, err = apiOpenURL(API{}, "docs")
+	assert.EqualError(t, err, "no docs URL found")
+}
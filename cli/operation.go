@@ -3,7 +3,6 @@ package cli
 import (
 	"fmt"
 	"io"
-	"log"
 	"net/http"
 	"net/url"
 	"reflect"
@@ -27,6 +26,9 @@ type Operation struct {
 	BodyMediaType string   `json:"bodyMediaType,omitempty"`
 	Examples      []string `json:"examples,omitempty"`
 	Hidden        bool     `json:"hidden,omitempty"`
+
+	// Tags group related operations, e.g. from OpenAPI operation tags.
+	Tags []string `json:"tags,omitempty"`
 }
 
 // command returns a Cobra command instance for this operation.
@@ -63,13 +65,16 @@ func (o Operation) command() *cobra.Command {
 		Example: examples,
 		Args:    argSpec,
 		Hidden:  o.Hidden,
-		Run: func(cmd *cobra.Command, args []string) {
+		Annotations: map[string]string{
+			tagsAnnotation: strings.Join(o.Tags, ","),
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
 			uri := o.URITemplate
 			for i, param := range o.PathParams {
 				value, err := param.Parse(args[i])
 				if err != nil {
 					value := param.Serialize(args[i])[0]
-					log.Fatalf("could not parse param %s with input %s: %v", param.Name, value, err)
+					return fmt.Errorf("could not parse param %s with input %s: %w", param.Name, value, err)
 				}
 				// Replaces URL-encoded `{`+name+`}` in the template.
 				uri = strings.Replace(uri, "{"+param.Name+"}", fmt.Sprintf("%v", value), 1)
@@ -107,13 +112,17 @@ func (o Operation) command() *cobra.Command {
 			if o.BodyMediaType != "" {
 				b, err := GetBody(o.BodyMediaType, args[len(o.PathParams):])
 				if err != nil {
-					panic(err)
+					return err
 				}
 				body = strings.NewReader(b)
 			}
 
-			req, _ := http.NewRequest(o.Method, uri, body)
-			MakeRequestAndFormat(req)
+			req, err := http.NewRequest(o.Method, uri, body)
+			if err != nil {
+				return err
+			}
+
+			return sendAndFormat(req)
 		},
 	}
 
@@ -57,7 +57,7 @@ func TestOperation(t *testing.T) {
 	Stdout = capture
 	Stderr = capture
 	cmd.Flags().Parse([]string{"--search=foo"})
-	cmd.Run(cmd, []string{"id1"})
+	assert.NoError(t, cmd.RunE(cmd, []string{"id1"}))
 
 	assert.Equal(t, "HTTP/1.1 200 OK\nContent-Type: application/json\n\n{\n  hello: \"world\"\n}\n", capture.String())
 }
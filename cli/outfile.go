@@ -0,0 +1,48 @@
+package cli
+
+import (
+	"io/ioutil"
+	"mime"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+)
+
+// outputFilename derives a filename for the response from its
+// `Content-Disposition` header or the last segment of its URL path, like
+// `curl -O` does.
+func outputFilename(resp Response) string {
+	if cd := resp.Headers["Content-Disposition"]; cd != "" {
+		if _, params, err := mime.ParseMediaType(cd); err == nil {
+			// Only use the base name so a server can't write elsewhere.
+			if name := filepath.Base(params["filename"]); name != "." && name != "/" && name != string(filepath.Separator) {
+				return name
+			}
+		}
+	}
+
+	if u, err := url.Parse(resp.URL); err == nil {
+		if name := path.Base(u.Path); name != "." && name != "/" {
+			return name
+		}
+	}
+
+	return "response"
+}
+
+// writeOutputFile writes the output to a file instead of the terminal. If the
+// filename is a directory, e.g. `.`, then the file is created within it using
+// a name derived from the response.
+func writeOutputFile(filename string, resp Response, data []byte) error {
+	if info, err := os.Stat(filename); err == nil && info.IsDir() {
+		filename = filepath.Join(filename, outputFilename(resp))
+	}
+
+	if err := ioutil.WriteFile(filename, data, 0644); err != nil {
+		return err
+	}
+
+	LogDebug("Wrote %d bytes to %s", len(data), filename)
+	return nil
+}
@@ -0,0 +1,62 @@
+package cli
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/h2non/gock.v1"
+)
+
+func TestOutputFilename(t *testing.T) {
+	assert.Equal(t, "report.pdf", outputFilename(Response{
+		Headers: map[string]string{"Content-Disposition": `attachment; filename="../report.pdf"`},
+		URL:     "https://example.com/download/123",
+	}))
+
+	assert.Equal(t, "123", outputFilename(Response{
+		Headers: map[string]string{},
+		URL:     "https://example.com/download/123?format=pdf",
+	}))
+
+	assert.Equal(t, "response", outputFilename(Response{
+		Headers: map[string]string{},
+		URL:     "https://example.com/",
+	}))
+}
+
+func TestOutputFile(t *testing.T) {
+	defer gock.Off()
+
+	dir, err := ioutil.TempDir("", "restish-output")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	gock.New("http://example.com").
+		Get("/items/1").
+		Reply(200).
+		JSON(map[string]interface{}{"id": 1, "name": "one"})
+
+	filename := filepath.Join(dir, "item.json")
+	captured := run("http://example.com/items/1 -O " + filename)
+	assert.Equal(t, "", captured)
+
+	data, err := ioutil.ReadFile(filename)
+	assert.NoError(t, err)
+	assert.Equal(t, "{\n  \"id\": 1,\n  \"name\": \"one\"\n}\n", string(data))
+
+	gock.New("http://example.com").
+		Get("/files/image.png").
+		Reply(200).
+		SetHeader("Content-Type", "application/octet-stream").
+		Body(bytes.NewReader([]byte{0x89, 'P', 'N', 'G'}))
+
+	run("http://example.com/files/image.png -O " + dir)
+
+	data, err = ioutil.ReadFile(filepath.Join(dir, "image.png"))
+	assert.NoError(t, err)
+	assert.Equal(t, []byte{0x89, 'P', 'N', 'G'}, data)
+}
@@ -0,0 +1,123 @@
+package cli
+
+import (
+	"fmt"
+	"net/url"
+	"strconv"
+
+	jmespath "github.com/danielgtaylor/go-jmespath-plus"
+)
+
+// PaginationConfig describes how to follow paginated collections for an API.
+// By default `next` links are followed and list responses are merged.
+type PaginationConfig struct {
+	// Rel is the link relation to follow to get the next page, e.g. from a
+	// `Link` header. Defaults to `next`.
+	Rel string `json:"rel,omitempty"`
+
+	// Param is a query param to set to the next page's cursor, for APIs which
+	// don't return links. The cursor is read via the `Cursor` JMESPath
+	// expression, e.g. `body.next_cursor` or `headers.X-Next-Page`.
+	Param  string `json:"param,omitempty"`
+	Cursor string `json:"cursor,omitempty"`
+
+	// Items is the key of the field holding each page's items when the
+	// response body is an object like `{"items": [...], "next": "..."}`.
+	Items string `json:"items,omitempty"`
+
+	// MaxPages stops pagination after this many pages have been fetched.
+	MaxPages int `json:"max_pages,omitempty" mapstructure:"max_pages"`
+}
+
+// nextPage returns the URL of the next page, if any.
+func (p PaginationConfig) nextPage(current *url.URL, resp Response) (*url.URL, error) {
+	if p.Param != "" {
+		if p.Cursor == "" {
+			return nil, fmt.Errorf("pagination param %s requires a cursor expression", p.Param)
+		}
+
+		result, err := jmespath.Search(p.Cursor, makeJSONSafe(resp.Map()))
+		if err != nil {
+			return nil, err
+		}
+
+		cursor := ""
+		switch v := result.(type) {
+		case nil:
+		case string:
+			cursor = v
+		case float64:
+			cursor = strconv.FormatFloat(v, 'f', -1, 64)
+		default:
+			cursor = fmt.Sprintf("%v", v)
+		}
+
+		query := current.Query()
+		if cursor == "" || cursor == query.Get(p.Param) {
+			// No cursor, or the same page again, means we are done.
+			return nil, nil
+		}
+
+		LogDebug("Found pagination via %s cursor: %s", p.Param, cursor)
+
+		next := *current
+		query.Set(p.Param, cursor)
+		next.RawQuery = query.Encode()
+		return &next, nil
+	}
+
+	rel := p.Rel
+	if rel == "" {
+		rel = "next"
+	}
+
+	if len(resp.Links[rel]) == 0 {
+		return nil, nil
+	}
+
+	LogDebug("Found pagination via rel=%s link: %s", rel, resp.Links[rel][0].URI)
+
+	next, err := url.Parse(resp.Links[rel][0].URI)
+	if err != nil {
+		return nil, err
+	}
+
+	return current.ResolveReference(next), nil
+}
+
+// items returns the items of a paginated response body, which is either a
+// list, an object with the configured items field, or an OData collection
+// wrapping its items in `value`.
+func (p PaginationConfig) items(body interface{}) ([]interface{}, bool) {
+	if l, ok := body.([]interface{}); ok {
+		return l, true
+	}
+
+	if m, ok := body.(map[string]interface{}); ok {
+		if p.Items != "" {
+			l, ok := m[p.Items].([]interface{})
+			return l, ok
+		}
+
+		if _, ok := m["@odata.context"]; ok {
+			l, ok := m["value"].([]interface{})
+			return l, ok
+		}
+	}
+
+	return nil, false
+}
+
+// setItems replaces the items of a paginated response body.
+func (p PaginationConfig) setItems(body interface{}, items []interface{}) interface{} {
+	if m, ok := body.(map[string]interface{}); ok {
+		key := p.Items
+		if key == "" {
+			key = "value"
+		}
+		m[key] = items
+		return m
+	}
+
+	return items
+}
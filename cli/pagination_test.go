@@ -0,0 +1,105 @@
+package cli
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/h2non/gock.v1"
+)
+
+func TestPaginationCursor(t *testing.T) {
+	defer gock.Off()
+	reset(false)
+
+	configs["paged"] = &APIConfig{
+		Base: "http://paged.example.com",
+		Pagination: &PaginationConfig{
+			Param:  "cursor",
+			Cursor: "body.next",
+			Items:  "items",
+		},
+	}
+
+	gock.New("http://paged.example.com").
+		Get("/items").
+		MatchParam("cursor", "b").
+		Reply(http.StatusOK).
+		JSON(map[string]interface{}{
+			"items": []interface{}{3},
+		})
+	gock.New("http://paged.example.com").
+		Get("/items").
+		MatchParam("cursor", "a").
+		Reply(http.StatusOK).
+		JSON(map[string]interface{}{
+			"items": []interface{}{2},
+			"next":  "b",
+		})
+	gock.New("http://paged.example.com").
+		Get("/items").
+		Reply(http.StatusOK).
+		JSON(map[string]interface{}{
+			"items": []interface{}{1},
+			"next":  "a",
+		})
+
+	req, _ := http.NewRequest(http.MethodGet, "http://paged.example.com/items", nil)
+	resp, err := GetParsedResponse(req)
+
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{
+		"items": []interface{}{1.0, 2.0, 3.0},
+	}, resp.Body)
+}
+
+func TestPaginationRelAndMaxPages(t *testing.T) {
+	defer gock.Off()
+	reset(false)
+
+	configs["paged"] = &APIConfig{
+		Base: "http://paged.example.com",
+		Pagination: &PaginationConfig{
+			Rel:      "more",
+			MaxPages: 2,
+		},
+	}
+
+	gock.New("http://paged.example.com").
+		Get("/page1").
+		Reply(http.StatusOK).
+		SetHeader("Link", "</page2>; rel=\"more\"").
+		JSON([]interface{}{1})
+	gock.New("http://paged.example.com").
+		Get("/page2").
+		Reply(http.StatusOK).
+		SetHeader("Link", "</page3>; rel=\"more\"").
+		JSON([]interface{}{2})
+
+	req, _ := http.NewRequest(http.MethodGet, "http://paged.example.com/page1", nil)
+	resp, err := GetParsedResponse(req)
+
+	assert.NoError(t, err)
+	assert.Equal(t, []interface{}{1.0, 2.0}, resp.Body)
+}
+
+func TestPaginationMaxPagesFlag(t *testing.T) {
+	defer gock.Off()
+	reset(false)
+
+	viper.Set("rsh-max-pages", 1)
+	defer viper.Set("rsh-max-pages", 0)
+
+	gock.New("http://example.com").
+		Get("/page1").
+		Reply(http.StatusOK).
+		SetHeader("Link", "</page2>; rel=\"next\"").
+		JSON([]interface{}{1, 2})
+
+	req, _ := http.NewRequest(http.MethodGet, "http://example.com/page1", nil)
+	resp, err := GetParsedResponse(req)
+
+	assert.NoError(t, err)
+	assert.Equal(t, []interface{}{1.0, 2.0}, resp.Body)
+}
@@ -0,0 +1,174 @@
+package cli
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// maxConcurrency caps the number of concurrent requests so that parallel
+// modes don't hammer the server.
+const maxConcurrency = 32
+
+// maxBackoffRetries is how many times a request which was rate limited is
+// retried by parallel modes.
+const maxBackoffRetries = 3
+
+// concurrency clamps the requested number of workers to a sane range.
+func concurrency(n int) int {
+	if n < 1 {
+		return 1
+	}
+
+	if n > maxConcurrency {
+		LogWarning("Limiting concurrency to %d", maxConcurrency)
+		return maxConcurrency
+	}
+
+	return n
+}
+
+// forEachParallel calls the function for each index up to count using a pool
+// of workers, and waits for all calls to finish.
+func forEachParallel(workers, count int, f func(i int)) {
+	indexes := make(chan int)
+	wg := sync.WaitGroup{}
+
+	for w := 0; w < concurrency(workers); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range indexes {
+				f(i)
+			}
+		}()
+	}
+
+	for i := 0; i < count; i++ {
+		indexes <- i
+	}
+	close(indexes)
+
+	wg.Wait()
+}
+
+// backoff coordinates workers so that when the server responds with
+// `429 Too Many Requests` all of them pause, rather than each one continuing
+// to send requests until it is rate limited too.
+type backoff struct {
+	mu    sync.Mutex
+	until time.Time
+}
+
+// wait blocks until any pause requested by the server is over.
+func (b *backoff) wait() {
+	b.mu.Lock()
+	d := time.Until(b.until)
+	b.mu.Unlock()
+
+	if d > 0 {
+		rateLimitSleep(d)
+	}
+}
+
+// pause asks all workers to wait for the duration before the next request.
+func (b *backoff) pause(d time.Duration) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if until := time.Now().Add(d); until.After(b.until) {
+		b.until = until
+	}
+}
+
+// do sends a request built by the function, honoring `Retry-After` when rate
+// limited by pausing all workers and then trying again.
+func (b *backoff) do(build func() (*http.Request, error)) (Response, error) {
+	for attempt := 0; ; attempt++ {
+		b.wait()
+
+		req, err := build()
+		if err != nil {
+			return Response{}, err
+		}
+
+		resp, err := GetParsedResponse(req)
+		if err != nil || resp.Status != http.StatusTooManyRequests || attempt >= maxBackoffRetries {
+			return resp, err
+		}
+
+		delay := time.Second
+		if d := retryAfter(&http.Response{Header: http.Header{"Retry-After": []string{resp.Headers["Retry-After"]}}}); d > 0 {
+			delay = d
+		}
+
+		LogInfo("Rate limited by %s, pausing requests for %s", req.URL.Host, delay.Round(time.Millisecond))
+		b.pause(delay)
+	}
+}
+
+// readURLs reads one URL per line, skipping blank lines and `#` comments.
+func readURLs(input io.Reader) ([]string, error) {
+	uris := []string{}
+	scanner := bufio.NewScanner(input)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		uris = append(uris, fixAddress(line))
+	}
+
+	return uris, scanner.Err()
+}
+
+// getParallel fetches each URL read from the input using a pool of workers.
+// Each result is written as a whole, prefixed by its URL, as soon as it is
+// ready so that output from different requests doesn't interleave. Sets a
+// non-zero exit code if any request fails.
+func getParallel(input io.Reader, workers int) error {
+	uris, err := readURLs(input)
+	if err != nil {
+		return err
+	}
+
+	var outMu sync.Mutex
+	failed := false
+	b := &backoff{}
+
+	forEachParallel(workers, len(uris), func(i int) {
+		resp, err := b.do(func() (*http.Request, error) {
+			return http.NewRequest(http.MethodGet, uris[i], nil)
+		})
+
+		outMu.Lock()
+		defer outMu.Unlock()
+
+		if err != nil {
+			LogError("%s: %v", uris[i], err)
+			failed = true
+			return
+		}
+
+		if resp.Status >= 400 {
+			failed = true
+		}
+
+		fmt.Fprintln(Stdout, au.Bold(uris[i]))
+		if err := Formatter.Format(resp); err != nil {
+			LogError("%s: %v", uris[i], err)
+			failed = true
+		}
+		fmt.Fprintln(Stdout)
+	})
+
+	if failed {
+		exitCode = 1
+	}
+
+	return nil
+}
@@ -0,0 +1,176 @@
+package cli
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestConcurrency(t *testing.T) {
+	assert.Equal(t, 1, concurrency(0))
+	assert.Equal(t, 1, concurrency(-5))
+	assert.Equal(t, 4, concurrency(4))
+	assert.Equal(t, maxConcurrency, concurrency(1000))
+}
+
+func TestForEachParallel(t *testing.T) {
+	var count int32
+	seen := make([]bool, 100)
+
+	forEachParallel(8, len(seen), func(i int) {
+		atomic.AddInt32(&count, 1)
+		seen[i] = true
+	})
+
+	assert.Equal(t, int32(100), count)
+	for i := range seen {
+		assert.True(t, seen[i])
+	}
+}
+
+func TestBackoffRetryAfter(t *testing.T) {
+	reset(false)
+
+	var waited time.Duration
+	rateLimitSleep = func(d time.Duration) { waited = d }
+	defer func() { rateLimitSleep = time.Sleep }()
+
+	var calls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&calls, 1) == 1 {
+			w.Header().Set("Retry-After", "30")
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, `{"ok": true}`)
+	}))
+	defer server.Close()
+
+	viper.Set("rsh-no-cache", true)
+	defer viper.Set("rsh-no-cache", false)
+
+	b := &backoff{}
+	resp, err := b.do(func() (*http.Request, error) {
+		return http.NewRequest(http.MethodGet, server.URL, nil)
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusOK, resp.Status)
+	assert.Equal(t, int32(2), calls)
+	assert.InDelta(t, 30*time.Second, waited, float64(time.Second))
+}
+
+func TestLinksResolve(t *testing.T) {
+	var inFlight, maxInFlight int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+
+		if r.URL.Path == "/" {
+			w.Header().Add("Link", `</a>; rel="item"`)
+			w.Header().Add("Link", `</b>; rel="item"`)
+			w.Header().Add("Link", `</author>; rel="author"`)
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+
+		n := atomic.AddInt32(&inFlight, 1)
+		defer atomic.AddInt32(&inFlight, -1)
+		for {
+			m := atomic.LoadInt32(&maxInFlight)
+			if n <= m || atomic.CompareAndSwapInt32(&maxInFlight, m, n) {
+				break
+			}
+		}
+		time.Sleep(20 * time.Millisecond)
+
+		fmt.Fprintf(w, `{"path": "%s"}`, r.URL.Path)
+	}))
+	defer server.Close()
+
+	captured := run("links " + server.URL + "/ --resolve --concurrency 2 --rsh-no-cache")
+	assert.JSONEq(t, `{
+		"author": {"path": "/author"},
+		"item": [
+			{"path": "/a"},
+			{"path": "/b"}
+		]
+	}`, captured)
+	assert.LessOrEqual(t, maxInFlight, int32(2))
+
+	captured = run("links " + server.URL + "/ author --resolve --rsh-no-cache")
+	assert.JSONEq(t, `{"author": {"path": "/author"}}`, captured)
+}
+
+func TestGetParallel(t *testing.T) {
+	reset(false)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/missing" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprintf(w, `{"path": "%s"}`, r.URL.Path)
+	}))
+	defer server.Close()
+
+	viper.Set("rsh-no-cache", true)
+	viper.Set("rsh-filter", "body")
+	defer viper.Set("rsh-no-cache", false)
+	defer viper.Set("rsh-filter", "")
+	defer func() { exitCode = 0 }()
+
+	captured := &strings.Builder{}
+	Stdout = captured
+	Stderr = captured
+
+	input := strings.Join([]string{
+		"# Comments and blank lines are ignored",
+		server.URL + "/one",
+		"",
+		server.URL + "/two",
+		server.URL + "/missing",
+	}, "\n")
+
+	assert.NoError(t, getParallel(strings.NewReader(input), 2))
+
+	out := captured.String()
+	assert.Contains(t, out, server.URL+"/one\n{\n  \"path\": \"/one\"\n}")
+	assert.Contains(t, out, server.URL+"/two\n{\n  \"path\": \"/two\"\n}")
+	assert.Contains(t, out, server.URL+"/missing")
+	assert.Equal(t, 1, exitCode)
+}
+
+func TestReadURLs(t *testing.T) {
+	uris, err := readURLs(strings.NewReader("\n  https://example.com/a  \n# skip\nhttps://example.com/b\n"))
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"https://example.com/a", "https://example.com/b"}, uris)
+}
+
+func TestGetParallelInvalidProfile(t *testing.T) {
+	reset(false)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+
+	viper.Set("rsh-profile", "missing")
+	defer viper.Set("rsh-profile", "default")
+	defer func() { exitCode = 0 }()
+
+	captured := &strings.Builder{}
+	Stdout = captured
+	Stderr = captured
+
+	// Errors from workers are reported per URL rather than crashing.
+	assert.NoError(t, getParallel(strings.NewReader(server.URL+"/one\n"+server.URL+"/two"), 2))
+	assert.Contains(t, captured.String(), server.URL+"/one: invalid profile missing")
+	assert.Contains(t, captured.String(), server.URL+"/two: invalid profile missing")
+	assert.Equal(t, 1, exitCode)
+}
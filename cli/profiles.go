@@ -0,0 +1,166 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+
+	"github.com/spf13/viper"
+)
+
+// sensitiveParam matches the names of auth params which usually contain
+// credentials, e.g. `password` or `client_secret`.
+var sensitiveParam = regexp.MustCompile(`(?i)password|secret|token|^value$|^key$|api[-_]?key`)
+
+// profileInfo summarizes a configured profile for display.
+type profileInfo struct {
+	Name    string                   `json:"name"`
+	Auth    []map[string]interface{} `json:"auth"`
+	Headers map[string]string        `json:"headers,omitempty"`
+	Query   map[string]string        `json:"query,omitempty"`
+}
+
+// maskSecret hides a credential unless `rsh-show-secrets` is set.
+func maskSecret(value string) string {
+	if value == "" || viper.GetBool("rsh-show-secrets") {
+		return value
+	}
+	return "REDACTED"
+}
+
+// maskValues returns a copy of the values with those whose names match the
+// pattern masked.
+func maskValues(values map[string]string, pattern *regexp.Regexp) map[string]string {
+	if len(values) == 0 {
+		return nil
+	}
+
+	masked := map[string]string{}
+	for k, v := range values {
+		if pattern.MatchString(k) {
+			v = maskSecret(v)
+		}
+		masked[k] = v
+	}
+	return masked
+}
+
+// apiProfiles returns the profiles configured for an API, sorted by name,
+// with credentials masked.
+func apiProfiles(config *APIConfig) []profileInfo {
+	names := []string{}
+	for name := range config.Profiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	profiles := []profileInfo{}
+	for _, name := range names {
+		p := config.Profiles[name]
+		if p == nil {
+			continue
+		}
+
+		info := profileInfo{
+			Name:    name,
+			Auth:    []map[string]interface{}{},
+			Headers: maskValues(p.Headers, sensitiveHeader),
+			Query:   maskValues(p.Query, sensitiveParam),
+		}
+
+		for _, a := range p.Auths() {
+			auth := map[string]interface{}{"name": a.Name}
+			if _, ok := authHandlers[a.Name]; !ok {
+				auth["unknown"] = true
+			}
+			if params := maskValues(a.Params, sensitiveParam); params != nil {
+				auth["params"] = params
+			}
+			info.Auth = append(info.Auth, auth)
+		}
+
+		profiles = append(profiles, info)
+	}
+
+	return profiles
+}
+
+// sortedKeys returns the keys of the map in sorted order.
+func sortedKeys(m map[string]string) []string {
+	keys := []string{}
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// listProfiles prints the profiles configured for the named API.
+func listProfiles(apiName string) error {
+	config := configs[apiName]
+	if config == nil {
+		return fmt.Errorf("API %s not found", apiName)
+	}
+
+	profiles := apiProfiles(config)
+
+	if viper.GetString("rsh-output-format") == "json" {
+		encoded, err := json.MarshalIndent(profiles, "", "  ")
+		if err != nil {
+			return err
+		}
+
+		if tty {
+			if encoded, err = Highlight("json", encoded); err != nil {
+				return err
+			}
+		}
+
+		fmt.Fprintln(Stdout, string(encoded))
+		return nil
+	}
+
+	if len(profiles) == 0 {
+		fmt.Fprintf(Stdout, "No profiles configured for %s\n", apiName)
+		return nil
+	}
+
+	for _, p := range profiles {
+		fmt.Fprintln(Stdout, au.Bold(p.Name))
+
+		if len(p.Auth) == 0 {
+			fmt.Fprintln(Stdout, "  auth: none")
+		}
+
+		for _, a := range p.Auth {
+			name := a["name"].(string)
+			if a["unknown"] == true {
+				name += " (unknown auth type)"
+			}
+			fmt.Fprintf(Stdout, "  auth: %s\n", name)
+
+			if params, ok := a["params"].(map[string]string); ok {
+				for _, k := range sortedKeys(params) {
+					fmt.Fprintf(Stdout, "    %s: %s\n", k, params[k])
+				}
+			}
+		}
+
+		for _, section := range []struct {
+			name   string
+			values map[string]string
+		}{{"headers", p.Headers}, {"query", p.Query}} {
+			if len(section.values) == 0 {
+				continue
+			}
+
+			fmt.Fprintf(Stdout, "  %s:\n", section.name)
+			for _, k := range sortedKeys(section.values) {
+				fmt.Fprintf(Stdout, "    %s: %s\n", k, section.values[k])
+			}
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,51 @@
+package cli
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestListProfiles(t *testing.T) {
+	reset(false)
+
+	configs["profiles"] = &APIConfig{
+		Base: "https://api.example.com",
+		Profiles: map[string]*APIProfile{
+			"default": {
+				Auth: &APIAuth{
+					Name:   "http-basic",
+					Params: map[string]string{"username": "alice", "password": "hunter2"},
+				},
+				Headers: map[string]string{"X-API-Key": "abc123", "Accept-Language": "en"},
+			},
+			"ci": {
+				Auth:      &APIAuth{Name: "bearer-token", Params: map[string]string{"token": "${CI_TOKEN}"}},
+				AuthChain: []*APIAuth{{Name: "custom"}},
+			},
+		},
+	}
+	defer delete(configs, "profiles")
+
+	captured := &strings.Builder{}
+	Stdout = captured
+	assert.NoError(t, listProfiles("profiles"))
+
+	out := captured.String()
+	assert.Less(t, strings.Index(out, "ci"), strings.Index(out, "default"))
+	assert.Contains(t, out, "auth: bearer-token\n    token: REDACTED")
+	assert.Contains(t, out, "auth: custom (unknown auth type)")
+	assert.Contains(t, out, "    password: REDACTED\n    username: alice")
+	assert.Contains(t, out, "    Accept-Language: en\n    X-API-Key: REDACTED")
+	assert.NotContains(t, out, "hunter2")
+
+	viper.Set("rsh-show-secrets", true)
+	defer viper.Set("rsh-show-secrets", false)
+	captured.Reset()
+	assert.NoError(t, listProfiles("profiles"))
+	assert.Contains(t, captured.String(), "password: hunter2")
+
+	assert.EqualError(t, listProfiles("missing"), "API missing not found")
+}
@@ -0,0 +1,197 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"mime"
+	"strings"
+	"sync"
+
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/dynamicpb"
+)
+
+// ProtobufConfig describes the messages of an API which sends or accepts
+// Protocol Buffers bodies.
+type ProtobufConfig struct {
+	// Descriptors is the path to a compiled `FileDescriptorSet`, e.g. from
+	// `protoc --include_imports --descriptor_set_out=api.pb api.proto`.
+	Descriptors string `json:"descriptors"`
+
+	// Message is the fully-qualified message type of response bodies, e.g.
+	// `example.v1.Item`. A `messageType` content type parameter sent by the
+	// server takes precedence.
+	Message string `json:"message,omitempty"`
+
+	// RequestMessage is the message type of request bodies if it differs from
+	// `Message`, e.g. `example.v1.CreateItemRequest`.
+	RequestMessage string `json:"request_message,omitempty" mapstructure:"request_message,omitempty"`
+}
+
+// protobufFiles caches loaded descriptor sets by path.
+var protobufFiles = map[string]*protoregistry.Files{}
+var protobufFilesMu sync.Mutex
+
+// loadDescriptors reads a compiled `FileDescriptorSet`.
+func loadDescriptors(filename string) (*protoregistry.Files, error) {
+	protobufFilesMu.Lock()
+	defer protobufFilesMu.Unlock()
+
+	if files := protobufFiles[filename]; files != nil {
+		return files, nil
+	}
+
+	data, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, fmt.Errorf("cannot read protobuf descriptors: %w", err)
+	}
+
+	set := &descriptorpb.FileDescriptorSet{}
+	if err := proto.Unmarshal(data, set); err != nil {
+		return nil, fmt.Errorf("cannot decode protobuf descriptors %s: %w", filename, err)
+	}
+
+	files, err := protodesc.NewFiles(set)
+	if err != nil {
+		return nil, fmt.Errorf("invalid protobuf descriptors %s: %w", filename, err)
+	}
+
+	protobufFiles[filename] = files
+	return files, nil
+}
+
+// newProtobufMessage creates an empty message of the type described by the
+// content type parameters, see `protobufContentType`.
+func newProtobufMessage(contentType string) (*dynamicpb.Message, error) {
+	_, params, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return nil, err
+	}
+
+	if params["descriptors"] == "" {
+		return nil, fmt.Errorf("no protobuf descriptors configured, set `protobuf.descriptors` in the API config")
+	}
+
+	name := params["messagetype"]
+	if name == "" {
+		name = params["message"]
+	}
+	if name == "" {
+		return nil, fmt.Errorf("no protobuf message type configured, set `protobuf.message` in the API config")
+	}
+
+	files, err := loadDescriptors(params["descriptors"])
+	if err != nil {
+		return nil, err
+	}
+
+	desc, err := files.FindDescriptorByName(protoreflect.FullName(strings.TrimPrefix(name, ".")))
+	if err != nil {
+		return nil, fmt.Errorf("protobuf message %s not found in %s", name, params["descriptors"])
+	}
+
+	md, ok := desc.(protoreflect.MessageDescriptor)
+	if !ok {
+		return nil, fmt.Errorf("protobuf %s is not a message", name)
+	}
+
+	return dynamicpb.NewMessage(md), nil
+}
+
+// protobufContentType adds the descriptor set and message type from the API
+// config for the URI as parameters to the content type so that the `Protobuf`
+// content type can encode or decode bodies. These parameters are only used
+// internally and never sent to the server.
+func protobufContentType(uri, contentType string, request bool) (string, error) {
+	_, config := findAPI(uri)
+	if config == nil || config.Protobuf == nil || config.Protobuf.Descriptors == "" {
+		return "", fmt.Errorf("no protobuf descriptors configured for %s, set `protobuf.descriptors` in the API config", uri)
+	}
+
+	mediaType, params, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return "", err
+	}
+
+	params["descriptors"] = config.Protobuf.Descriptors
+	params["message"] = config.Protobuf.Message
+	if request {
+		// The request message type is always ours to choose.
+		delete(params, "messagetype")
+		if config.Protobuf.RequestMessage != "" {
+			params["message"] = config.Protobuf.RequestMessage
+		}
+	}
+
+	return mime.FormatMediaType(mediaType, params), nil
+}
+
+// Protobuf describes content types like `application/x-protobuf`. Since the
+// encoding is not self-describing, messages are decoded using a descriptor
+// set from the API config. https://developers.google.com/protocol-buffers
+type Protobuf struct{}
+
+// Detect if the content type is Protocol Buffers.
+func (p Protobuf) Detect(contentType string) bool {
+	first := strings.TrimSpace(strings.Split(contentType, ";")[0])
+	return first == "application/x-protobuf" || first == "application/protobuf" || first == "application/vnd.google.protobuf"
+}
+
+// Marshal the value. Without the content type parameters the message type is
+// unknown, so this always fails. See `MarshalContentType`.
+func (p Protobuf) Marshal(value interface{}) ([]byte, error) {
+	return p.MarshalContentType("application/x-protobuf", value)
+}
+
+// Unmarshal the value. Without the content type parameters the message type
+// is unknown, so this always fails. See `UnmarshalContentType`.
+func (p Protobuf) Unmarshal(data []byte, value interface{}) error {
+	return p.UnmarshalContentType("application/x-protobuf", data, value)
+}
+
+// MarshalContentType encodes the value as the message described by the
+// content type parameters. The value uses the protobuf JSON mapping, so
+// field names may be in `lowerCamelCase` or as written in the `.proto` file.
+func (p Protobuf) MarshalContentType(contentType string, value interface{}) ([]byte, error) {
+	msg, err := newProtobufMessage(contentType)
+	if err != nil {
+		return nil, err
+	}
+
+	encoded, err := json.Marshal(makeJSONSafe(value))
+	if err != nil {
+		return nil, err
+	}
+
+	if err := protojson.Unmarshal(encoded, msg); err != nil {
+		return nil, fmt.Errorf("cannot convert body to %s: %w", msg.Descriptor().FullName(), err)
+	}
+
+	return proto.Marshal(msg)
+}
+
+// UnmarshalContentType decodes the message described by the content type
+// parameters into generic data using the protobuf JSON mapping.
+func (p Protobuf) UnmarshalContentType(contentType string, data []byte, value interface{}) error {
+	msg, err := newProtobufMessage(contentType)
+	if err != nil {
+		return err
+	}
+
+	if err := proto.Unmarshal(data, msg); err != nil {
+		return fmt.Errorf("cannot decode %s: %w", msg.Descriptor().FullName(), err)
+	}
+
+	encoded, err := protojson.Marshal(msg)
+	if err != nil {
+		return err
+	}
+
+	return json.Unmarshal(encoded, value)
+}
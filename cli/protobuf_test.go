@@ -0,0 +1,154 @@
+package cli
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path"
+	"reflect"
+	"testing"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"gopkg.in/h2non/gock.v1"
+)
+
+// writeTestDescriptors writes a descriptor set for `test.Item`.
+func writeTestDescriptors(t *testing.T) string {
+	set := &descriptorpb.FileDescriptorSet{
+		File: []*descriptorpb.FileDescriptorProto{{
+			Name:    proto.String("item.proto"),
+			Package: proto.String("test"),
+			Syntax:  proto.String("proto3"),
+			MessageType: []*descriptorpb.DescriptorProto{{
+				Name: proto.String("Item"),
+				Field: []*descriptorpb.FieldDescriptorProto{
+					{
+						Name:     proto.String("name"),
+						JsonName: proto.String("name"),
+						Number:   proto.Int32(1),
+						Label:    descriptorpb.FieldDescriptorProto_LABEL_OPTIONAL.Enum(),
+						Type:     descriptorpb.FieldDescriptorProto_TYPE_STRING.Enum(),
+					},
+					{
+						Name:     proto.String("item_count"),
+						JsonName: proto.String("itemCount"),
+						Number:   proto.Int32(2),
+						Label:    descriptorpb.FieldDescriptorProto_LABEL_OPTIONAL.Enum(),
+						Type:     descriptorpb.FieldDescriptorProto_TYPE_INT32.Enum(),
+					},
+				},
+			}},
+		}},
+	}
+
+	data, err := proto.Marshal(set)
+	assert.NoError(t, err)
+
+	dir, err := ioutil.TempDir("", "restish-protobuf")
+	assert.NoError(t, err)
+	t.Cleanup(func() { os.RemoveAll(dir) })
+
+	filename := path.Join(dir, "item.pb")
+	assert.NoError(t, ioutil.WriteFile(filename, data, 0600))
+
+	return filename
+}
+
+func TestProtobufRoundTrip(t *testing.T) {
+	reset(false)
+
+	configs["proto"] = &APIConfig{
+		Base: "http://proto.example.com",
+		Protobuf: &ProtobufConfig{
+			Descriptors: writeTestDescriptors(t),
+			Message:     "test.Item",
+		},
+	}
+
+	ct, err := protobufContentType("http://proto.example.com/items/1", "application/x-protobuf", false)
+	assert.NoError(t, err)
+
+	encoded, err := Marshal(ct, map[string]interface{}{"name": "foo", "item_count": 3})
+	assert.NoError(t, err)
+
+	var decoded interface{}
+	assert.NoError(t, Unmarshal(ct, encoded, &decoded))
+	assert.Equal(t, map[string]interface{}{"name": "foo", "itemCount": 3.0}, decoded)
+
+	_, err = Marshal(ct, map[string]interface{}{"unknown": true})
+	assert.Error(t, err)
+}
+
+func TestProtobufMissingConfig(t *testing.T) {
+	reset(false)
+
+	_, err := protobufContentType("http://unknown.example.com/items", "application/x-protobuf", false)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "protobuf.descriptors")
+
+	configs["proto"] = &APIConfig{
+		Base: "http://proto.example.com",
+		Protobuf: &ProtobufConfig{
+			Descriptors: writeTestDescriptors(t),
+		},
+	}
+
+	ct, err := protobufContentType("http://proto.example.com/items", "application/x-protobuf", false)
+	assert.NoError(t, err)
+
+	var decoded interface{}
+	err = Unmarshal(ct, []byte{}, &decoded)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "protobuf.message")
+}
+
+func TestProtobufRequest(t *testing.T) {
+	defer gock.Off()
+	reset(false)
+
+	configs["proto"] = &APIConfig{
+		Base: "http://proto.example.com",
+		Protobuf: &ProtobufConfig{
+			Descriptors: writeTestDescriptors(t),
+			Message:     "test.Item",
+		},
+	}
+	viper.Set("rsh-header", []string{"Content-Type:application/x-protobuf"})
+
+	req, body, err := newGenericRequest(http.MethodPost, "http://proto.example.com/items", []string{"name:", "foo,", "itemCount:", "2"})
+	require.NoError(t, err)
+
+	ct, err := protobufContentType("http://proto.example.com/items", "application/x-protobuf", true)
+	require.NoError(t, err)
+
+	// Gock can't compare binary bodies, so decode the sent message instead.
+	gock.New("http://proto.example.com").
+		Post("/items").
+		MatchHeader("Content-Type", "^application/x-protobuf$").
+		AddMatcher(func(r *http.Request, _ *gock.Request) (bool, error) {
+			data, err := ioutil.ReadAll(r.Body)
+			if err != nil {
+				return false, err
+			}
+			r.Body = ioutil.NopCloser(bytes.NewReader(data))
+
+			var sent interface{}
+			if err := Unmarshal(ct, data, &sent); err != nil {
+				return false, nil
+			}
+			return reflect.DeepEqual(sent, map[string]interface{}{"name": "foo", "itemCount": 2.0}), nil
+		}).
+		Reply(http.StatusCreated).
+		SetHeader("Content-Type", "application/x-protobuf").
+		BodyString(body)
+
+	resp, err := GetParsedResponse(req)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusCreated, resp.Status)
+	assert.Equal(t, map[string]interface{}{"name": "foo", "itemCount": 2.0}, resp.Body)
+}
@@ -0,0 +1,22 @@
+package cli
+
+import (
+	"math/rand"
+	"time"
+
+	"github.com/spf13/viper"
+)
+
+// seedRandom seeds the random number generator used for non-security related
+// randomness like retry jitter. Setting `rsh-seed` makes these behaviors
+// reproducible for tests and demos, otherwise the current time is used.
+func seedRandom() {
+	seed := viper.GetInt64("rsh-seed")
+	if seed == 0 {
+		seed = time.Now().UnixNano()
+	} else {
+		LogDebug("Using random seed %d", seed)
+	}
+
+	rand.Seed(seed)
+}
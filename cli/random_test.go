@@ -0,0 +1,25 @@
+package cli
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSeedRandom(t *testing.T) {
+	viper.Set("rsh-seed", 42)
+	viper.Set("rsh-retry-delay", "1s")
+	defer viper.Set("rsh-seed", 0)
+
+	resp := &http.Response{Header: http.Header{}}
+
+	seedRandom()
+	first := []interface{}{retryDelay(resp, 1), retryDelay(resp, 2)}
+
+	seedRandom()
+	second := []interface{}{retryDelay(resp, 1), retryDelay(resp, 2)}
+
+	assert.Equal(t, first, second)
+}
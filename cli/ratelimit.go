@@ -0,0 +1,113 @@
+package cli
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// rateLimit describes the client's quota as advertised by the server.
+type rateLimit struct {
+	Limit     int
+	Remaining int
+	Reset     time.Duration
+}
+
+// low returns whether few requests remain in the current window, which is
+// under 10% of the limit, or 5 or fewer if the limit is unknown.
+func (r rateLimit) low() bool {
+	if r.Limit > 0 {
+		return r.Remaining*10 < r.Limit
+	}
+	return r.Remaining <= 5
+}
+
+// parseRateLimit parses rate limit headers, supporting the common
+// `X-RateLimit-*` headers, the draft RFC `RateLimit-*` headers, and the
+// newer combined draft `RateLimit: limit=100, remaining=50, reset=30`.
+// Returns false if there is no remaining count.
+func parseRateLimit(header http.Header, now time.Time) (rateLimit, bool) {
+	fields := map[string]string{}
+	for _, prefix := range []string{"X-RateLimit-", "RateLimit-"} {
+		for _, name := range []string{"limit", "remaining", "reset"} {
+			if v := header.Get(prefix + name); v != "" {
+				fields[name] = v
+			}
+		}
+	}
+
+	for _, item := range strings.Split(header.Get("RateLimit"), ",") {
+		if parts := strings.SplitN(strings.TrimSpace(item), "=", 2); len(parts) == 2 {
+			fields[strings.ToLower(parts[0])] = parts[1]
+		}
+	}
+
+	remaining, ok := leadingInt(fields["remaining"])
+	if !ok {
+		return rateLimit{}, false
+	}
+
+	r := rateLimit{Remaining: remaining}
+	r.Limit, _ = leadingInt(fields["limit"])
+
+	if reset, ok := leadingInt(fields["reset"]); ok {
+		if reset > 1000000000 {
+			// Large values are a Unix timestamp rather than delta seconds, as
+			// used by e.g. GitHub's `X-RateLimit-Reset`.
+			r.Reset = time.Unix(int64(reset), 0).Sub(now)
+		} else {
+			r.Reset = time.Duration(reset) * time.Second
+		}
+
+		if r.Reset < 0 {
+			r.Reset = 0
+		}
+	}
+
+	return r, true
+}
+
+// leadingInt parses the integer at the start of a header value, ignoring
+// any parameters like the window in `100, 100;w=60`.
+func leadingInt(value string) (int, bool) {
+	value = strings.TrimSpace(value)
+	end := 0
+	for end < len(value) && value[end] >= '0' && value[end] <= '9' {
+		end++
+	}
+
+	i, err := strconv.Atoi(value[:end])
+	return i, err == nil
+}
+
+// noteRateLimit warns when the response says few requests remain.
+func noteRateLimit(resp *http.Response) {
+	r, ok := parseRateLimit(resp.Header, time.Now())
+	if !ok || !r.low() {
+		return
+	}
+
+	msg := "Rate limit: " + strconv.Itoa(r.Remaining)
+	if r.Limit > 0 {
+		msg += " of " + strconv.Itoa(r.Limit)
+	}
+	msg += " requests remaining"
+	if r.Reset > 0 {
+		msg += ", resets in " + r.Reset.Round(time.Second).String()
+	}
+
+	LogWarning("%s", msg)
+}
+
+// waitForRateLimit pauses until the rate limit window resets if there are no
+// requests remaining, e.g. before fetching the next page.
+func waitForRateLimit(resp *http.Response) {
+	if r, ok := parseRateLimit(resp.Header, time.Now()); ok && r.Remaining == 0 && r.Reset > 0 {
+		LogInfo("Rate limit reached, waiting %s for it to reset", r.Reset.Round(time.Second))
+		rateLimitSleep(r.Reset)
+	}
+}
+
+// rateLimitSleep waits for the given duration and can be overridden in tests.
+var rateLimitSleep = time.Sleep
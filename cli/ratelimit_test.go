@@ -0,0 +1,76 @@
+package cli
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/h2non/gock.v1"
+)
+
+func TestParseRateLimit(t *testing.T) {
+	now := time.Unix(1600000000, 0)
+
+	r, ok := parseRateLimit(http.Header{
+		"X-Ratelimit-Limit":     []string{"5000"},
+		"X-Ratelimit-Remaining": []string{"12"},
+		"X-Ratelimit-Reset":     []string{"1600000090"},
+	}, now)
+	assert.True(t, ok)
+	assert.Equal(t, rateLimit{Limit: 5000, Remaining: 12, Reset: 90 * time.Second}, r)
+	assert.True(t, r.low())
+
+	r, ok = parseRateLimit(http.Header{
+		"Ratelimit-Limit":     []string{"100, 100;w=60"},
+		"Ratelimit-Remaining": []string{"50"},
+		"Ratelimit-Reset":     []string{"30"},
+	}, now)
+	assert.True(t, ok)
+	assert.Equal(t, rateLimit{Limit: 100, Remaining: 50, Reset: 30 * time.Second}, r)
+	assert.False(t, r.low())
+
+	r, ok = parseRateLimit(http.Header{
+		"Ratelimit": []string{"limit=10, remaining=0, reset=5"},
+	}, now)
+	assert.True(t, ok)
+	assert.Equal(t, rateLimit{Limit: 10, Remaining: 0, Reset: 5 * time.Second}, r)
+
+	_, ok = parseRateLimit(http.Header{}, now)
+	assert.False(t, ok)
+}
+
+func TestRateLimitPagination(t *testing.T) {
+	defer gock.Off()
+	reset(false)
+
+	var waited time.Duration
+	rateLimitSleep = func(d time.Duration) { waited = d }
+	defer func() { rateLimitSleep = time.Sleep }()
+
+	gock.New("http://example.com").
+		Get("/limited").
+		Reply(http.StatusOK).
+		SetHeader("Link", "</limited2>; rel=\"next\"").
+		SetHeader("RateLimit-Remaining", "0").
+		SetHeader("RateLimit-Reset", "7").
+		JSON([]interface{}{1})
+	gock.New("http://example.com").
+		Get("/limited2").
+		Reply(http.StatusOK).
+		SetHeader("RateLimit-Remaining", "9").
+		JSON([]interface{}{2})
+
+	captured := &strings.Builder{}
+	Stderr = captured
+
+	req, _ := http.NewRequest(http.MethodGet, "http://example.com/limited", nil)
+	resp, err := GetParsedResponse(req)
+	assert.NoError(t, err)
+	assert.Equal(t, []interface{}{1.0, 2.0}, resp.Body)
+
+	assert.Equal(t, 7*time.Second, waited)
+	assert.Contains(t, captured.String(), "Rate limit: 0 requests remaining, resets in 7s")
+	assert.Contains(t, captured.String(), "waiting 7s")
+}
@@ -2,6 +2,7 @@ package cli
 
 import (
 	"encoding/hex"
+	"encoding/json"
 	"fmt"
 	"math"
 	"reflect"
@@ -9,14 +10,46 @@ import (
 	"strconv"
 	"strings"
 	"time"
+
+	"github.com/spf13/viper"
 )
 
+// formatTime renders a time using the `rsh-time-format` setting, which may be
+// a Go time layout or one of `rfc3339`, `unix`, or `kitchen`. Defaults to
+// RFC 3339 with nanoseconds in UTC.
+func formatTime(t time.Time) string {
+	layout := viper.GetString("rsh-time-format")
+
+	switch strings.ToLower(layout) {
+	case "":
+		return t.UTC().Format(time.RFC3339Nano)
+	case "rfc3339":
+		return t.UTC().Format(time.RFC3339)
+	case "unix":
+		return strconv.FormatInt(t.Unix(), 10)
+	case "kitchen":
+		return t.Format(time.Kitchen)
+	}
+
+	return t.UTC().Format(layout)
+}
+
 // MarshalReadable marshals a value into a human-friendly readable format.
 func MarshalReadable(v interface{}) ([]byte, error) {
 	return marshalReadable("", v)
 }
 
 func marshalReadable(indent string, v interface{}) ([]byte, error) {
+	if n, ok := v.(json.Number); ok {
+		// Precise numbers are output exactly as they were received.
+		return []byte(n.String()), nil
+	}
+
+	if t, ok := v.(truncated); ok {
+		// Placeholders for data beyond the max depth are not quoted.
+		return []byte(t), nil
+	}
+
 	rv := reflect.ValueOf(v)
 	switch rv.Kind() {
 	case reflect.Invalid:
@@ -105,7 +138,7 @@ func marshalReadable(indent string, v interface{}) ([]byte, error) {
 		}
 
 		s := ""
-		if !hasNewlines && len(indent)+(len(lines)*2)+length < 80 {
+		if !hasNewlines && (len(indent)+(len(lines)*2)+length < 80 || viper.GetBool("rsh-body-compact-arrays")) {
 			// Special-case: short array gets inlined like [1, 2, 3]
 			s += "[" + strings.Join(lines, ", ") + "]"
 		} else {
@@ -148,7 +181,7 @@ func marshalReadable(indent string, v interface{}) ([]byte, error) {
 		return []byte(m), nil
 	case reflect.Struct:
 		if t, ok := v.(time.Time); ok {
-			return []byte(t.UTC().Format(time.RFC3339Nano)), nil
+			return []byte(formatTime(t)), nil
 		}
 
 		// TODO: user-defined structs, go through each field.
@@ -4,6 +4,7 @@ import (
 	"testing"
 	"time"
 
+	"github.com/spf13/viper"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -60,3 +61,25 @@ func TestSingleItemWithNewlines(t *testing.T) {
   }
 ]`, string(encoded))
 }
+
+func TestReadableTimeFormat(t *testing.T) {
+	defer viper.Set("rsh-time-format", "")
+
+	created := time.Date(2020, 8, 12, 15, 4, 5, 0, time.UTC)
+
+	for _, tt := range []struct {
+		format   string
+		expected string
+	}{
+		{"", "2020-08-12T15:04:05Z"},
+		{"rfc3339", "2020-08-12T15:04:05Z"},
+		{"unix", "1597244645"},
+		{"kitchen", "3:04PM"},
+		{"2006-01-02", "2020-08-12"},
+	} {
+		viper.Set("rsh-time-format", tt.format)
+		encoded, err := MarshalReadable(created)
+		assert.NoError(t, err)
+		assert.Equal(t, tt.expected, string(encoded))
+	}
+}
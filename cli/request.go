@@ -1,12 +1,13 @@
 package cli
 
 import (
-	"crypto/tls"
+	"context"
 	"crypto/x509"
+	"errors"
 	"fmt"
 	"io/ioutil"
 	"net/http"
-	"net/url"
+	"net/http/httputil"
 	"strconv"
 	"strings"
 	"time"
@@ -20,7 +21,7 @@ func fixAddress(addr string) string {
 		addr = "http://localhost" + addr
 	}
 
-	if !strings.HasPrefix(addr, "http://") && !strings.HasPrefix(addr, "https://") {
+	if !strings.HasPrefix(addr, "http://") && !strings.HasPrefix(addr, "https://") && !strings.HasPrefix(addr, "http+unix://") {
 		// Does the first part match a known API? If so, replace it with
 		// the base URL for that API.
 		parts := strings.Split(addr, "/")
@@ -42,8 +43,9 @@ func fixAddress(addr string) string {
 }
 
 type requestOption struct {
-	client     *http.Client
-	disableLog bool
+	client       *http.Client
+	disableLog   bool
+	ignoreDryRun bool
 }
 
 // WithClient sets the client to use for the request.
@@ -60,6 +62,41 @@ func WithoutLog() requestOption {
 	}
 }
 
+// WithoutDryRun sends the request even in dry-run mode, e.g. for internal
+// requests like loading API descriptions.
+func WithoutDryRun() requestOption {
+	return requestOption{
+		ignoreDryRun: true,
+	}
+}
+
+// ErrDryRun is returned instead of a response when `rsh-dry-run` is enabled
+// and the request was printed rather than sent.
+var ErrDryRun = errors.New("dry run, request not sent")
+
+// printDryRun writes the fully prepared request to stdout as raw HTTP, with
+// credentials in headers and query params redacted unless `rsh-show-secrets`
+// is set.
+func printDryRun(req *http.Request) error {
+	original, originalURL := req.Header, req.URL
+	req.Header, req.URL = redactHeaders(original), redactURL(originalURL)
+	defer func() { req.Header, req.URL = original, originalURL }()
+
+	dumped, err := httputil.DumpRequest(req, true)
+	if err != nil {
+		return err
+	}
+
+	if tty {
+		if dumped, err = Highlight("http", dumped); err != nil {
+			return err
+		}
+	}
+
+	fmt.Fprintln(Stdout, string(dumped))
+	return nil
+}
+
 // MakeRequest makes an HTTP request using the default client. It adds the
 // user-agent, auth, and any passed headers or query params to the request
 // before sending it out on the wire. If verbose mode is enabled, it will
@@ -79,12 +116,17 @@ func MakeRequest(req *http.Request, options ...requestOption) (*http.Response, e
 
 	if profile == nil {
 		if viper.GetString("rsh-profile") != "default" {
-			panic("Invalid profile " + viper.GetString("rsh-profile"))
+			return nil, fmt.Errorf("invalid profile %s", viper.GetString("rsh-profile"))
 		}
 
 		profile = &APIProfile{}
 	}
 
+	profile, err := profile.ExpandEnv()
+	if err != nil {
+		return nil, fmt.Errorf("profile %s: %w", viper.GetString("rsh-profile"), err)
+	}
+
 	// Now that we have the profile, set up profile-based headers/params.
 	query := req.URL.Query()
 	for k, v := range profile.Headers {
@@ -93,9 +135,13 @@ func MakeRequest(req *http.Request, options ...requestOption) (*http.Response, e
 		}
 	}
 
+	// Only re-encode the query if params are added, so that links like an
+	// OData `$skiptoken` are sent verbatim.
+	queryChanged := false
 	for k, v := range profile.Query {
 		if query.Get(k) == "" {
 			query.Add(k, v)
+			queryChanged = true
 		}
 	}
 
@@ -118,28 +164,43 @@ func MakeRequest(req *http.Request, options ...requestOption) (*http.Response, e
 		}
 
 		query.Add(parts[0], value)
+		queryChanged = true
 	}
 
-	// Save modified query string arguments.
-	req.URL.RawQuery = query.Encode()
+	if since := viper.GetString("rsh-since"); since != "" {
+		sinceConfig := SinceConfig{}
+		if config.Since != nil {
+			sinceConfig = *config.Since
+		}
 
-	// Add auth if needed.
-	if profile.Auth != nil && profile.Auth.Name != "" {
-		auth, ok := authHandlers[profile.Auth.Name]
-		if ok {
-			err := auth.OnRequest(req, name+":"+viper.GetString("rsh-profile"), profile.Auth.Params)
+		// Links to further pages may already include the param.
+		if query.Get(sinceConfig.param()) == "" {
+			value, err := sinceConfig.value(since, time.Now())
 			if err != nil {
-				panic(err)
+				return nil, err
 			}
+			query.Set(sinceConfig.param(), value)
+			queryChanged = true
 		}
 	}
 
+	// Save modified query string arguments.
+	if queryChanged {
+		req.URL.RawQuery = query.Encode()
+	}
+
 	if req.Header.Get("user-agent") == "" {
-		req.Header.Set("user-agent", "restish-"+Root.Version)
+		req.Header.Set("user-agent", userAgent(config))
 	}
 
 	if req.Header.Get("accept") == "" {
-		req.Header.Set("accept", buildAcceptHeader())
+		accept := buildAcceptHeader()
+		if a := viper.GetString("rsh-accept"); a != "" {
+			if accept, err = acceptMediaTypes(a); err != nil {
+				return nil, err
+			}
+		}
+		req.Header.Set("accept", accept)
 	}
 
 	if req.Header.Get("accept-encoding") == "" {
@@ -151,12 +212,28 @@ func MakeRequest(req *http.Request, options ...requestOption) (*http.Response, e
 		req.Header.Set("content-type", "application/json; charset=utf-8")
 	}
 
-	client := CachedTransport().Client()
-	if viper.GetBool("rsh-no-cache") {
-		client = &http.Client{Transport: InvalidateCachedTransport()}
+	if viper.GetBool("rsh-auto-compress") || config.CompressRequests {
+		if err := CompressRequest(req, "gzip", viper.GetInt("rsh-auto-compress-min")); err != nil {
+			return nil, err
+		}
 	}
 
+	// Add auth if needed. Handlers are applied in order and the first failure
+	// stops the chain. This happens once all other headers are set and the
+	// body is final so that signatures can cover them.
+	for _, a := range profile.Auths() {
+		auth, ok := authHandlers[a.Name]
+		if ok {
+			err := auth.OnRequest(req, name+":"+viper.GetString("rsh-profile"), a.Params)
+			if err != nil {
+				return nil, fmt.Errorf("auth %s failed: %w", a.Name, err)
+			}
+		}
+	}
+
+	var client *http.Client
 	log := true
+	dryRun := viper.GetBool("rsh-dry-run")
 	for _, option := range options {
 		if option.client != nil {
 			client = option.client
@@ -165,66 +242,67 @@ func MakeRequest(req *http.Request, options ...requestOption) (*http.Response, e
 		if option.disableLog {
 			log = false
 		}
-	}
 
-	// The assumption is that all Transport implementations eventually use the
-	// default HTTP transport.
-	// We can therefore inject the TLS config once here, along with all the other
-	// config options, instead of modifying all the places where Transports are
-	// created
-	LogDebug("Adding TLS configuration")
-	if t, ok := http.DefaultTransport.(*http.Transport); ok {
-		if t.TLSClientConfig == nil {
-			t.TLSClientConfig = &tls.Config{}
-		}
-		if config.TLS == nil {
-			config.TLS = &TLSConfig{}
+		if option.ignoreDryRun {
+			dryRun = false
 		}
+	}
 
-		// CLI flags overwrite profile options
-		if viper.GetBool("rsh-insecure") {
-			config.TLS.InsecureSkipVerify = true
-		}
-		if cert := viper.GetString("rsh-client-cert"); cert != "" {
-			config.TLS.Cert = cert
-		}
-		if key := viper.GetString("rsh-client-key"); key != "" {
-			config.TLS.Key = key
-		}
-		if caCert := viper.GetString("rsh-ca-cert"); caCert != "" {
-			config.TLS.CACert = caCert
+	if log && viper.GetBool("rsh-curl") {
+		curl, err := curlCommand(req)
+		if err != nil {
+			return nil, err
 		}
 
-		if config.TLS.InsecureSkipVerify {
-			LogWarning("Disabling TLS security checks")
-			t.TLSClientConfig.InsecureSkipVerify = config.TLS.InsecureSkipVerify
+		if dryRun {
+			// Print just the command, e.g. to copy and share it.
+			fmt.Fprintln(Stdout, curl)
+			return nil, ErrDryRun
 		}
-		if config.TLS.Cert != "" {
-			cert, err := tls.LoadX509KeyPair(config.TLS.Cert, config.TLS.Key)
-			if err != nil {
-				return nil, err
-			}
-			t.TLSClientConfig.Certificates = append(t.TLSClientConfig.Certificates, cert)
+
+		fmt.Fprintln(Stderr, curl)
+	}
+
+	if dryRun {
+		if err := printDryRun(req); err != nil {
+			return nil, err
 		}
-		if config.TLS.CACert != "" {
-			caCert, err := ioutil.ReadFile(config.TLS.CACert)
-			if err != nil {
-				return nil, err
-			}
-			systemCerts := BestEffortSystemCertPool()
-			if !systemCerts.AppendCertsFromPEM(caCert) {
-				return nil, fmt.Errorf("Failed to append CACert %s RootCA list", config.TLS.CACert)
-			}
-			t.TLSClientConfig.RootCAs = systemCerts
+		return nil, ErrDryRun
+	}
+
+	if client == nil {
+		if client, err = defaultClient(config); err != nil {
+			return nil, err
 		}
 	}
 
+	if req, err = withUnixSocket(req); err != nil {
+		return nil, err
+	}
+
 	if log {
 		LogDebugRequest(req)
 	}
 
+	if viper.GetInt("rsh-retry") > 0 {
+		if err := bufferBody(req); err != nil {
+			return nil, err
+		}
+	}
+
+	var trace *requestTrace
+	if viper.GetString("rsh-trace-out") != "" {
+		req, trace = withTrace(req)
+	}
+
 	resp, err := client.Do(req)
 	if err != nil {
+		trace.finish(nil, err)
+		return nil, err
+	}
+
+	if err := checkHTTPVersion(resp); err != nil {
+		trace.finish(nil, err)
 		return nil, err
 	}
 
@@ -232,9 +310,121 @@ func MakeRequest(req *http.Request, options ...requestOption) (*http.Response, e
 		LogDebugResponse(start, resp)
 	}
 
+	maxRetries := viper.GetInt("rsh-retry")
+	for attempt := 1; attempt <= maxRetries && shouldRetry(resp); attempt++ {
+		delay := retryDelay(resp, attempt)
+		LogDebug("Got %d, retrying in %s (%d/%d)", resp.StatusCode, delay, attempt, maxRetries)
+		ioutil.ReadAll(resp.Body)
+		resp.Body.Close()
+		time.Sleep(delay)
+
+		if err := resetBody(req); err != nil {
+			return nil, err
+		}
+
+		start = time.Now()
+		resp, err = client.Do(req)
+		if err != nil {
+			trace.finish(nil, err)
+			return nil, err
+		}
+
+		if log {
+			LogDebugResponse(start, resp)
+		}
+	}
+
+	retries := viper.GetInt("rsh-optimistic-retries")
+	for attempt := 1; attempt <= retries && isConflict(req, resp); attempt++ {
+		LogWarning("Got %d for conditional write, re-fetching latest version and retrying (%d/%d)", resp.StatusCode, attempt, retries)
+		resp.Body.Close()
+
+		start = time.Now()
+		resp, err = optimisticRetry(client, req)
+		if err != nil {
+			return nil, err
+		}
+
+		if log {
+			LogDebugResponse(start, resp)
+		}
+	}
+
+	trace.finish(resp, nil)
+
+	if log {
+		noteRateLimit(resp)
+	}
+
 	return resp, nil
 }
 
+// isConflict returns whether a conditional (`If-Match`) write failed because
+// the resource was modified by someone else.
+func isConflict(req *http.Request, resp *http.Response) bool {
+	if req.Header.Get("If-Match") == "" {
+		return false
+	}
+
+	return resp.StatusCode == http.StatusPreconditionFailed || resp.StatusCode == http.StatusConflict
+}
+
+// canReapply returns whether a write can be safely re-applied on top of a
+// newer version of the resource. Merge patches only describe the changed
+// fields so they can, while full replacements or JSON patches cannot. Plain
+// JSON is not assumed to be a merge patch since its semantics are up to the
+// server.
+func canReapply(req *http.Request) bool {
+	if req.Method != http.MethodPatch || req.GetBody == nil {
+		return false
+	}
+
+	ct := strings.Split(req.Header.Get("Content-Type"), ";")[0]
+	return ct == "application/merge-patch+json"
+}
+
+// optimisticRetry re-fetches the latest version of the resource to get its
+// current ETag and then re-sends the original write conditioned on it.
+func optimisticRetry(client *http.Client, req *http.Request) (*http.Response, error) {
+	if !canReapply(req) {
+		return nil, fmt.Errorf("resource was modified and a %s with %s cannot be re-applied automatically, fetch the latest version and try again", req.Method, req.Header.Get("Content-Type"))
+	}
+
+	// Reuse the original headers (e.g. auth) but skip the cache to make sure
+	// the latest version is loaded.
+	get := req.Clone(req.Context())
+	get.Method = http.MethodGet
+	get.Body = nil
+	get.GetBody = nil
+	get.ContentLength = 0
+	get.Header.Del("If-Match")
+	get.Header.Del("Content-Type")
+	get.Header.Set("Cache-Control", "no-cache")
+
+	latest, err := client.Do(get)
+	if err != nil {
+		return nil, err
+	}
+	ioutil.ReadAll(latest.Body)
+	latest.Body.Close()
+
+	etag := latest.Header.Get("ETag")
+	if latest.StatusCode >= 400 || etag == "" {
+		return nil, fmt.Errorf("resource was modified but the latest version could not be loaded to retry (status %d, etag %q)", latest.StatusCode, etag)
+	}
+
+	body, err := req.GetBody()
+	if err != nil {
+		return nil, err
+	}
+
+	retry := req.Clone(req.Context())
+	retry.Body = body
+	retry.Header.Set("If-Match", etag)
+
+	return client.Do(retry)
+}
+
 // Response describes a parsed HTTP response which can be marshalled to enable
 // printing and filtering/projection.
 type Response struct {
@@ -243,6 +433,27 @@ type Response struct {
 	Headers map[string]string `json:"headers"`
 	Links   Links             `json:"links"`
 	Body    interface{}       `json:"body"`
+
+	// HeaderValues contains every value for each header in the order they were
+	// received, which preserves repeated headers like `Set-Cookie`.
+	HeaderValues map[string][]string `json:"-"`
+
+	// URL is the address the response was fetched from.
+	URL string `json:"-"`
+}
+
+// HeaderList returns all values for the given canonical header name. Repeated
+// headers return one entry per occurrence.
+func (r Response) HeaderList(name string) []string {
+	if values := r.HeaderValues[name]; len(values) > 1 {
+		return values
+	}
+
+	if value, ok := r.Headers[name]; ok {
+		return []string{value}
+	}
+
+	return nil
 }
 
 // Map returns a map representing this response matching the encoded JSON.
@@ -255,17 +466,34 @@ func (r Response) Map() map[string]interface{} {
 		}
 
 		for _, l := range list {
-			links[rel] = append(links[rel], map[string]interface{}{
+			link := map[string]interface{}{
 				"rel": l.Rel,
 				"uri": l.URI,
-			})
+			}
+
+			if l.Templated {
+				link["templated"] = true
+			}
+
+			links[rel] = append(links[rel], link)
+		}
+	}
+
+	// Repeated headers are output as an array of values so that none are lost,
+	// while single headers remain simple strings.
+	headers := map[string]interface{}{}
+	for name := range r.Headers {
+		if values := r.HeaderList(name); len(values) > 1 {
+			headers[name] = values
+		} else {
+			headers[name] = r.Headers[name]
 		}
 	}
 
 	return map[string]interface{}{
 		"proto":   r.Proto,
 		"status":  r.Status,
-		"headers": r.Headers,
+		"headers": headers,
 		"links":   links,
 		"body":    r.Body,
 	}
@@ -274,39 +502,73 @@ func (r Response) Map() map[string]interface{} {
 // ParseResponse takes an HTTP response and tries to parse it using the
 // registered content types. It returns a map representing the request,
 func ParseResponse(resp *http.Response) (Response, error) {
-	var parsed interface{}
-
 	// Handle content encodings
 	defer resp.Body.Close()
 	if err := DecodeResponse(resp); err != nil {
 		return Response{}, err
 	}
 
-	data, _ := ioutil.ReadAll(resp.Body)
+	data, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return Response{}, fmt.Errorf("cannot read response body: %w", err)
+	}
+
+	return parseResponseData(resp, data)
+}
+
+// parseResponseData builds a parsed response from the already read and
+// decoded body data.
+func parseResponseData(resp *http.Response, data []byte) (Response, error) {
+	var parsed interface{}
 
 	if len(data) > 0 {
 		ct := resp.Header.Get("content-type")
-		if err := Unmarshal(ct, data, &parsed); err != nil {
+		var err error
+		if (Protobuf{}).Detect(ct) {
+			// Protobuf needs the message type from the API config, and unlike
+			// other formats can't be shown as-is, so explain why it failed.
+			if resp.Request != nil {
+				ct, err = protobufContentType(resp.Request.URL.String(), ct, false)
+			}
+			if err == nil {
+				err = Unmarshal(ct, data, &parsed)
+			}
+			if err != nil {
+				LogWarning("Unable to decode protobuf response: %v", err)
+			}
+		} else {
+			err = Unmarshal(ct, data, &parsed)
+		}
+		if err != nil {
 			parsed = data
+		} else if viper.GetBool("rsh-warn-dup-keys") && (JSON{}).Detect(ct) {
+			for _, path := range DuplicateKeys(data) {
+				LogWarning("Duplicate key in JSON response: %s", path)
+			}
 		}
 	}
 
 	// Wrap the body to describe the entire response
 	headers := map[string]string{}
+	values := map[string][]string{}
 	output := Response{
-		Proto:   resp.Proto,
-		Status:  resp.StatusCode,
-		Headers: headers,
-		Links:   Links{},
-		Body:    parsed,
+		Proto:        resp.Proto,
+		Status:       resp.StatusCode,
+		Headers:      headers,
+		HeaderValues: values,
+		Links:        Links{},
+		Body:         parsed,
+		URL:          resp.Request.URL.String(),
 	}
 
 	for k, v := range resp.Header {
+		k = http.CanonicalHeaderKey(k)
 		joiner := ", "
 		if k == "Set-Cookie" {
 			joiner = "\n"
 		}
-		headers[k] = strings.Join(v, joiner)
+		values[k] = append(values[k], v...)
+		headers[k] = strings.Join(values[k], joiner)
 	}
 
 	if err := ParseLinks(resp.Request.URL, &output); err != nil {
@@ -320,12 +582,76 @@ func ParseResponse(resp *http.Response) (Response, error) {
 // GetParsedResponse makes a request and gets the parsed response back. It
 // handles any auto-pagination or linking that needs to be done and may
 // return a psuedo-responsse that is a combination of all responses.
-func GetParsedResponse(req *http.Request) (Response, error) {
-	resp, err := MakeRequest(req)
+//
+// If `rsh-timeout` is set, then the entire operation including any pagination
+// must complete within that duration.
+func GetParsedResponse(req *http.Request, options ...requestOption) (Response, error) {
+	req, timeout, cancel, err := withTimeout(req)
 	if err != nil {
 		return Response{}, err
 	}
+	defer cancel()
+
+	parsed, err := getParsedResponse(req, options...)
+	return parsed, timeoutError(req, timeout, err)
+}
+
+// withTimeout returns a copy of the request which is cancelled after the
+// `rsh-timeout` if one is set. The returned cancel function must be called
+// once the request and response are no longer needed.
+func withTimeout(req *http.Request) (*http.Request, time.Duration, context.CancelFunc, error) {
+	timeout, err := requestTimeout()
+	if err != nil {
+		return nil, 0, nil, err
+	}
+
+	if timeout <= 0 {
+		return req, 0, func() {}, nil
+	}
+
+	ctx, cancel := context.WithTimeout(req.Context(), timeout)
+	return req.WithContext(ctx), timeout, cancel, nil
+}
+
+// timeoutError replaces the error with a friendlier message if it was caused
+// by the request timeout.
+func timeoutError(req *http.Request, timeout time.Duration, err error) error {
+	if err != nil && req.Context().Err() == context.DeadlineExceeded {
+		return fmt.Errorf("request timed out after %s", timeout)
+	}
 
+	return err
+}
+
+// requestTimeout returns the configured request timeout, or zero if there
+// is no timeout.
+func requestTimeout() (time.Duration, error) {
+	value := viper.GetString("rsh-timeout")
+	if value == "" || value == "0" {
+		return 0, nil
+	}
+
+	timeout, err := time.ParseDuration(value)
+	if err != nil {
+		return 0, fmt.Errorf("invalid timeout %s: %v", value, err)
+	}
+
+	return timeout, nil
+}
+
+func getParsedResponse(req *http.Request, options ...requestOption) (Response, error) {
+	resp, err := MakeRequest(req, options...)
+	if err != nil {
+		return Response{}, err
+	}
+
+	return paginate(req, resp, options...)
+}
+
+// paginate parses the response and follows any `next` links, or the API's
+// configured pagination strategy, merging the results into a single response.
+// The request options are used for each page.
+func paginate(req *http.Request, resp *http.Response, options ...requestOption) (Response, error) {
 	parsed, err := ParseResponse(resp)
 	if err != nil {
 		LogError("Parse response error")
@@ -337,27 +663,44 @@ func GetParsedResponse(req *http.Request) (Response, error) {
 		computedSize = s
 	}
 
-	base := req.URL
-	for {
-		links := parsed.Links
-		if len(links["next"]) == 0 || viper.GetBool("rsh-no-paginate") {
+	pagination := PaginationConfig{}
+	if _, config := findAPI(req.URL.String()); config != nil && config.Pagination != nil {
+		pagination = *config.Pagination
+	}
+
+	if maxPages := viper.GetInt("rsh-max-pages"); maxPages > 0 {
+		pagination.MaxPages = maxPages
+	}
+
+	for page := 1; !viper.GetBool("rsh-no-paginate"); page++ {
+		next, err := pagination.nextPage(req.URL, parsed)
+		if err != nil {
+			return Response{}, err
+		}
+
+		if next == nil {
 			break
 		}
 
-		LogDebug("Found pagination via rel=next link: %s", links["next"][0].URI)
+		if pagination.MaxPages > 0 && page >= pagination.MaxPages {
+			LogWarning("Stopping auto-pagination after %d pages, more pages remain", page)
+			break
+		}
 
-		if _, ok := parsed.Body.([]interface{}); !ok {
+		items, ok := pagination.items(parsed.Body)
+		if !ok {
 			// TODO: support non-list formats like JSON:API
 			LogWarning("Skipping auto-pagination: response body not a list, not sure how to merge")
 			break
 		}
 
+		// Avoid a 429 by waiting for the quota to refresh if it is used up.
+		waitForRateLimit(resp)
+
 		// Make the next request
-		next, _ := url.Parse(links["next"][0].URI)
-		next = base.ResolveReference(next)
-		req, _ = http.NewRequest(http.MethodGet, next.String(), nil)
+		req, _ = http.NewRequestWithContext(req.Context(), http.MethodGet, next.String(), nil)
 
-		resp, err = MakeRequest(req)
+		resp, err = MakeRequest(req, options...)
 		if err != nil {
 			return Response{}, err
 		}
@@ -368,14 +711,15 @@ func GetParsedResponse(req *http.Request) (Response, error) {
 			return Response{}, err
 		}
 
-		if l, ok := parsedNext.Body.([]interface{}); ok {
+		if l, ok := pagination.items(parsedNext.Body); ok {
 			// The last request in the chain will be the one that gets displayed
 			// for the proto/status/headers, plus the merged body.
 			parsed.Proto = parsedNext.Proto
 			parsed.Status = parsedNext.Status
 			parsed.Headers = parsedNext.Headers
+			parsed.HeaderValues = parsedNext.HeaderValues
 			parsed.Links = parsedNext.Links
-			parsed.Body = append(parsed.Body.([]interface{}), l...)
+			parsed.Body = pagination.setItems(parsedNext.Body, append(items, l...))
 
 			// Update the total computed size to include the size of each individual
 			// request if the content size is available.
@@ -397,16 +741,59 @@ func GetParsedResponse(req *http.Request) (Response, error) {
 
 // MakeRequestAndFormat is a convenience function for calling `GetParsedResponse`
 // and then calling the default formatter's `Format` function with the parsed
-// response. Panics on error.
+// response. If `rsh-repeat` is set then the request is sent multiple times and
+// only the last response is formatted. NDJSON responses, and JSON arrays when
+// `rsh-stream` is set, are output as they arrive. Panics on error.
 func MakeRequestAndFormat(req *http.Request) {
-	parsed, err := GetParsedResponse(req)
-	if err != nil {
+	if err := sendAndFormat(req); err != nil {
 		panic(err)
 	}
+}
+
+// sendAndFormat is like `MakeRequestAndFormat` but returns errors.
+func sendAndFormat(req *http.Request) error {
+	if count := viper.GetInt("rsh-repeat"); count > 1 || viper.GetString("rsh-metrics-out") != "" {
+		return makeRepeatedRequest(req, count)
+	}
+
+	return makeRequestAndFormat(req)
+}
+
+func makeRequestAndFormat(req *http.Request) error {
+	req, timeout, cancel, err := withTimeout(req)
+	if err != nil {
+		return err
+	}
+	defer cancel()
+
+	body := historyBody(req)
+	start := time.Now()
+
+	resp, err := MakeRequest(req)
+	if errors.Is(err, ErrDryRun) {
+		return nil
+	}
+	if err != nil {
+		recordHistory(req, body, 0, time.Since(start))
+		return timeoutError(req, timeout, err)
+	}
+	recordHistory(req, body, resp.StatusCode, time.Since(start))
+
+	if streamed, err := streamResponse(resp); streamed {
+		checkStatus(resp.StatusCode)
+		return timeoutError(req, timeout, err)
+	}
+
+	parsed, err := paginate(req, resp)
+	if err != nil {
+		return timeoutError(req, timeout, err)
+	}
 
 	if err := Formatter.Format(parsed); err != nil {
-		panic(err)
+		return err
 	}
+
+	return checkFailIf(parsed)
 }
 
 // BestEffortSystemCertPool returns system cert pool as best effort, otherwise an empty cert pool
@@ -1,10 +1,20 @@
 package cli
 
 import (
+	"encoding/json"
 	"errors"
+	"io/ioutil"
+	"net"
 	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
 	"testing"
+	"time"
 
+	"github.com/spf13/viper"
 	"github.com/stretchr/testify/assert"
 	"gopkg.in/h2non/gock.v1"
 )
@@ -57,6 +67,45 @@ func TestRequestPagination(t *testing.T) {
 	assert.Equal(t, []interface{}{1.0, 2.0, 3.0, 4.0, 5.0, 6.0}, resp.Body)
 }
 
+func TestRequestPaginationOData(t *testing.T) {
+	defer gock.Off()
+	reset(false)
+
+	gock.New("http://example.com").
+		Get("/People").
+		Reply(http.StatusOK).
+		JSON(map[string]interface{}{
+			"@odata.context":  "http://example.com/$metadata#People",
+			"@odata.nextLink": "/People?$skiptoken=2",
+			"value":           []interface{}{1, 2},
+		})
+	rawQuery := ""
+	gock.New("http://example.com").
+		Get("/People").
+		MatchParam("$skiptoken", "2").
+		AddMatcher(func(req *http.Request, _ *gock.Request) (bool, error) {
+			rawQuery = req.URL.RawQuery
+			return true, nil
+		}).
+		Reply(http.StatusOK).
+		JSON(map[string]interface{}{
+			"@odata.context": "http://example.com/$metadata#People",
+			"value":          []interface{}{3},
+		})
+
+	req, _ := http.NewRequest(http.MethodGet, "http://example.com/People", nil)
+	resp, err := GetParsedResponse(req)
+
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{
+		"@odata.context": "http://example.com/$metadata#People",
+		"value":          []interface{}{1.0, 2.0, 3.0},
+	}, resp.Body)
+
+	// The next link is followed as-is rather than re-encoded.
+	assert.Equal(t, "$skiptoken=2", rawQuery)
+}
+
 type authHookFailure struct{}
 
 func (a *authHookFailure) Parameters() []AuthParam {
@@ -81,7 +130,338 @@ func TestAuthHookFailure(t *testing.T) {
 	authHandlers["hook-fail"] = &authHookFailure{}
 
 	r, _ := http.NewRequest(http.MethodGet, "/test", nil)
-	assert.PanicsWithError(t, "some-error", func() {
-		MakeRequest(r)
-	})
+	_, err := MakeRequest(r)
+	assert.EqualError(t, err, "auth hook-fail failed: some-error")
+}
+
+func TestParseResponseRepeatedHeaders(t *testing.T) {
+	resp := &http.Response{
+		Proto:      "HTTP/1.1",
+		StatusCode: http.StatusOK,
+		Header: http.Header{
+			"Content-Type": []string{"text/plain"},
+			"Set-Cookie":   []string{"a=1", "b=2"},
+		},
+		Body:    ioutil.NopCloser(strings.NewReader("hello")),
+		Request: &http.Request{URL: &url.URL{Scheme: "http", Host: "example.com"}},
+	}
+
+	parsed, err := ParseResponse(resp)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"a=1", "b=2"}, parsed.HeaderList("Set-Cookie"))
+	assert.Equal(t, []string{"text/plain"}, parsed.HeaderList("Content-Type"))
+
+	headers := parsed.Map()["headers"].(map[string]interface{})
+	assert.Equal(t, []string{"a=1", "b=2"}, headers["Set-Cookie"])
+	assert.Equal(t, "text/plain", headers["Content-Type"])
+}
+
+type authHookHeader struct {
+	name string
+}
+
+func (a *authHookHeader) Parameters() []AuthParam {
+	return []AuthParam{}
+}
+
+func (a *authHookHeader) OnRequest(req *http.Request, key string, params map[string]string) error {
+	req.Header.Add("X-Auth-Chain", a.name+"="+params["value"])
+	return nil
+}
+
+func TestAuthChain(t *testing.T) {
+	reset(false)
+
+	configs["auth-chain"] = &APIConfig{
+		Base: "https://auth-chain.example.com",
+		Profiles: map[string]*APIProfile{
+			"default": {
+				Auth: &APIAuth{
+					Name:   "chain-first",
+					Params: map[string]string{"value": "1"},
+				},
+				AuthChain: []*APIAuth{
+					{Name: "chain-second", Params: map[string]string{"value": "2"}},
+				},
+			},
+		},
+	}
+	defer delete(configs, "auth-chain")
+
+	authHandlers["chain-first"] = &authHookHeader{name: "first"}
+	authHandlers["chain-second"] = &authHookHeader{name: "second"}
+
+	defer gock.Off()
+	gock.New("https://auth-chain.example.com").Get("/test").Reply(204)
+
+	r, _ := http.NewRequest(http.MethodGet, "https://auth-chain.example.com/test", nil)
+	_, err := MakeRequest(r)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"first=1", "second=2"}, r.Header["X-Auth-Chain"])
+}
+
+func TestAuthChainFailure(t *testing.T) {
+	reset(false)
+
+	configs["auth-chain-fail"] = &APIConfig{
+		Base: "https://auth-chain-fail.example.com",
+		Profiles: map[string]*APIProfile{
+			"default": {
+				AuthChain: []*APIAuth{
+					{Name: "hook-fail"},
+					{Name: "chain-never"},
+				},
+			},
+		},
+	}
+	defer delete(configs, "auth-chain-fail")
+
+	authHandlers["hook-fail"] = &authHookFailure{}
+	authHandlers["chain-never"] = &authHookHeader{name: "never"}
+
+	r, _ := http.NewRequest(http.MethodGet, "https://auth-chain-fail.example.com/test", nil)
+	_, err := MakeRequest(r)
+	assert.EqualError(t, err, "auth hook-fail failed: some-error")
+	assert.Empty(t, r.Header.Get("X-Auth-Chain"))
+}
+
+func TestProfileEnvExpansion(t *testing.T) {
+	reset(false)
+
+	configs["profile-env"] = &APIConfig{
+		Base: "https://profile-env.example.com",
+		Profiles: map[string]*APIProfile{
+			"default": {
+				Headers: map[string]string{"X-Tenant": "${RSH_TEST_TENANT}", "X-Price": "$5"},
+				Query:   map[string]string{"region": "${RSH_TEST_REGION}"},
+				Auth: &APIAuth{
+					Name:   "chain-first",
+					Params: map[string]string{"value": "${RSH_TEST_USER}"},
+				},
+			},
+		},
+	}
+	defer delete(configs, "profile-env")
+
+	authHandlers["chain-first"] = &authHookHeader{name: "first"}
+
+	os.Setenv("RSH_TEST_TENANT", "acme")
+	os.Setenv("RSH_TEST_REGION", "eu")
+	defer os.Unsetenv("RSH_TEST_TENANT")
+	defer os.Unsetenv("RSH_TEST_REGION")
+
+	r, _ := http.NewRequest(http.MethodGet, "https://profile-env.example.com/test", nil)
+	_, err := MakeRequest(r)
+	assert.EqualError(t, err, "profile default: chain-first auth param value: environment variable RSH_TEST_USER is not set")
+
+	os.Setenv("RSH_TEST_USER", "alice")
+	defer os.Unsetenv("RSH_TEST_USER")
+
+	defer gock.Off()
+	gock.New("https://profile-env.example.com").Get("/test").MatchParam("region", "eu").Reply(204)
+
+	r, _ = http.NewRequest(http.MethodGet, "https://profile-env.example.com/test", nil)
+	_, err = MakeRequest(r)
+	assert.NoError(t, err)
+	assert.Equal(t, "acme", r.Header.Get("X-Tenant"))
+	assert.Equal(t, "$5", r.Header.Get("X-Price"))
+	assert.Equal(t, []string{"first=alice"}, r.Header["X-Auth-Chain"])
+
+	// The stored config is unchanged.
+	assert.Equal(t, "${RSH_TEST_USER}", configs["profile-env"].Profiles["default"].Auth.Params["value"])
+}
+
+func TestRequestTimeout(t *testing.T) {
+	reset(false)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-r.Context().Done():
+		case <-time.After(time.Second):
+		}
+	}))
+	defer server.Close()
+
+	viper.Set("rsh-timeout", "10ms")
+	defer viper.Set("rsh-timeout", "")
+
+	req, _ := http.NewRequest(http.MethodGet, server.URL+"/slow", nil)
+	_, err := GetParsedResponse(req)
+	assert.EqualError(t, err, "request timed out after 10ms")
+}
+
+func TestTLSTimeout(t *testing.T) {
+	reset(false)
+
+	// Accept connections but never complete the TLS handshake.
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	defer listener.Close()
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			defer conn.Close()
+		}
+	}()
+
+	viper.Set("rsh-tls-timeout", "50ms")
+	defer viper.Set("rsh-tls-timeout", "")
+
+	req, _ := http.NewRequest(http.MethodGet, "https://"+listener.Addr().String()+"/", nil)
+	_, err = GetParsedResponse(req)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "TLS handshake timeout")
+
+	viper.Set("rsh-connect-timeout", "soon")
+	defer viper.Set("rsh-connect-timeout", "")
+	_, err = GetParsedResponse(req)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "invalid connect timeout soon")
+}
+
+func TestTraceOut(t *testing.T) {
+	reset(false)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	f, err := ioutil.TempFile("", "restish-trace")
+	assert.NoError(t, err)
+	f.Close()
+	defer os.Remove(f.Name())
+
+	traces = nil
+	viper.Set("rsh-trace-out", f.Name())
+	defer viper.Set("rsh-trace-out", "")
+
+	req, _ := http.NewRequest(http.MethodGet, server.URL+"/traced", nil)
+	_, err = GetParsedResponse(req)
+	assert.NoError(t, err)
+
+	data, err := ioutil.ReadFile(f.Name())
+	assert.NoError(t, err)
+
+	var written []map[string]interface{}
+	assert.NoError(t, json.Unmarshal(data, &written))
+	assert.Len(t, written, 1)
+	assert.Equal(t, server.URL+"/traced", written[0]["url"])
+	assert.Equal(t, 204.0, written[0]["status"])
+
+	events := []string{}
+	for _, e := range written[0]["events"].([]interface{}) {
+		events = append(events, e.(map[string]interface{})["event"].(string))
+	}
+	assert.Contains(t, events, "wrote_request")
+	assert.Contains(t, events, "first_response_byte")
+}
+
+// chunkSignal closes `logged` once the first chunk has been logged.
+type chunkSignal struct {
+	strings.Builder
+	logged chan struct{}
+	once   sync.Once
+}
+
+func (w *chunkSignal) Write(p []byte) (int, error) {
+	if strings.Contains(string(p), "Received chunk 1:") {
+		w.once.Do(func() { close(w.logged) })
+	}
+	return w.Builder.Write(p)
+}
+
+func TestVerboseChunks(t *testing.T) {
+	reset(false)
+
+	captured := &chunkSignal{logged: make(chan struct{})}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain")
+		w.Write([]byte("hello"))
+		w.(http.Flusher).Flush()
+
+		// The rest of the body is only sent once the first chunk has been
+		// logged, which would never happen if the body were read up front.
+		select {
+		case <-captured.logged:
+		case <-time.After(5 * time.Second):
+			return
+		}
+		w.Write([]byte(" world"))
+	}))
+	defer server.Close()
+
+	Stderr = captured
+	enableVerbose = true
+	defer func() { enableVerbose = false }()
+
+	req, _ := http.NewRequest(http.MethodGet, server.URL, nil)
+	resp, err := GetParsedResponse(req)
+	assert.NoError(t, err)
+	assert.Equal(t, "hello world", resp.Body)
+
+	assert.Contains(t, captured.String(), "Received chunk 1: 5 bytes")
+	assert.Contains(t, captured.String(), "Received chunk 2: 6 bytes")
+	assert.Contains(t, captured.String(), "Received chunked body in 2 chunks")
+}
+
+func TestVerboseRedactsSecrets(t *testing.T) {
+	reset(false)
+	defer gock.Off()
+
+	gock.New("http://example.com").
+		Get("/secret").
+		Times(2).
+		Reply(200).
+		SetHeader("Set-Cookie", "session=abc123").
+		JSON(map[string]interface{}{"ok": true})
+
+	captured := &strings.Builder{}
+	Stderr = captured
+	enableVerbose = true
+	defer func() { enableVerbose = false }()
+
+	req, _ := http.NewRequest(http.MethodGet, "http://example.com/secret?api_key=abc123&q=1", nil)
+	req.Header.Set("Authorization", "Bearer abc123")
+	req.Header.Set("X-Api-Key", "abc123")
+	_, err := GetParsedResponse(req)
+	assert.NoError(t, err)
+
+	assert.Contains(t, captured.String(), "GET /secret?api_key=REDACTED&q=1 HTTP/1.1")
+	assert.Contains(t, captured.String(), "Authorization: Bearer REDACTED")
+	assert.Contains(t, captured.String(), "X-Api-Key: REDACTED")
+	assert.Contains(t, captured.String(), "Set-Cookie: REDACTED")
+	assert.NotContains(t, captured.String(), "abc123")
+
+	// The request itself is unchanged.
+	assert.Equal(t, "Bearer abc123", req.Header.Get("Authorization"))
+	assert.Equal(t, "api_key=abc123&q=1", req.URL.RawQuery)
+
+	viper.Set("rsh-show-secrets", true)
+	defer viper.Set("rsh-show-secrets", false)
+
+	captured.Reset()
+	req, _ = http.NewRequest(http.MethodGet, "http://example.com/secret", nil)
+	req.Header.Set("Authorization", "Bearer abc123")
+	_, err = GetParsedResponse(req)
+	assert.NoError(t, err)
+	assert.Contains(t, captured.String(), "Authorization: Bearer abc123")
+}
+
+func TestRequestAccept(t *testing.T) {
+	defer gock.Off()
+
+	gock.New("http://example.com").
+		Get("/items").
+		MatchHeader("Accept", "^application/yaml$").
+		Reply(http.StatusOK).
+		SetHeader("Content-Type", "application/yaml").
+		BodyString("name: foo\n")
+
+	captured := run("http://example.com/items --rsh-accept yaml -f body.name -r")
+	assert.Equal(t, "foo\n", captured)
 }
@@ -0,0 +1,197 @@
+package cli
+
+import (
+	"crypto/md5"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// responseCache is an on-disk HTTP response cache. Each entry is stored in a
+// file named after the MD5 of its key, matching the layout previously used
+// via `diskcache`, along with a `.key` file holding the key itself so that
+// entries can be listed and cleared by URL.
+type responseCache struct {
+	dir string
+}
+
+// responseCacheEntry describes a single cached response.
+type responseCacheEntry struct {
+	Key      string
+	Filename string
+	Size     int64
+	Modified time.Time
+}
+
+// URL returns the entry's URL, without any method prefix from the key.
+func (e responseCacheEntry) URL() string {
+	if i := strings.Index(e.Key, " "); i != -1 {
+		return e.Key[i+1:]
+	}
+	return e.Key
+}
+
+func newResponseCache() *responseCache {
+	return &responseCache{dir: filepath.Join(cacheDir(), "responses")}
+}
+
+func (c *responseCache) filename(key string) string {
+	h := md5.New()
+	io.WriteString(h, key)
+	return filepath.Join(c.dir, hex.EncodeToString(h.Sum(nil)))
+}
+
+// Get returns the cached response for the key, if present.
+func (c *responseCache) Get(key string) ([]byte, bool) {
+	data, err := ioutil.ReadFile(c.filename(key))
+	if err != nil {
+		return nil, false
+	}
+	return data, true
+}
+
+// Set stores a response for the key. Writes go to a temporary file which is
+// then renamed so readers never see a partial entry.
+func (c *responseCache) Set(key string, data []byte) {
+	if err := os.MkdirAll(c.dir, 0700); err != nil {
+		LogWarning("Unable to create cache directory: %v", err)
+		return
+	}
+
+	filename := c.filename(key)
+	for name, contents := range map[string][]byte{filename: data, filename + ".key": []byte(key)} {
+		tmp := name + ".tmp"
+		if err := ioutil.WriteFile(tmp, contents, 0600); err != nil {
+			LogWarning("Unable to write cache: %v", err)
+			return
+		}
+		if err := os.Rename(tmp, name); err != nil {
+			os.Remove(tmp)
+			LogWarning("Unable to write cache: %v", err)
+			return
+		}
+	}
+}
+
+// Delete removes the cached response for the key.
+func (c *responseCache) Delete(key string) {
+	filename := c.filename(key)
+	os.Remove(filename)
+	os.Remove(filename + ".key")
+}
+
+// Entries returns all cached responses, sorted by key. Entries written before
+// keys were recorded have an empty key.
+func (c *responseCache) Entries() ([]responseCacheEntry, error) {
+	files, err := ioutil.ReadDir(c.dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	entries := []responseCacheEntry{}
+	for _, f := range files {
+		if f.IsDir() || strings.Contains(f.Name(), ".") {
+			continue
+		}
+
+		entry := responseCacheEntry{
+			Filename: filepath.Join(c.dir, f.Name()),
+			Size:     f.Size(),
+			Modified: f.ModTime(),
+		}
+
+		if key, err := ioutil.ReadFile(entry.Filename + ".key"); err == nil {
+			entry.Key = string(key)
+		}
+
+		entries = append(entries, entry)
+	}
+
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].Key < entries[j].Key
+	})
+
+	return entries, nil
+}
+
+// Clear removes cached responses whose URL starts with the prefix, or all
+// of them if the prefix is empty. Returns the number of removed entries.
+func (c *responseCache) Clear(prefix string) (int, error) {
+	entries, err := c.Entries()
+	if err != nil {
+		return 0, err
+	}
+
+	count := 0
+	for _, e := range entries {
+		if prefix != "" && (e.Key == "" || !strings.HasPrefix(e.URL(), prefix)) {
+			continue
+		}
+
+		if err := os.Remove(e.Filename); err != nil && !os.IsNotExist(err) {
+			return count, err
+		}
+		os.Remove(e.Filename + ".key")
+		count++
+	}
+
+	return count, nil
+}
+
+// cachePrefix resolves an API short name to its base URL, otherwise returns
+// the URL prefix as-is.
+func cachePrefix(arg string) string {
+	if config, ok := configs[arg]; ok {
+		return config.Base
+	}
+	return arg
+}
+
+// formatSize returns a human readable size like `1.5 KiB`.
+func formatSize(size int64) string {
+	const unit = 1024
+	if size < unit {
+		return fmt.Sprintf("%d B", size)
+	}
+
+	div, exp := int64(unit), 0
+	for n := size / unit; n >= unit; n /= unit {
+		div *= unit
+		exp++
+	}
+
+	return fmt.Sprintf("%.1f %ciB", float64(size)/float64(div), "KMGTPE"[exp])
+}
+
+// listCache prints the cached responses with their sizes and ages.
+func listCache(prefix string) error {
+	entries, err := newResponseCache().Entries()
+	if err != nil {
+		return err
+	}
+
+	for _, e := range entries {
+		if prefix != "" && (e.Key == "" || !strings.HasPrefix(e.URL(), prefix)) {
+			continue
+		}
+
+		key := e.Key
+		if key == "" {
+			key = "(unknown)"
+		}
+
+		age := time.Since(e.Modified).Round(time.Second)
+		fmt.Fprintf(Stdout, "%10s  %10s  %s\n", formatSize(e.Size), age, key)
+	}
+
+	return nil
+}
@@ -0,0 +1,78 @@
+package cli
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestResponseCache(t *testing.T) {
+	dir, err := ioutil.TempDir("", "restish-cache")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	c := &responseCache{dir: filepath.Join(dir, "responses")}
+
+	_, ok := c.Get("https://a.example.com/items")
+	assert.False(t, ok)
+
+	c.Set("https://a.example.com/items", []byte("a1"))
+	c.Set("https://a.example.com/other", []byte("a2"))
+	c.Set("POST https://b.example.com/items", []byte("b1"))
+
+	data, ok := c.Get("https://a.example.com/items")
+	assert.True(t, ok)
+	assert.Equal(t, "a1", string(data))
+
+	// Entries from before keys were recorded have no key.
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(c.dir, "0123456789abcdef0123456789abcdef"), []byte("legacy"), 0600))
+
+	entries, err := c.Entries()
+	assert.NoError(t, err)
+	keys := []string{}
+	for _, e := range entries {
+		keys = append(keys, e.Key)
+	}
+	assert.Equal(t, []string{"", "POST https://b.example.com/items", "https://a.example.com/items", "https://a.example.com/other"}, keys)
+	assert.Equal(t, "https://b.example.com/items", entries[1].URL())
+
+	count, err := c.Clear("https://b.example.com")
+	assert.NoError(t, err)
+	assert.Equal(t, 1, count)
+
+	c.Delete("https://a.example.com/other")
+	_, ok = c.Get("https://a.example.com/other")
+	assert.False(t, ok)
+
+	count, err = c.Clear("")
+	assert.NoError(t, err)
+	assert.Equal(t, 2, count)
+
+	files, _ := ioutil.ReadDir(c.dir)
+	assert.Empty(t, files)
+}
+
+func TestFormatSize(t *testing.T) {
+	assert.Equal(t, "512 B", formatSize(512))
+	assert.Equal(t, "1.5 KiB", formatSize(1536))
+	assert.Equal(t, "2.0 MiB", formatSize(2*1024*1024))
+}
+
+func TestCacheCommands(t *testing.T) {
+	reset(false)
+
+	c := newResponseCache()
+	c.Set("https://cache-cmd.example.com/items", []byte("hello"))
+
+	out := run("cache list https://cache-cmd.example.com")
+	assert.Contains(t, out, "5 B")
+	assert.Contains(t, out, "https://cache-cmd.example.com/items")
+
+	out = run("cache clear https://cache-cmd.example.com")
+	assert.Contains(t, out, "Removed 1 cached responses")
+	assert.False(t, strings.Contains(run("cache list"), "cache-cmd.example.com"))
+}
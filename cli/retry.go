@@ -0,0 +1,96 @@
+package cli
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/spf13/viper"
+)
+
+// shouldRetry returns whether a response is a transient failure which may
+// succeed if tried again later.
+func shouldRetry(resp *http.Response) bool {
+	return resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500
+}
+
+// retryAfter parses the `Retry-After` header, which may be given in either
+// seconds or as an HTTP date. Returns zero if not present or invalid.
+func retryAfter(resp *http.Response) time.Duration {
+	value := strings.TrimSpace(resp.Header.Get("Retry-After"))
+	if value == "" {
+		return 0
+	}
+
+	if seconds, err := strconv.Atoi(value); err == nil && seconds >= 0 {
+		return time.Duration(seconds) * time.Second
+	}
+
+	if t, err := http.ParseTime(value); err == nil {
+		if d := time.Until(t); d > 0 {
+			return d
+		}
+	}
+
+	return 0
+}
+
+// retryDelay returns how long to wait before the given retry attempt, which
+// starts at one. The server's `Retry-After` is used if present, otherwise
+// an exponential backoff with jitter based on `rsh-retry-delay`.
+func retryDelay(resp *http.Response, attempt int) time.Duration {
+	if d := retryAfter(resp); d > 0 {
+		return d
+	}
+
+	base, err := time.ParseDuration(viper.GetString("rsh-retry-delay"))
+	if err != nil || base <= 0 {
+		base = time.Second
+	}
+
+	delay := base * time.Duration(1<<uint(attempt-1))
+
+	// Add up to 50% jitter so many clients don't retry in lockstep.
+	return delay + time.Duration(rand.Int63n(int64(delay)/2+1))
+}
+
+// bufferBody reads the request body into memory so that it can be re-sent
+// when retrying, unless it can already be recreated.
+func bufferBody(req *http.Request) error {
+	if req.Body == nil || req.GetBody != nil {
+		return nil
+	}
+
+	data, err := ioutil.ReadAll(req.Body)
+	if err != nil {
+		return err
+	}
+	req.Body.Close()
+
+	req.Body = ioutil.NopCloser(bytes.NewReader(data))
+	req.GetBody = func() (io.ReadCloser, error) {
+		return ioutil.NopCloser(bytes.NewReader(data)), nil
+	}
+
+	return nil
+}
+
+// resetBody recreates the request body so the request can be sent again.
+func resetBody(req *http.Request) error {
+	if req.GetBody == nil {
+		return nil
+	}
+
+	body, err := req.GetBody()
+	if err != nil {
+		return err
+	}
+	req.Body = body
+
+	return nil
+}
@@ -0,0 +1,52 @@
+package cli
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/h2non/gock.v1"
+)
+
+func TestRetryAfter(t *testing.T) {
+	resp := &http.Response{Header: http.Header{}}
+	assert.Equal(t, time.Duration(0), retryAfter(resp))
+
+	resp.Header.Set("Retry-After", "5")
+	assert.Equal(t, 5*time.Second, retryAfter(resp))
+
+	resp.Header.Set("Retry-After", time.Now().Add(time.Minute).UTC().Format(http.TimeFormat))
+	assert.InDelta(t, float64(time.Minute), float64(retryAfter(resp)), float64(2*time.Second))
+
+	resp.Header.Set("Retry-After", "invalid")
+	assert.Equal(t, time.Duration(0), retryAfter(resp))
+}
+
+func TestRetryDelay(t *testing.T) {
+	viper.Set("rsh-retry-delay", "100ms")
+	defer viper.Set("rsh-retry-delay", "1s")
+
+	resp := &http.Response{Header: http.Header{}}
+
+	// Exponential backoff with up to 50% jitter.
+	for attempt, base := range []time.Duration{100 * time.Millisecond, 200 * time.Millisecond, 400 * time.Millisecond} {
+		delay := retryDelay(resp, attempt+1)
+		assert.True(t, delay >= base && delay <= base+base/2, "%s not in range for %s", delay, base)
+	}
+}
+
+func TestRetryRequest(t *testing.T) {
+	defer gock.Off()
+
+	gock.New("http://example.com").Post("/retry").BodyString(`{"value":1}`).Reply(http.StatusServiceUnavailable)
+	gock.New("http://example.com").Post("/retry").BodyString(`{"value":1}`).Reply(http.StatusTooManyRequests).SetHeader("Retry-After", "0")
+	gock.New("http://example.com").Post("/retry").BodyString(`{"value":1}`).Reply(http.StatusOK).JSON(map[string]interface{}{
+		"ok": true,
+	})
+
+	expectJSON(t, "post http://example.com/retry --rsh-retry 2 --rsh-retry-delay 1ms value: 1", `{
+		"ok": true
+	}`)
+}
@@ -0,0 +1,286 @@
+package cli
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/AlecAivazis/survey/v2"
+	"github.com/AlecAivazis/survey/v2/terminal"
+	"github.com/spf13/cobra"
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/chacha20poly1305"
+)
+
+// encryptedPrefix marks a config value as encrypted. The rest of the value is
+// the base64 encoded salt, nonce and XChaCha20-Poly1305 ciphertext.
+const encryptedPrefix = "rsh-enc:v1:"
+
+// passphraseEnv is the environment variable to read the passphrase from
+// instead of prompting for it.
+const passphraseEnv = "RSH_PASSPHRASE"
+
+const secretSaltSize = 16
+
+// askPassphrase prompts for the passphrase used to encrypt secrets. It can be
+// overridden in tests.
+var askPassphrase = func(message string) string {
+	resp := ""
+	err := survey.AskOne(&survey.Password{Message: message}, &resp)
+	if err == terminal.InterruptErr {
+		os.Exit(0)
+	}
+	if err != nil {
+		panic(err)
+	}
+	return resp
+}
+
+// passphrase is remembered so the user is only prompted once per run.
+var passphrase string
+
+// secretKeys caches derived keys by salt, since derivation is deliberately
+// slow and all values encrypted together share a salt.
+var secretKeys = map[string][]byte{}
+
+// getPassphrase returns the passphrase from the environment or by prompting,
+// optionally asking twice to catch typos when encrypting.
+func getPassphrase(confirm bool) (string, error) {
+	if passphrase != "" {
+		return passphrase, nil
+	}
+
+	if p := os.Getenv(passphraseEnv); p != "" {
+		passphrase = p
+		return passphrase, nil
+	}
+
+	p := askPassphrase("Passphrase for encrypted secrets")
+	if p == "" {
+		return "", fmt.Errorf("a passphrase is required, enter one or set %s", passphraseEnv)
+	}
+
+	if confirm && askPassphrase("Confirm passphrase") != p {
+		return "", fmt.Errorf("passphrases do not match")
+	}
+
+	passphrase = p
+	return passphrase, nil
+}
+
+// secretKey derives an encryption key from the passphrase using Argon2id.
+func secretKey(pass string, salt []byte) []byte {
+	cacheKey := pass + string(salt)
+	if key, ok := secretKeys[cacheKey]; ok {
+		return key
+	}
+
+	key := argon2.IDKey([]byte(pass), salt, 1, 64*1024, 4, chacha20poly1305.KeySize)
+	secretKeys[cacheKey] = key
+	return key
+}
+
+// isEncrypted returns whether the config value is an encrypted secret.
+func isEncrypted(value string) bool {
+	return strings.HasPrefix(value, encryptedPrefix)
+}
+
+// encryptSecret encrypts a value with a key derived from the passphrase and
+// salt, returning it in the format understood by decryptSecret.
+func encryptSecret(pass string, salt []byte, value string) (string, error) {
+	aead, err := chacha20poly1305.NewX(secretKey(pass, salt))
+	if err != nil {
+		return "", err
+	}
+
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+
+	data := append(append([]byte{}, salt...), nonce...)
+	data = aead.Seal(data, nonce, []byte(value), nil)
+
+	return encryptedPrefix + base64.RawURLEncoding.EncodeToString(data), nil
+}
+
+// decryptSecret decrypts a value produced by encryptSecret.
+func decryptSecret(pass, value string) (string, error) {
+	data, err := base64.RawURLEncoding.DecodeString(strings.TrimPrefix(value, encryptedPrefix))
+	if err != nil || len(data) < secretSaltSize+chacha20poly1305.NonceSizeX {
+		return "", fmt.Errorf("invalid encrypted secret")
+	}
+
+	salt := data[:secretSaltSize]
+	nonce := data[secretSaltSize : secretSaltSize+chacha20poly1305.NonceSizeX]
+
+	aead, err := chacha20poly1305.NewX(secretKey(pass, salt))
+	if err != nil {
+		return "", err
+	}
+
+	plain, err := aead.Open(nil, nonce, data[secretSaltSize+chacha20poly1305.NonceSizeX:], nil)
+	if err != nil {
+		return "", fmt.Errorf("unable to decrypt secret, wrong passphrase?")
+	}
+
+	return string(plain), nil
+}
+
+// resolveSecret decrypts the value if it is encrypted, prompting for the
+// passphrase if needed. Other values are returned as-is.
+func resolveSecret(value string) (string, error) {
+	if !isEncrypted(value) {
+		return value, nil
+	}
+
+	pass, err := getPassphrase(false)
+	if err != nil {
+		return "", err
+	}
+
+	return decryptSecret(pass, value)
+}
+
+// transformSecrets calls the function for each secret value in the profile,
+// i.e. sensitive headers, query params and auth params, replacing the value
+// with the result. Returns the number of changed values.
+func transformSecrets(p *APIProfile, f func(string) (string, error)) (int, error) {
+	count := 0
+
+	apply := func(values map[string]string, isSecret func(string) bool) error {
+		for k, v := range values {
+			if v == "" || !isSecret(k) {
+				continue
+			}
+
+			updated, err := f(v)
+			if err != nil {
+				return fmt.Errorf("%s: %w", k, err)
+			}
+
+			if updated != v {
+				values[k] = updated
+				count++
+			}
+		}
+		return nil
+	}
+
+	if err := apply(p.Headers, sensitiveHeader.MatchString); err != nil {
+		return count, err
+	}
+
+	if err := apply(p.Query, sensitiveParam.MatchString); err != nil {
+		return count, err
+	}
+
+	for _, a := range p.Auths() {
+		if err := apply(a.Params, sensitiveParam.MatchString); err != nil {
+			return count, err
+		}
+	}
+
+	return count, nil
+}
+
+// encryptAPISecrets encrypts the plaintext secrets in all of the API's
+// profiles. Values which are already encrypted are left alone.
+func encryptAPISecrets(config *APIConfig, pass string) (int, error) {
+	salt := make([]byte, secretSaltSize)
+	if _, err := rand.Read(salt); err != nil {
+		return 0, err
+	}
+
+	total := 0
+	for name, p := range config.Profiles {
+		if p == nil {
+			continue
+		}
+
+		count, err := transformSecrets(p, func(v string) (string, error) {
+			if isEncrypted(v) {
+				return v, nil
+			}
+			return encryptSecret(pass, salt, v)
+		})
+		total += count
+		if err != nil {
+			return total, fmt.Errorf("profile %s: %w", name, err)
+		}
+	}
+
+	return total, nil
+}
+
+// decryptAPISecrets decrypts the encrypted secrets in all of the API's
+// profiles, storing them as plaintext again.
+func decryptAPISecrets(config *APIConfig, pass string) (int, error) {
+	total := 0
+	for name, p := range config.Profiles {
+		if p == nil {
+			continue
+		}
+
+		count, err := transformSecrets(p, func(v string) (string, error) {
+			if !isEncrypted(v) {
+				return v, nil
+			}
+			return decryptSecret(pass, v)
+		})
+		total += count
+		if err != nil {
+			return total, fmt.Errorf("profile %s: %w", name, err)
+		}
+	}
+
+	return total, nil
+}
+
+func apiEncrypt(cmd *cobra.Command, args []string) {
+	config := configs[args[0]]
+	if config == nil {
+		panic("API " + args[0] + " not found")
+	}
+
+	pass, err := getPassphrase(true)
+	if err != nil {
+		panic(err)
+	}
+
+	count, err := encryptAPISecrets(config, pass)
+	if err != nil {
+		panic(err)
+	}
+
+	if err := config.Save(); err != nil {
+		panic(err)
+	}
+
+	fmt.Fprintf(Stdout, "Encrypted %d secrets for %s\n", count, args[0])
+}
+
+func apiDecrypt(cmd *cobra.Command, args []string) {
+	config := configs[args[0]]
+	if config == nil {
+		panic("API " + args[0] + " not found")
+	}
+
+	pass, err := getPassphrase(false)
+	if err != nil {
+		panic(err)
+	}
+
+	count, err := decryptAPISecrets(config, pass)
+	if err != nil {
+		panic(err)
+	}
+
+	if err := config.Save(); err != nil {
+		panic(err)
+	}
+
+	fmt.Fprintf(Stdout, "Decrypted %d secrets for %s\n", count, args[0])
+}
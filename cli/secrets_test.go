@@ -0,0 +1,112 @@
+package cli
+
+import (
+	"net/http"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/h2non/gock.v1"
+)
+
+func TestEncryptSecret(t *testing.T) {
+	salt := make([]byte, secretSaltSize)
+
+	encrypted, err := encryptSecret("hunter2", salt, "my-token")
+	assert.NoError(t, err)
+	assert.True(t, isEncrypted(encrypted))
+	assert.NotContains(t, encrypted, "my-token")
+
+	decrypted, err := decryptSecret("hunter2", encrypted)
+	assert.NoError(t, err)
+	assert.Equal(t, "my-token", decrypted)
+
+	_, err = decryptSecret("wrong", encrypted)
+	assert.EqualError(t, err, "unable to decrypt secret, wrong passphrase?")
+
+	_, err = decryptSecret("hunter2", encryptedPrefix+"short")
+	assert.EqualError(t, err, "invalid encrypted secret")
+}
+
+func TestEncryptAPISecrets(t *testing.T) {
+	reset(false)
+
+	os.Setenv(passphraseEnv, "hunter2")
+	defer os.Unsetenv(passphraseEnv)
+	defer func() { passphrase = "" }()
+
+	config := &APIConfig{
+		Base: "https://secrets.example.com",
+		Profiles: map[string]*APIProfile{
+			"default": {
+				Headers: map[string]string{"X-API-Key": "abc123", "Accept-Language": "en"},
+				Auth: &APIAuth{
+					Name:   "http-basic",
+					Params: map[string]string{"username": "alice", "password": "s3cret"},
+				},
+			},
+		},
+	}
+	configs["secrets"] = config
+	defer delete(configs, "secrets")
+
+	count, err := encryptAPISecrets(config, "hunter2")
+	assert.NoError(t, err)
+	assert.Equal(t, 2, count)
+
+	profile := config.Profiles["default"]
+	assert.True(t, isEncrypted(profile.Headers["X-API-Key"]))
+	assert.True(t, isEncrypted(profile.Auth.Params["password"]))
+	assert.Equal(t, "en", profile.Headers["Accept-Language"])
+	assert.Equal(t, "alice", profile.Auth.Params["username"])
+
+	// Already encrypted values are left alone.
+	count, err = encryptAPISecrets(config, "hunter2")
+	assert.NoError(t, err)
+	assert.Equal(t, 0, count)
+
+	// Requests use the decrypted values. Gock compares header names as-is, so
+	// they must be in canonical form.
+	defer gock.Off()
+	gock.New("https://secrets.example.com").Get("/items").
+		MatchHeader("X-Api-Key", "^abc123$").
+		MatchHeader("Authorization", "^Basic YWxpY2U6czNjcmV0$").
+		Reply(204)
+
+	req, _ := http.NewRequest(http.MethodGet, "https://secrets.example.com/items", nil)
+	resp, err := MakeRequest(req)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusNoContent, resp.StatusCode)
+
+	count, err = decryptAPISecrets(config, "hunter2")
+	assert.NoError(t, err)
+	assert.Equal(t, 2, count)
+	assert.Equal(t, "abc123", profile.Headers["X-API-Key"])
+	assert.Equal(t, "s3cret", profile.Auth.Params["password"])
+}
+
+func TestPassphrasePrompt(t *testing.T) {
+	defer func() { passphrase = "" }()
+	orig := askPassphrase
+	defer func() { askPassphrase = orig }()
+
+	responses := []string{"one", "two"}
+	askPassphrase = func(message string) string {
+		r := responses[0]
+		responses = responses[1:]
+		return r
+	}
+
+	_, err := getPassphrase(true)
+	assert.EqualError(t, err, "passphrases do not match")
+
+	responses = []string{""}
+	_, err = getPassphrase(false)
+	assert.EqualError(t, err, "a passphrase is required, enter one or set RSH_PASSPHRASE")
+
+	responses = []string{"same", "same"}
+	p, err := getPassphrase(true)
+	assert.NoError(t, err)
+	assert.Equal(t, "same", p)
+}
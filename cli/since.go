@@ -0,0 +1,71 @@
+package cli
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// SinceConfig describes how the `--rsh-since` time is sent to an API.
+type SinceConfig struct {
+	// Param is the query param name, defaults to `since`.
+	Param string `json:"param,omitempty" mapstructure:",omitempty"`
+
+	// Format is `rfc3339` (default), `unix`, `unix_ms` or a Go time layout
+	// like `2006-01-02`.
+	Format string `json:"format,omitempty" mapstructure:",omitempty"`
+}
+
+// param returns the query param name to use.
+func (c SinceConfig) param() string {
+	if c.Param == "" {
+		return "since"
+	}
+	return c.Param
+}
+
+// value parses a relative duration like `24h` or `7d`, or an absolute
+// RFC3339 time or date, and formats it for the query param.
+func (c SinceConfig) value(since string, now time.Time) (string, error) {
+	t, err := parseSince(since, now)
+	if err != nil {
+		return "", err
+	}
+
+	t = t.UTC()
+	switch c.Format {
+	case "", "rfc3339":
+		return t.Format(time.RFC3339), nil
+	case "unix":
+		return strconv.FormatInt(t.Unix(), 10), nil
+	case "unix_ms":
+		return strconv.FormatInt(t.UnixNano()/int64(time.Millisecond), 10), nil
+	}
+
+	return t.Format(c.Format), nil
+}
+
+// parseSince converts a relative duration or absolute time into a time.
+func parseSince(since string, now time.Time) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, since); err == nil {
+		return t, nil
+	}
+
+	if t, err := time.Parse("2006-01-02", since); err == nil {
+		return t, nil
+	}
+
+	if strings.HasSuffix(since, "d") {
+		// Go durations don't support days, which are common for time windows.
+		if days, err := strconv.Atoi(strings.TrimSuffix(since, "d")); err == nil {
+			return now.AddDate(0, 0, -days), nil
+		}
+	}
+
+	if d, err := time.ParseDuration(since); err == nil {
+		return now.Add(-d), nil
+	}
+
+	return time.Time{}, fmt.Errorf("invalid since %s, expected a duration like 24h or 7d, or an RFC3339 time", since)
+}
@@ -0,0 +1,54 @@
+package cli
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/h2non/gock.v1"
+)
+
+func TestSinceValue(t *testing.T) {
+	now := time.Date(2020, 5, 28, 12, 0, 0, 0, time.UTC)
+
+	v, err := SinceConfig{}.value("24h", now)
+	assert.NoError(t, err)
+	assert.Equal(t, "2020-05-27T12:00:00Z", v)
+
+	v, err = SinceConfig{Format: "unix"}.value("7d", now)
+	assert.NoError(t, err)
+	assert.Equal(t, "1590062400", v)
+
+	v, err = SinceConfig{Format: "2006-01-02"}.value("2020-01-02T03:04:05+01:00", now)
+	assert.NoError(t, err)
+	assert.Equal(t, "2020-01-02", v)
+
+	_, err = SinceConfig{}.value("yesterday", now)
+	assert.Error(t, err)
+}
+
+func TestSinceParam(t *testing.T) {
+	defer gock.Off()
+	reset(false)
+
+	configs["events"] = &APIConfig{
+		Base:  "http://events.example.com",
+		Since: &SinceConfig{Param: "from", Format: "2006-01-02"},
+	}
+
+	gock.New("http://events.example.com").
+		Get("/events").
+		MatchParam("from", "2020-05-01").
+		Reply(http.StatusOK).
+		JSON([]interface{}{})
+
+	viper.Set("rsh-since", "2020-05-01")
+	defer viper.Set("rsh-since", "")
+
+	req, _ := http.NewRequest(http.MethodGet, "http://events.example.com/events", nil)
+	_, err := GetParsedResponse(req)
+	assert.NoError(t, err)
+	assert.True(t, gock.IsDone())
+}
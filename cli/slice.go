@@ -0,0 +1,79 @@
+package cli
+
+import (
+	"fmt"
+	"strings"
+)
+
+// sliceItems returns the first and/or last N items of an array, as a shortcut
+// for JMESPath slices like `[:5]` or `[-5:]`. A count of zero means no limit
+// and values which are not arrays are returned unchanged.
+func sliceItems(v interface{}, first, last int) interface{} {
+	items, ok := v.([]interface{})
+	if !ok {
+		return v
+	}
+
+	if first > 0 && first < len(items) {
+		items = items[:first]
+	}
+
+	if last > 0 && last < len(items) {
+		items = items[len(items)-last:]
+	}
+
+	return items
+}
+
+// itemsAt returns the value at a dot-separated path of object keys like
+// `data.items`, as set by `--rsh-items-path`. An empty path returns the value
+// itself.
+func itemsAt(v interface{}, path string) (interface{}, error) {
+	if path == "" {
+		return v, nil
+	}
+
+	for _, key := range strings.Split(path, ".") {
+		m, ok := v.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("cannot find items at %s: not an object", path)
+		}
+
+		if v, ok = m[key]; !ok {
+			return nil, fmt.Errorf("cannot find items at %s: no key %s", path, key)
+		}
+	}
+
+	return v, nil
+}
+
+// sliceItemsAt is like `sliceItems` but slices the array found at the items
+// path, leaving the rest of the value as is.
+func sliceItemsAt(v interface{}, path string, first, last int) (interface{}, error) {
+	if path == "" {
+		return sliceItems(v, first, last), nil
+	}
+
+	parent, key := "", path
+	if i := strings.LastIndex(path, "."); i >= 0 {
+		parent, key = path[:i], path[i+1:]
+	}
+
+	p, err := itemsAt(v, parent)
+	if err != nil {
+		return nil, err
+	}
+
+	m, ok := p.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("cannot find items at %s: not an object", path)
+	}
+
+	items, ok := m[key]
+	if !ok {
+		return nil, fmt.Errorf("cannot find items at %s: no key %s", path, key)
+	}
+
+	m[key] = sliceItems(items, first, last)
+	return v, nil
+}
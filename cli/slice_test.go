@@ -0,0 +1,44 @@
+package cli
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSliceItems(t *testing.T) {
+	items := []interface{}{1, 2, 3, 4, 5}
+
+	assert.Equal(t, []interface{}{1, 2}, sliceItems(items, 2, 0))
+	assert.Equal(t, []interface{}{4, 5}, sliceItems(items, 0, 2))
+	assert.Equal(t, []interface{}{2, 3}, sliceItems(items, 3, 2))
+	assert.Equal(t, items, sliceItems(items, 10, 0))
+	assert.Equal(t, items, sliceItems(items, 0, 0))
+	assert.Equal(t, "foo", sliceItems("foo", 1, 1))
+}
+
+func TestSliceItemsAt(t *testing.T) {
+	body := map[string]interface{}{
+		"data": map[string]interface{}{
+			"items": []interface{}{1, 2, 3},
+		},
+	}
+
+	items, err := itemsAt(body, "data.items")
+	assert.NoError(t, err)
+	assert.Equal(t, []interface{}{1, 2, 3}, items)
+
+	sliced, err := sliceItemsAt(body, "data.items", 0, 1)
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{
+		"data": map[string]interface{}{
+			"items": []interface{}{3},
+		},
+	}, sliced)
+
+	_, err = itemsAt(body, "data.missing")
+	assert.Error(t, err)
+
+	_, err = sliceItemsAt([]interface{}{1}, "items", 1, 0)
+	assert.Error(t, err)
+}
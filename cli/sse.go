@@ -0,0 +1,200 @@
+package cli
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/spf13/viper"
+)
+
+// sseEvent is a single server-sent event.
+type sseEvent struct {
+	ID    string
+	Event string
+	Data  string
+	Retry int
+}
+
+// Map returns the event as a generic map for filtering and JSON output. The
+// data is decoded if it is JSON, otherwise it is left as a string.
+func (e sseEvent) Map() map[string]interface{} {
+	m := map[string]interface{}{
+		"event": e.Event,
+	}
+
+	if e.ID != "" {
+		m["id"] = e.ID
+	}
+
+	if e.Retry > 0 {
+		m["retry"] = e.Retry
+	}
+
+	var data interface{}
+	if err := json.Unmarshal([]byte(e.Data), &data); err == nil {
+		m["data"] = data
+	} else {
+		m["data"] = e.Data
+	}
+
+	return m
+}
+
+// isEventStream returns whether the content type is `text/event-stream`.
+func isEventStream(contentType string) bool {
+	return strings.TrimSpace(strings.Split(contentType, ";")[0]) == "text/event-stream"
+}
+
+// sseReader parses server-sent events as described in the HTML spec:
+// https://html.spec.whatwg.org/multipage/server-sent-events.html
+type sseReader struct {
+	reader *bufio.Reader
+	lastID string
+}
+
+func newSSEReader(r io.Reader) *sseReader {
+	return &sseReader{reader: bufio.NewReader(r)}
+}
+
+// Next blocks until the next event arrives. Returns `io.EOF` once the stream
+// has ended. Comments and events without data are skipped.
+func (r *sseReader) Next() (*sseEvent, error) {
+	event := &sseEvent{}
+	data := []string{}
+	hasData := false
+
+	for {
+		line, err := r.reader.ReadString('\n')
+		if err != nil && (err != io.EOF || line == "") {
+			return nil, err
+		}
+		line = strings.TrimRight(line, "\r\n")
+
+		if line == "" {
+			// A blank line dispatches the event.
+			if !hasData {
+				event = &sseEvent{}
+				continue
+			}
+
+			event.ID = r.lastID
+			if event.Event == "" {
+				event.Event = "message"
+			}
+			event.Data = strings.Join(data, "\n")
+			return event, nil
+		}
+
+		if strings.HasPrefix(line, ":") {
+			// Comments are often used as keep-alives.
+			continue
+		}
+
+		field, value := line, ""
+		if i := strings.Index(line, ":"); i != -1 {
+			field = line[:i]
+			value = strings.TrimPrefix(line[i+1:], " ")
+		}
+
+		switch field {
+		case "event":
+			event.Event = value
+		case "data":
+			data = append(data, value)
+			hasData = true
+		case "id":
+			if !strings.Contains(value, "\x00") {
+				r.lastID = value
+			}
+		case "retry":
+			if retry, err := strconv.Atoi(value); err == nil {
+				event.Retry = retry
+			}
+		}
+	}
+}
+
+// streamEvents outputs server-sent events as they arrive until the stream
+// ends or the command is interrupted. Events are printed much like they are
+// sent, with JSON data formatted, unless a filter or a JSON output format is
+// used, in which case each event is output as a JSON object on its own line.
+func streamEvents(resp *http.Response) error {
+	if err := DecodeResponse(resp); err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	outFormat := viper.GetString("rsh-output-format")
+	filter := viper.GetString("rsh-filter")
+
+	var w *streamWriter
+	if filter != "" || outFormat == "json" || outFormat == "ndjson" || viper.GetBool("rsh-raw") || viper.GetBool("rsh-count") {
+		w = &streamWriter{
+			filter:    filter,
+			raw:       viper.GetBool("rsh-raw"),
+			countOnly: viper.GetBool("rsh-count"),
+			lines:     true,
+		}
+	}
+
+	events := newSSEReader(resp.Body)
+	for {
+		event, err := events.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+
+		if w != nil {
+			if err := w.write(event.Map()); err != nil {
+				return err
+			}
+			continue
+		}
+
+		if err := printEvent(event); err != nil {
+			return err
+		}
+	}
+
+	if w != nil {
+		w.close()
+	}
+
+	return nil
+}
+
+// printEvent writes a human-readable event, highlighting JSON data.
+func printEvent(event *sseEvent) error {
+	fmt.Fprintf(Stdout, "%s %s\n", au.Index(74, "event:"), au.Bold(event.Event))
+	if event.ID != "" {
+		fmt.Fprintf(Stdout, "%s %s\n", au.Index(74, "id:"), event.ID)
+	}
+
+	var data interface{}
+	if err := json.Unmarshal([]byte(event.Data), &data); err != nil {
+		fmt.Fprintf(Stdout, "%s\n\n", event.Data)
+		return nil
+	}
+
+	encoded, err := json.MarshalIndent(data, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	if tty {
+		if encoded, err = Highlight("json", encoded); err != nil {
+			return err
+		}
+	}
+
+	fmt.Fprintf(Stdout, "%s\n\n", encoded)
+	return nil
+}
@@ -0,0 +1,71 @@
+package cli
+
+import (
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/h2non/gock.v1"
+)
+
+func TestSSEReader(t *testing.T) {
+	r := newSSEReader(strings.NewReader(": keep-alive\r\n" +
+		"event: created\r\n" +
+		"id: 1\r\n" +
+		"data: {\"a\":\r\n" +
+		"data: 1}\r\n" +
+		"\r\n" +
+		"retry: 500\n" +
+		"\n" +
+		"data:no space\n" +
+		"\n" +
+		"data: incomplete"))
+
+	e, err := r.Next()
+	assert.NoError(t, err)
+	assert.Equal(t, &sseEvent{ID: "1", Event: "created", Data: "{\"a\":\n1}"}, e)
+	assert.Equal(t, map[string]interface{}{
+		"event": "created",
+		"id":    "1",
+		"data":  map[string]interface{}{"a": 1.0},
+	}, e.Map())
+
+	// The event without data is skipped and the last ID is kept.
+	e, err = r.Next()
+	assert.NoError(t, err)
+	assert.Equal(t, &sseEvent{ID: "1", Event: "message", Data: "no space"}, e)
+	assert.Equal(t, "no space", e.Map()["data"])
+
+	// Incomplete events at the end of the stream are discarded.
+	_, err = r.Next()
+	assert.Equal(t, io.EOF, err)
+}
+
+func TestStreamSSE(t *testing.T) {
+	defer gock.Off()
+
+	body := "event: created\nid: 1\ndata: {\"name\": \"one\"}\n\n: ping\n\nevent: deleted\ndata: two\n\n"
+
+	gock.New("http://example.com").Get("/events").Reply(200).
+		SetHeader("Content-Type", "text/event-stream").
+		BodyString(body)
+
+	captured := run("http://example.com/events")
+	// The last event ID carries over to events which don't set one.
+	assert.Equal(t, "event: created\nid: 1\n{\n  \"name\": \"one\"\n}\n\nevent: deleted\nid: 1\ntwo\n\n", captured)
+
+	gock.New("http://example.com").Get("/events").Reply(200).
+		SetHeader("Content-Type", "text/event-stream").
+		BodyString(body)
+
+	captured = run("http://example.com/events -o json")
+	assert.Equal(t, "{\"data\":{\"name\":\"one\"},\"event\":\"created\",\"id\":\"1\"}\n{\"data\":\"two\",\"event\":\"deleted\",\"id\":\"1\"}\n", captured)
+
+	gock.New("http://example.com").Get("/events").Reply(200).
+		SetHeader("Content-Type", "text/plain").
+		BodyString(body)
+
+	captured = run("http://example.com/events --rsh-stream -f event -r")
+	assert.Equal(t, "created\ndeleted\n", captured)
+}
@@ -0,0 +1,204 @@
+package cli
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"reflect"
+	"strings"
+
+	jmespath "github.com/danielgtaylor/go-jmespath-plus"
+	"github.com/spf13/viper"
+)
+
+// startsWithArray returns whether the next non-whitespace byte in the reader
+// opens a JSON array, without consuming it.
+func startsWithArray(reader *bufio.Reader) bool {
+	for i := 1; ; i++ {
+		peeked, err := reader.Peek(i)
+		if err != nil || len(peeked) < i {
+			return false
+		}
+
+		switch peeked[i-1] {
+		case ' ', '\t', '\r', '\n':
+			continue
+		case '[':
+			return true
+		default:
+			return false
+		}
+	}
+}
+
+// bufferedBody lets a peeked response body be read again from the start.
+type bufferedBody struct {
+	*bufio.Reader
+	io.Closer
+}
+
+// streamResponse outputs the items of JSON array or NDJSON responses when
+// `rsh-stream` is set as they arrive instead of buffering the entire response
+// in memory. The `rsh-filter` is applied to each item rather
+// than to the whole response. Server-sent events are streamed too, and
+// `rsh-stream` forces plain text responses to be parsed as events since some
+// servers send them without the right content type. Returns false if the
+// response cannot be streamed, in which case its body is left ready to be
+// parsed as usual.
+func streamResponse(resp *http.Response) (bool, error) {
+	ct := resp.Header.Get("Content-Type")
+
+	if isEventStream(ct) || (viper.GetBool("rsh-stream") && (ct == "" || strings.HasPrefix(ct, "text/plain"))) {
+		return true, streamEvents(resp)
+	}
+
+	if !viper.GetBool("rsh-stream") {
+		return false, nil
+	}
+
+	ndjson := (NDJSON{}).Detect(ct)
+
+	if !ndjson && !(JSON{}).Detect(ct) {
+		return false, nil
+	}
+
+	if err := DecodeResponse(resp); err != nil {
+		return true, err
+	}
+
+	reader := bufio.NewReader(resp.Body)
+
+	if !ndjson && !startsWithArray(reader) {
+		LogDebug("Response is not a JSON array, buffering instead of streaming")
+		resp.Body = bufferedBody{reader, resp.Body}
+		return false, nil
+	}
+
+	defer resp.Body.Close()
+
+	dec := json.NewDecoder(reader)
+	if viper.GetBool("rsh-precise-numbers") {
+		dec.UseNumber()
+	}
+
+	if !ndjson {
+		// Consume the opening `[`.
+		if _, err := dec.Token(); err != nil {
+			return true, err
+		}
+	}
+
+	w := &streamWriter{
+		filter:    viper.GetString("rsh-filter"),
+		raw:       viper.GetBool("rsh-raw"),
+		countOnly: viper.GetBool("rsh-count"),
+		// Line-delimited input is output the same way unless JSON is requested,
+		// since an unbounded stream would never close the array.
+		lines: viper.GetString("rsh-output-format") == "ndjson" || (ndjson && viper.GetString("rsh-output-format") != "json"),
+	}
+
+	for dec.More() {
+		var item interface{}
+		if err := dec.Decode(&item); err != nil {
+			return true, err
+		}
+
+		if err := w.write(item); err != nil {
+			return true, err
+		}
+	}
+
+	if !ndjson {
+		// Consume the closing `]` to catch truncated responses.
+		if _, err := dec.Token(); err != nil {
+			return true, err
+		}
+	}
+
+	w.close()
+	return true, nil
+}
+
+// streamWriter outputs streamed items either as a JSON array or as one item
+// per line.
+type streamWriter struct {
+	filter    string
+	raw       bool
+	lines     bool
+	countOnly bool
+	count     int
+}
+
+func (w *streamWriter) write(item interface{}) error {
+	if w.filter != "" {
+		var err error
+		item, err = jmespath.Search(w.filter, item)
+		if err != nil {
+			return err
+		}
+
+		if item == nil {
+			return nil
+		}
+	}
+
+	w.count++
+
+	if w.countOnly {
+		return nil
+	}
+
+	if w.raw && reflect.ValueOf(item).Kind() == reflect.String {
+		// Raw mode outputs strings as-is, which is useful for scripts.
+		fmt.Fprintln(Stdout, item)
+		return nil
+	}
+
+	if w.raw || w.lines {
+		encoded, err := json.Marshal(item)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintln(Stdout, string(encoded))
+		return nil
+	}
+
+	encoded, err := json.MarshalIndent(item, "  ", "  ")
+	if err != nil {
+		return err
+	}
+
+	if tty {
+		if encoded, err = Highlight("json", encoded); err != nil {
+			return err
+		}
+	}
+
+	if w.count == 1 {
+		fmt.Fprint(Stdout, "[\n  ")
+	} else {
+		fmt.Fprint(Stdout, ",\n  ")
+	}
+	fmt.Fprint(Stdout, string(encoded))
+
+	return nil
+}
+
+func (w *streamWriter) close() {
+	if w.countOnly {
+		fmt.Fprintln(Stdout, w.count)
+		return
+	}
+
+	if w.raw || w.lines {
+		return
+	}
+
+	if w.count == 0 {
+		fmt.Fprintln(Stdout, "[]")
+	} else {
+		fmt.Fprintln(Stdout, "\n]")
+	}
+}
@@ -0,0 +1,100 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Summarize returns a structural overview of a value, like a sketch of its
+// schema, rather than the value itself. Objects list their keys with value
+// types, arrays show their length and the shape of the first item. Nesting
+// stops after `depth` levels.
+func Summarize(v interface{}, depth int) string {
+	sb := &strings.Builder{}
+	summarize(sb, "", "", v, depth)
+	return sb.String()
+}
+
+// describe returns a short description of the kind of value.
+func describe(v interface{}) string {
+	if _, ok := v.(time.Time); ok {
+		return "date"
+	}
+
+	if _, ok := v.(json.Number); ok {
+		return "number"
+	}
+
+	if b, ok := v.([]byte); ok {
+		return fmt.Sprintf("binary (%d bytes)", len(b))
+	}
+
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Invalid:
+		return "null"
+	case reflect.Ptr:
+		if rv.IsNil() {
+			return "null"
+		}
+		return describe(rv.Elem().Interface())
+	case reflect.Bool:
+		return "boolean"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64, reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Float32, reflect.Float64:
+		return "number"
+	case reflect.String:
+		return "string"
+	case reflect.Slice, reflect.Array:
+		return fmt.Sprintf("array (%d items)", rv.Len())
+	case reflect.Map:
+		return fmt.Sprintf("object (%d keys)", rv.Len())
+	}
+
+	return rv.Kind().String()
+}
+
+func summarize(sb *strings.Builder, indent, key string, v interface{}, depth int) {
+	sb.WriteString(indent)
+	if key != "" {
+		sb.WriteString(key + ": ")
+	}
+	sb.WriteString(describe(v) + "\n")
+
+	if depth <= 0 {
+		return
+	}
+
+	if _, ok := v.([]byte); ok {
+		return
+	}
+
+	rv := reflect.ValueOf(v)
+	if rv.Kind() == reflect.Ptr && !rv.IsNil() {
+		rv = rv.Elem()
+	}
+
+	switch rv.Kind() {
+	case reflect.Slice, reflect.Array:
+		if rv.Len() > 0 {
+			// Assume items are similar, so only the first is described.
+			summarize(sb, indent+"  ", "[0]", rv.Index(0).Interface(), depth-1)
+		}
+	case reflect.Map:
+		keys := []string{}
+		reverse := map[string]reflect.Value{}
+		for _, k := range rv.MapKeys() {
+			ks := fmt.Sprintf("%v", k)
+			keys = append(keys, ks)
+			reverse[ks] = k
+		}
+		sort.Strings(keys)
+
+		for _, k := range keys {
+			summarize(sb, indent+"  ", k, rv.MapIndex(reverse[k]).Interface(), depth-1)
+		}
+	}
+}
@@ -0,0 +1,37 @@
+package cli
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSummarize(t *testing.T) {
+	data := map[string]interface{}{
+		"id":    "abc123",
+		"count": 2.0,
+		"items": []interface{}{
+			map[string]interface{}{
+				"name":    "one",
+				"enabled": true,
+				"tags":    []interface{}{"a", "b"},
+			},
+			map[string]interface{}{
+				"name": "two",
+			},
+		},
+		"binary": []byte{1, 2, 3},
+		"next":   nil,
+	}
+
+	assert.Equal(t, `object (5 keys)
+  binary: binary (3 bytes)
+  count: number
+  id: string
+  items: array (2 items)
+    [0]: object (3 keys)
+  next: null
+`, Summarize(data, 2))
+
+	assert.Equal(t, "object (5 keys)\n", Summarize(data, 0))
+}
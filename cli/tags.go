@@ -0,0 +1,195 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+// tagsAnnotation is the command annotation holding an operation's
+// comma-separated tags, used to group commands in help output.
+const tagsAnnotation = "restish-tags"
+
+// untaggedGroup is the name of the group for operations without tags.
+const untaggedGroup = "Other"
+
+// commandGroup is a named set of commands shown together in help output.
+type commandGroup struct {
+	Name     string
+	Commands []*cobra.Command
+}
+
+// commandTags returns the tags of a command created from an operation.
+func commandTags(cmd *cobra.Command) []string {
+	if cmd.Annotations[tagsAnnotation] == "" {
+		return nil
+	}
+	return strings.Split(cmd.Annotations[tagsAnnotation], ",")
+}
+
+// groupNames returns the sorted group names, with the untagged group last.
+func groupNames(groups map[string]bool) []string {
+	names := []string{}
+	for name := range groups {
+		if name != untaggedGroup {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	if groups[untaggedGroup] {
+		names = append(names, untaggedGroup)
+	}
+
+	return names
+}
+
+// commandGroups groups the available subcommands by their operation tags.
+// A command with several tags is listed under each. Returns nil if none of
+// the subcommands are tagged so that a flat list is used instead.
+func commandGroups(cmd *cobra.Command) []commandGroup {
+	byTag := map[string][]*cobra.Command{}
+	seen := map[string]bool{}
+	tagged := false
+
+	for _, sub := range cmd.Commands() {
+		if !sub.IsAvailableCommand() && sub.Name() != "help" {
+			continue
+		}
+
+		tags := commandTags(sub)
+		if len(tags) > 0 {
+			tagged = true
+		} else {
+			tags = []string{untaggedGroup}
+		}
+
+		for _, tag := range tags {
+			byTag[tag] = append(byTag[tag], sub)
+			seen[tag] = true
+		}
+	}
+
+	if !tagged {
+		return nil
+	}
+
+	groups := []commandGroup{}
+	for _, name := range groupNames(seen) {
+		groups = append(groups, commandGroup{Name: name, Commands: byTag[name]})
+	}
+
+	return groups
+}
+
+// operationGroup is a tag along with the operations which use it.
+type operationGroup struct {
+	Tag        string      `json:"tag"`
+	Operations []Operation `json:"operations"`
+}
+
+// groupOperations groups visible operations by tag, optionally only those
+// with the given tag (case-insensitive).
+func groupOperations(ops []Operation, only string) []operationGroup {
+	byTag := map[string][]Operation{}
+	seen := map[string]bool{}
+
+	for _, op := range ops {
+		if op.Hidden {
+			continue
+		}
+
+		tags := op.Tags
+		if len(tags) == 0 {
+			tags = []string{untaggedGroup}
+		}
+
+		for _, tag := range tags {
+			if only != "" && !strings.EqualFold(tag, only) {
+				continue
+			}
+			byTag[tag] = append(byTag[tag], op)
+			seen[tag] = true
+		}
+	}
+
+	groups := []operationGroup{}
+	for _, name := range groupNames(seen) {
+		ops := byTag[name]
+		sort.Slice(ops, func(i, j int) bool {
+			return ops[i].Name < ops[j].Name
+		})
+		groups = append(groups, operationGroup{Tag: name, Operations: ops})
+	}
+
+	return groups
+}
+
+// listOperations prints an API's operations grouped by tag.
+func listOperations(api API, tag string) error {
+	groups := groupOperations(api.Operations, tag)
+
+	if tag != "" && len(groups) == 0 {
+		return fmt.Errorf("no operations found with tag %s", tag)
+	}
+
+	if viper.GetString("rsh-output-format") == "json" {
+		encoded, err := json.MarshalIndent(groups, "", "  ")
+		if err != nil {
+			return err
+		}
+
+		if tty {
+			if encoded, err = Highlight("json", encoded); err != nil {
+				return err
+			}
+		}
+
+		fmt.Fprintln(Stdout, string(encoded))
+		return nil
+	}
+
+	padding := 0
+	for _, g := range groups {
+		for _, op := range g.Operations {
+			if len(op.Name) > padding {
+				padding = len(op.Name)
+			}
+		}
+	}
+
+	for i, g := range groups {
+		if i > 0 {
+			fmt.Fprintln(Stdout)
+		}
+
+		fmt.Fprintln(Stdout, au.Bold(g.Tag))
+		for _, op := range g.Operations {
+			fmt.Fprintf(Stdout, "  %-*s  %s\n", padding, op.Name, op.Short)
+		}
+	}
+
+	return nil
+}
+
+func apiOperations(cmd *cobra.Command, args []string) {
+	config := configs[args[0]]
+	if config == nil {
+		panic("API " + args[0] + " not found")
+	}
+
+	// Use a throwaway command so the API's operations aren't registered.
+	api, err := Load(config.Base, &cobra.Command{})
+	if err != nil {
+		panic(err)
+	}
+
+	tag, _ := cmd.Flags().GetString("tag")
+	if err := listOperations(api, tag); err != nil {
+		panic(err)
+	}
+}
@@ -0,0 +1,72 @@
+package cli
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/spf13/cobra"
+	"github.com/stretchr/testify/assert"
+)
+
+var taggedOperations = []Operation{
+	{Name: "list-invoices", Short: "List invoices", Tags: []string{"billing"}},
+	{Name: "create-user", Short: "Create a user", Tags: []string{"users", "admin"}},
+	{Name: "get-invoice", Short: "Get an invoice", Tags: []string{"billing"}},
+	{Name: "health", Short: "Health check"},
+	{Name: "secret", Short: "Hidden operation", Hidden: true, Tags: []string{"billing"}},
+}
+
+func TestGroupOperations(t *testing.T) {
+	groups := groupOperations(taggedOperations, "")
+	assert.Len(t, groups, 4)
+	assert.Equal(t, "admin", groups[0].Tag)
+	assert.Equal(t, "billing", groups[1].Tag)
+	assert.Equal(t, "get-invoice", groups[1].Operations[0].Name)
+	assert.Equal(t, "list-invoices", groups[1].Operations[1].Name)
+	assert.Len(t, groups[1].Operations, 2)
+	assert.Equal(t, "users", groups[2].Tag)
+	assert.Equal(t, "Other", groups[3].Tag)
+
+	groups = groupOperations(taggedOperations, "Billing")
+	assert.Len(t, groups, 1)
+	assert.Equal(t, "billing", groups[0].Tag)
+}
+
+func TestListOperations(t *testing.T) {
+	reset(false)
+
+	captured := &strings.Builder{}
+	Stdout = captured
+	assert.NoError(t, listOperations(API{Operations: taggedOperations}, "billing"))
+	assert.Equal(t, "billing\n  get-invoice    Get an invoice\n  list-invoices  List invoices\n", captured.String())
+
+	assert.EqualError(t, listOperations(API{Operations: taggedOperations}, "missing"), "no operations found with tag missing")
+}
+
+func TestHelpGroupsByTag(t *testing.T) {
+	reset(false)
+
+	api := &cobra.Command{Use: "example"}
+	Root.AddCommand(api)
+	defer Root.RemoveCommand(api)
+
+	for _, op := range taggedOperations {
+		api.AddCommand(op.command())
+	}
+
+	usage := api.UsageString()
+	assert.Contains(t, usage, "billing Commands:\n  get-invoice")
+	assert.Contains(t, usage, "users Commands:\n  create-user")
+	assert.Contains(t, usage, "Other Commands:\n  health")
+	assert.NotContains(t, usage, "Available Commands:")
+	// Match the description since global flags like `--rsh-show-secrets`
+	// contain the name.
+	assert.NotContains(t, usage, "Hidden operation")
+
+	// Untagged APIs keep the flat list.
+	plain := &cobra.Command{Use: "plain"}
+	Root.AddCommand(plain)
+	defer Root.RemoveCommand(plain)
+	plain.AddCommand(Operation{Name: "health", Short: "Health check"}.command())
+	assert.Contains(t, plain.UsageString(), "Available Commands:\n  health")
+}
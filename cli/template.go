@@ -0,0 +1,70 @@
+package cli
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"strings"
+	"text/template"
+)
+
+// templateFuncs are helper functions available to `-o template` output in
+// addition to Go's built-ins like `index`, `len` and `printf`.
+var templateFuncs = template.FuncMap{
+	// json encodes a value as compact JSON.
+	"json": func(v interface{}) (string, error) {
+		encoded, err := json.Marshal(v)
+		return string(encoded), err
+	},
+	// pretty encodes a value as indented JSON.
+	"pretty": func(v interface{}) (string, error) {
+		encoded, err := json.MarshalIndent(v, "", "  ")
+		return string(encoded), err
+	},
+	// join concatenates the items of a list with a separator.
+	"join": func(sep string, v []interface{}) string {
+		parts := make([]string, len(v))
+		for i, item := range v {
+			parts[i] = fmt.Sprintf("%v", item)
+		}
+		return strings.Join(parts, sep)
+	},
+	// default returns the fallback if the value is missing or empty.
+	"default": func(fallback, v interface{}) interface{} {
+		if v == nil || v == "" {
+			return fallback
+		}
+		return v
+	},
+	"upper": strings.ToUpper,
+	"lower": strings.ToLower,
+}
+
+// renderTemplate renders the data with a Go `text/template`. The template is
+// given inline or loaded from a file when prefixed with `@`.
+func renderTemplate(tmpl string, data interface{}) ([]byte, error) {
+	if tmpl == "" {
+		return nil, fmt.Errorf("template output requires --rsh-template")
+	}
+
+	if strings.HasPrefix(tmpl, "@") {
+		contents, err := ioutil.ReadFile(tmpl[1:])
+		if err != nil {
+			return nil, fmt.Errorf("cannot read template: %w", err)
+		}
+		tmpl = string(contents)
+	}
+
+	t, err := template.New("output").Funcs(templateFuncs).Parse(tmpl)
+	if err != nil {
+		return nil, err
+	}
+
+	buf := &bytes.Buffer{}
+	if err := t.Execute(buf, data); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
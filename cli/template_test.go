@@ -0,0 +1,57 @@
+package cli
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/h2non/gock.v1"
+)
+
+func TestRenderTemplate(t *testing.T) {
+	data := map[string]interface{}{
+		"items": []interface{}{
+			map[string]interface{}{"id": "a", "tags": []interface{}{"x", "y"}},
+			map[string]interface{}{"id": "b", "tags": []interface{}{}},
+		},
+	}
+
+	out, err := renderTemplate(`{{range .items}}{{.id}}: {{join "," .tags}} {{json .tags}}
+{{end}}`, data)
+	assert.NoError(t, err)
+	assert.Equal(t, "a: x,y [\"x\",\"y\"]\nb:  []\n", string(out))
+
+	_, err = renderTemplate("", data)
+	assert.Error(t, err)
+
+	_, err = renderTemplate("{{.foo", data)
+	assert.Error(t, err)
+}
+
+func TestRenderTemplateFile(t *testing.T) {
+	f, err := ioutil.TempFile("", "restish-template")
+	assert.NoError(t, err)
+	defer os.Remove(f.Name())
+	f.WriteString(`{{upper .name}}`)
+	f.Close()
+
+	out, err := renderTemplate("@"+f.Name(), map[string]interface{}{"name": "foo"})
+	assert.NoError(t, err)
+	assert.Equal(t, "FOO", string(out))
+}
+
+func TestTemplateOutput(t *testing.T) {
+	defer gock.Off()
+
+	gock.New("http://example.com").
+		Get("/items").
+		Reply(200).
+		JSON([]interface{}{
+			map[string]interface{}{"id": 1, "name": "one"},
+			map[string]interface{}{"id": 2, "name": "two"},
+		})
+
+	captured := run(`http://example.com/items -o template --rsh-template {{range.body}}{{.id}}={{.name}};{{end}}`)
+	assert.Equal(t, "1=one;2=two;\n", captured)
+}
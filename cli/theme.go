@@ -0,0 +1,125 @@
+package cli
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/alecthomas/chroma"
+	"github.com/alecthomas/chroma/styles"
+	"github.com/spf13/viper"
+)
+
+// highlightStyle is the name of the registered chroma style used for all
+// highlighted output. It is built from the selected theme.
+const highlightStyle = "cli"
+
+// themes are the built-in 256-color presets selectable via `rsh-theme`.
+var themes = map[string]chroma.StyleEntries{
+	"dark": {
+		// Used for JSON/YAML/Readable
+		chroma.Comment:      "#9e9e9e",
+		chroma.Keyword:      "#ff5f87",
+		chroma.Punctuation:  "#9e9e9e",
+		chroma.NameTag:      "#5fafd7",
+		chroma.Number:       "#d78700",
+		chroma.String:       "#afd787",
+		chroma.StringSymbol: "italic #D6FFB7",
+		chroma.Date:         "#af87af",
+		chroma.NumberHex:    "#ffd7d7",
+
+		// Used for HTTP
+		chroma.Name:          "#5fafd7",
+		chroma.NameFunction:  "#ff5f87",
+		chroma.NameNamespace: "#b2b2b2",
+
+		// Used for Markdown
+		chroma.GenericHeading:    "#5fafd7",
+		chroma.GenericSubheading: "#5fafd7",
+		chroma.GenericEmph:       "italic #ffd7d7",
+		chroma.GenericStrong:     "bold #af87af",
+		chroma.GenericDeleted:    "#3a3a3a",
+		chroma.NameAttribute:     "underline",
+	},
+	"light": {
+		// Used for JSON/YAML/Readable
+		chroma.Comment:      "#8a8a8a",
+		chroma.Keyword:      "#d7005f",
+		chroma.Punctuation:  "#585858",
+		chroma.NameTag:      "#005f87",
+		chroma.Number:       "#af5f00",
+		chroma.String:       "#5f8700",
+		chroma.StringSymbol: "italic #008700",
+		chroma.Date:         "#875f87",
+		chroma.NumberHex:    "#870000",
+
+		// Used for HTTP
+		chroma.Name:          "#005f87",
+		chroma.NameFunction:  "#d7005f",
+		chroma.NameNamespace: "#585858",
+
+		// Used for Markdown
+		chroma.GenericHeading:    "#005f87",
+		chroma.GenericSubheading: "#005f87",
+		chroma.GenericEmph:       "italic #870000",
+		chroma.GenericStrong:     "bold #875f87",
+		chroma.GenericDeleted:    "#bcbcbc",
+		chroma.NameAttribute:     "underline",
+	},
+}
+
+// themeTokens maps the keys of the `theme` config to the token types they
+// color. JSON output from the standard lexer colors `null` like booleans.
+var themeTokens = map[string]chroma.TokenType{
+	"key":         chroma.NameTag,
+	"string":      chroma.String,
+	"number":      chroma.Number,
+	"bool":        chroma.KeywordConstant,
+	"null":        chroma.KeywordPseudo,
+	"punctuation": chroma.Punctuation,
+}
+
+func init() {
+	// Make sure highlighting works even before the theme is loaded.
+	styles.Register(chroma.MustNewStyle(highlightStyle, themes["dark"]))
+}
+
+// loadTheme registers the highlighting style from the `rsh-theme` preset
+// with any color overrides from the `theme` config applied.
+func loadTheme() error {
+	name := viper.GetString("rsh-theme")
+	if name == "" {
+		name = "dark"
+	}
+
+	preset, ok := themes[name]
+	if !ok {
+		return fmt.Errorf("unknown theme %s, expected dark or light", name)
+	}
+
+	entries := chroma.StyleEntries{}
+	for token, style := range preset {
+		entries[token] = style
+	}
+
+	for key, style := range viper.GetStringMapString("theme") {
+		token, ok := themeTokens[strings.ToLower(key)]
+		if !ok {
+			keys := []string{}
+			for k := range themeTokens {
+				keys = append(keys, k)
+			}
+			sort.Strings(keys)
+			return fmt.Errorf("unknown theme key %s, expected one of %s", key, strings.Join(keys, ", "))
+		}
+		entries[token] = style
+	}
+
+	style, err := chroma.NewStyle(highlightStyle, entries)
+	if err != nil {
+		return fmt.Errorf("invalid theme: %w", err)
+	}
+
+	styles.Register(style)
+	return nil
+}
@@ -0,0 +1,55 @@
+package cli
+
+import (
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/alecthomas/chroma"
+	"github.com/alecthomas/chroma/styles"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTheme(t *testing.T) {
+	defer func() {
+		viper.Set("rsh-theme", "")
+		viper.Set("theme", nil)
+		loadTheme()
+	}()
+
+	viper.Set("rsh-theme", "light")
+	viper.Set("theme", map[string]interface{}{"string": "#ff0000"})
+	assert.NoError(t, loadTheme())
+
+	style := styles.Get(highlightStyle)
+	assert.Equal(t, chroma.MustParseColour("#005f87"), style.Get(chroma.NameTag).Colour)
+	assert.Equal(t, chroma.MustParseColour("#ff0000"), style.Get(chroma.LiteralStringDouble).Colour)
+
+	viper.Set("theme", map[string]interface{}{"bogus": "#ff0000"})
+	assert.Error(t, loadTheme())
+
+	viper.Set("rsh-theme", "solarized")
+	assert.Error(t, loadTheme())
+}
+
+func TestInvalidThemeFlag(t *testing.T) {
+	defer func() {
+		viper.Set("rsh-theme", "")
+		loadTheme()
+	}()
+
+	reset(false)
+
+	code := 0
+	osExit = func(c int) { code = c }
+
+	captured := &strings.Builder{}
+	Stdout = captured
+	Stderr = captured
+	os.Args = []string{"restish", "--rsh-theme", "solarized", "http://example.com/"}
+	Run()
+
+	assert.Equal(t, 1, code)
+	assert.Contains(t, captured.String(), "unknown theme solarized")
+}
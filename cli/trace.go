@@ -0,0 +1,160 @@
+package cli
+
+import (
+	"crypto/tls"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptrace"
+	"sync"
+	"time"
+
+	"github.com/spf13/viper"
+)
+
+// traceEvent is a single timed event during a request.
+type traceEvent struct {
+	Event   string    `json:"event"`
+	Time    time.Time `json:"time"`
+	Elapsed float64   `json:"elapsed_ms"`
+	Detail  string    `json:"detail,omitempty"`
+}
+
+// requestTrace contains detailed timing information for a single request.
+type requestTrace struct {
+	Method   string       `json:"method"`
+	URL      string       `json:"url"`
+	Start    time.Time    `json:"start"`
+	Status   int          `json:"status,omitempty"`
+	Error    string       `json:"error,omitempty"`
+	Duration float64      `json:"duration_ms"`
+	Events   []traceEvent `json:"events"`
+
+	lock sync.Mutex
+}
+
+var (
+	tracesLock sync.Mutex
+	traces     []*requestTrace
+)
+
+func msSince(start, t time.Time) float64 {
+	return float64(t.Sub(start).Microseconds()) / 1000
+}
+
+// add records an event with the current time.
+func (t *requestTrace) add(event, detail string) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	now := time.Now()
+	t.Events = append(t.Events, traceEvent{
+		Event:   event,
+		Time:    now,
+		Elapsed: msSince(t.Start, now),
+		Detail:  detail,
+	})
+}
+
+// withTrace returns a copy of the request which records `httptrace` events.
+func withTrace(req *http.Request) (*http.Request, *requestTrace) {
+	t := &requestTrace{
+		Method: req.Method,
+		URL:    req.URL.String(),
+		Start:  time.Now(),
+		Events: []traceEvent{},
+	}
+
+	ct := &httptrace.ClientTrace{
+		GetConn: func(hostPort string) {
+			t.add("get_conn", hostPort)
+		},
+		DNSStart: func(info httptrace.DNSStartInfo) {
+			t.add("dns_start", info.Host)
+		},
+		DNSDone: func(info httptrace.DNSDoneInfo) {
+			detail := ""
+			if info.Err != nil {
+				detail = info.Err.Error()
+			}
+			t.add("dns_done", detail)
+		},
+		ConnectStart: func(network, addr string) {
+			t.add("connect_start", addr)
+		},
+		ConnectDone: func(network, addr string, err error) {
+			detail := addr
+			if err != nil {
+				detail = err.Error()
+			}
+			t.add("connect_done", detail)
+		},
+		TLSHandshakeStart: func() {
+			t.add("tls_handshake_start", "")
+		},
+		TLSHandshakeDone: func(state tls.ConnectionState, err error) {
+			detail := state.NegotiatedProtocol
+			if err != nil {
+				detail = err.Error()
+			}
+			t.add("tls_handshake_done", detail)
+		},
+		GotConn: func(info httptrace.GotConnInfo) {
+			detail := "new"
+			if info.Reused {
+				detail = "reused"
+			}
+			t.add("got_conn", detail)
+		},
+		WroteHeaders: func() {
+			t.add("wrote_headers", "")
+		},
+		WroteRequest: func(info httptrace.WroteRequestInfo) {
+			detail := ""
+			if info.Err != nil {
+				detail = info.Err.Error()
+			}
+			t.add("wrote_request", detail)
+		},
+		GotFirstResponseByte: func() {
+			t.add("first_response_byte", "")
+		},
+	}
+
+	return req.WithContext(httptrace.WithClientTrace(req.Context(), ct)), t
+}
+
+// finish completes the trace and writes all traces so far to the file set
+// via `rsh-trace-out`. Traces accumulate for the lifetime of the process, so
+// e.g. each page of a paginated response is included. Safe to call on a nil
+// trace.
+func (t *requestTrace) finish(resp *http.Response, err error) {
+	if t == nil {
+		return
+	}
+
+	t.lock.Lock()
+	t.Duration = msSince(t.Start, time.Now())
+	if resp != nil {
+		t.Status = resp.StatusCode
+	}
+	if err != nil {
+		t.Error = err.Error()
+	}
+	t.lock.Unlock()
+
+	tracesLock.Lock()
+	defer tracesLock.Unlock()
+
+	traces = append(traces, t)
+
+	encoded, err := json.MarshalIndent(traces, "", "  ")
+	if err != nil {
+		LogWarning("Unable to encode request trace: %v", err)
+		return
+	}
+
+	if err := ioutil.WriteFile(viper.GetString("rsh-trace-out"), encoded, 0644); err != nil {
+		LogWarning("Unable to write request trace: %v", err)
+	}
+}
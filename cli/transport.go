@@ -1,15 +1,20 @@
 package cli
 
 import (
+	"context"
+	"crypto/tls"
 	"fmt"
 	"io/ioutil"
+	"net"
 	"net/http"
-	"path"
+	"net/url"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/gbl08ma/httpcache"
-	"github.com/gbl08ma/httpcache/diskcache"
+	"github.com/spf13/viper"
+	"golang.org/x/net/http2"
 )
 
 // cacheKey returns the cache key for req.
@@ -42,9 +47,48 @@ func shouldCache(resp *http.Response) bool {
 	return true
 }
 
-// CachedTransport returns an HTTP transport with caching abilities.
+// revalidatingTransport notes when a conditional request sent by the cache
+// with a stored `ETag` or `Last-Modified` validator results in a
+// `304 Not Modified`, meaning the cached body is used. Requests are sent
+// using the given transport, or the default HTTP transport if it is nil.
+type revalidatingTransport struct {
+	transport http.RoundTripper
+}
+
+func (r revalidatingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	transport := r.transport
+	if transport == nil {
+		transport = http.DefaultTransport
+	}
+
+	resp, err := transport.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode == http.StatusNotModified {
+		if etag := req.Header.Get("If-None-Match"); etag != "" {
+			LogDebug("Server confirmed ETag %s is not modified", etag)
+		} else if since := req.Header.Get("If-Modified-Since"); since != "" {
+			LogDebug("Server confirmed no modifications since %s", since)
+		}
+	}
+
+	return resp, nil
+}
+
+// CachedTransport returns an HTTP transport with caching abilities. Stale
+// responses with an `ETag` or `Last-Modified` header are revalidated with a
+// conditional request, and a `304 Not Modified` returns the cached body.
 func CachedTransport() *httpcache.Transport {
-	t := httpcache.NewTransport(diskcache.New(path.Join(cacheDir(), "responses")))
+	return cachedTransport(nil)
+}
+
+// cachedTransport is like `CachedTransport` but sends requests using the
+// given transport.
+func cachedTransport(transport http.RoundTripper) *httpcache.Transport {
+	t := httpcache.NewTransport(newResponseCache())
+	t.Transport = revalidatingTransport{transport}
 	t.MarkCachedResponses = false
 	return t
 }
@@ -89,6 +133,346 @@ func MinCachedTransport(min time.Duration) *httpcache.Transport {
 	return t
 }
 
+// ttlTransport overrides the freshness lifetime set by the server for
+// successful responses, so they are cached for the given duration.
+type ttlTransport struct {
+	ttl       time.Duration
+	transport http.RoundTripper
+}
+
+func (t ttlTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp, err := revalidatingTransport{t.transport}.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode < 400 {
+		resp.Header.Set("Cache-Control", fmt.Sprintf("max-age=%d", int(t.ttl.Seconds())))
+		resp.Header.Del("Expires")
+		resp.Header.Del("Pragma")
+	}
+
+	return resp, nil
+}
+
+// TTLCachedTransport returns an HTTP transport with caching abilities where
+// responses are considered fresh for the given duration regardless of any
+// cache headers sent by the server.
+func TTLCachedTransport(ttl time.Duration) *httpcache.Transport {
+	return ttlCachedTransport(ttl, nil)
+}
+
+// ttlCachedTransport is like `TTLCachedTransport` but sends requests using
+// the given transport.
+func ttlCachedTransport(ttl time.Duration, transport http.RoundTripper) *httpcache.Transport {
+	t := cachedTransport(transport)
+	t.Transport = ttlTransport{ttl, transport}
+	return t
+}
+
+// cacheTTL returns the cache lifetime override from `rsh-cache-ttl`, or else
+// the API's `cache_ttl` config, or zero to use the server's cache headers.
+func cacheTTL(config *APIConfig) (time.Duration, error) {
+	value := viper.GetString("rsh-cache-ttl")
+	if value == "" && config != nil {
+		value = config.CacheTTL
+	}
+
+	if value == "" || value == "0" {
+		return 0, nil
+	}
+
+	ttl, err := time.ParseDuration(value)
+	if err != nil {
+		return 0, fmt.Errorf("invalid cache TTL %s: %v", value, err)
+	}
+
+	return ttl, nil
+}
+
+// durationFlag parses a duration flag, returning the default if it is unset.
+func durationFlag(name, description string, def time.Duration) (time.Duration, error) {
+	value := viper.GetString(name)
+	if value == "" || value == "0" {
+		return def, nil
+	}
+
+	d, err := time.ParseDuration(value)
+	if err != nil {
+		return 0, fmt.Errorf("invalid %s %s: %v", description, value, err)
+	}
+
+	return d, nil
+}
+
+// unixSocketKey is the request context key for the Unix socket to dial
+// instead of the host in the URL.
+type unixSocketKey struct{}
+
+// splitUnixSocketURL splits an `http+unix` URL like
+// `http+unix:///var/run/docker.sock:/v1.41/info` into the socket path and an
+// HTTP URL for the request path, as the host is not used to connect.
+func splitUnixSocketURL(u *url.URL) (string, *url.URL, error) {
+	i := strings.Index(u.Path, ":")
+	if i < 1 {
+		return "", nil, fmt.Errorf("invalid unix socket URL %s, expected http+unix:///path/to.sock:/request/path", u)
+	}
+
+	httpURL := *u
+	httpURL.Scheme = "http"
+	httpURL.Host = "localhost"
+	httpURL.Path = u.Path[i+1:]
+	httpURL.RawPath = ""
+	if httpURL.Path == "" {
+		httpURL.Path = "/"
+	}
+
+	return u.Path[:i], &httpURL, nil
+}
+
+// withUnixSocket returns a request which is sent over a Unix socket, either
+// from an `http+unix` URL or `rsh-unix-socket`, like curl's `--unix-socket`.
+// Other requests are returned unchanged.
+func withUnixSocket(req *http.Request) (*http.Request, error) {
+	socket := viper.GetString("rsh-unix-socket")
+
+	if req.URL.Scheme == "http+unix" {
+		s, u, err := splitUnixSocketURL(req.URL)
+		if err != nil {
+			return nil, err
+		}
+
+		socket = s
+		req = req.WithContext(req.Context())
+		req.URL = u
+		req.Host = ""
+	}
+
+	if socket == "" {
+		return req, nil
+	}
+
+	LogDebug("Connecting via unix socket %s", socket)
+	return req.WithContext(context.WithValue(req.Context(), unixSocketKey{}, socket)), nil
+}
+
+// applyTransportTimeouts sets the connection and TLS handshake timeouts from
+// `rsh-connect-timeout` and `rsh-tls-timeout`, which are separate from the
+// overall `rsh-timeout`. Go's defaults are used if they are unset.
+func applyTransportTimeouts(t *http.Transport) error {
+	connect, err := durationFlag("rsh-connect-timeout", "connect timeout", 30*time.Second)
+	if err != nil {
+		return err
+	}
+
+	handshake, err := durationFlag("rsh-tls-timeout", "TLS timeout", 10*time.Second)
+	if err != nil {
+		return err
+	}
+
+	dialer := &net.Dialer{
+		Timeout:   connect,
+		KeepAlive: 30 * time.Second,
+	}
+	t.DialContext = func(ctx context.Context, network, addr string) (net.Conn, error) {
+		if socket, ok := ctx.Value(unixSocketKey{}).(string); ok {
+			return dialer.DialContext(ctx, "unix", socket)
+		}
+		return dialer.DialContext(ctx, network, addr)
+	}
+	t.TLSHandshakeTimeout = handshake
+
+	return nil
+}
+
+// defaultMaxIdleConns is the default number of idle connections kept open
+// per host, which is higher than Go's default of two so that parallel
+// requests can reuse connections too.
+const defaultMaxIdleConns = 16
+
+var (
+	sharedClientsMu sync.Mutex
+	sharedClients   = map[string]*http.Client{}
+)
+
+// sharedClient returns the client for the key, creating it on first use.
+func sharedClient(key string, create func() *http.Client) *http.Client {
+	sharedClientsMu.Lock()
+	defer sharedClientsMu.Unlock()
+
+	if c, ok := sharedClients[key]; ok {
+		return c
+	}
+
+	c := create()
+	sharedClients[key] = c
+	return c
+}
+
+// resetSharedClients forgets the shared clients, e.g. when the configuration
+// is reloaded and the cache location may have changed.
+func resetSharedClients() {
+	sharedClientsMu.Lock()
+	defer sharedClientsMu.Unlock()
+	sharedClients = map[string]*http.Client{}
+}
+
+// applyConnectionPool enables keep-alives and sets how many idle connections
+// are kept open per host from `rsh-max-idle-conns`.
+func applyConnectionPool(t *http.Transport) error {
+	max := viper.GetInt("rsh-max-idle-conns")
+	if max < 0 {
+		return fmt.Errorf("invalid max idle connections %d", max)
+	}
+
+	if max == 0 {
+		max = defaultMaxIdleConns
+	}
+
+	t.DisableKeepAlives = false
+	t.MaxIdleConnsPerHost = max
+	if t.MaxIdleConns != 0 && t.MaxIdleConns < max {
+		t.MaxIdleConns = max
+	}
+
+	return nil
+}
+
+// h2cTransport sends cleartext `http://` requests using HTTP/2 without TLS
+// (h2c) when `rsh-http-version` is `2`. Otherwise it defers to the normal
+// HTTP/1.1 transport.
+type h2cTransport struct {
+	transport *http2.Transport
+}
+
+func (h h2cTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if viper.GetString("rsh-http-version") != "2" {
+		return nil, http.ErrSkipAltProtocol
+	}
+
+	return h.transport.RoundTrip(req)
+}
+
+// h2cConnPool reuses h2c connections by address. Unlike the default pool it
+// dials using the request's context, so timeouts and cancellation apply while
+// connecting.
+type h2cConnPool struct {
+	transport *http2.Transport
+	dial      func(ctx context.Context, network, addr string) (net.Conn, error)
+
+	mu    sync.Mutex
+	conns map[string][]*http2.ClientConn
+}
+
+// GetClientConn returns an open connection to the address which can take
+// another request, or dials a new one.
+func (p *h2cConnPool) GetClientConn(req *http.Request, addr string) (*http2.ClientConn, error) {
+	p.mu.Lock()
+	for _, cc := range p.conns[addr] {
+		if cc.CanTakeNewRequest() {
+			p.mu.Unlock()
+			return cc, nil
+		}
+	}
+	p.mu.Unlock()
+
+	conn, err := p.dial(req.Context(), "tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+
+	cc, err := p.transport.NewClientConn(conn)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.conns == nil {
+		p.conns = map[string][]*http2.ClientConn{}
+	}
+	p.conns[addr] = append(p.conns[addr], cc)
+
+	return cc, nil
+}
+
+// MarkDead removes a connection which can no longer be used from the pool.
+func (p *h2cConnPool) MarkDead(dead *http2.ClientConn) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	for addr, conns := range p.conns {
+		kept := conns[:0]
+		for _, cc := range conns {
+			if cc != dead {
+				kept = append(kept, cc)
+			}
+		}
+
+		if len(kept) == 0 {
+			delete(p.conns, addr)
+		} else {
+			p.conns[addr] = kept
+		}
+	}
+}
+
+// applyHTTPVersion configures the transport for `rsh-http-version`, which is
+// one of `auto` to negotiate the version, `1.1` to disable HTTP/2, or `2` to
+// require it, including over cleartext connections via h2c.
+func applyHTTPVersion(t *http.Transport) error {
+	version := viper.GetString("rsh-http-version")
+
+	switch version {
+	case "", "auto", "2":
+		t.ForceAttemptHTTP2 = true
+	case "1.1":
+		// An empty, non-nil map disables HTTP/2, and it must not be offered
+		// via ALPN either.
+		t.ForceAttemptHTTP2 = false
+		t.TLSNextProto = map[string]func(string, *tls.Conn) http.RoundTripper{}
+		if t.TLSClientConfig != nil {
+			protos := []string{}
+			for _, p := range t.TLSClientConfig.NextProtos {
+				if p != "h2" {
+					protos = append(protos, p)
+				}
+			}
+			t.TLSClientConfig.NextProtos = protos
+		}
+	default:
+		return fmt.Errorf("invalid HTTP version %s, expected one of auto, 1.1, 2", version)
+	}
+
+	if version == "2" {
+		t2 := &http2.Transport{AllowHTTP: true}
+		t2.ConnPool = &h2cConnPool{
+			transport: t2,
+			dial: func(ctx context.Context, network, addr string) (net.Conn, error) {
+				// Prior knowledge h2c uses a plain connection.
+				return t.DialContext(ctx, network, addr)
+			},
+		}
+		t.RegisterProtocol("http", h2cTransport{t2})
+	}
+
+	return nil
+}
+
+// checkHTTPVersion logs the negotiated protocol and returns an error if
+// HTTP/2 was required but the server does not support it.
+func checkHTTPVersion(resp *http.Response) error {
+	LogDebug("Negotiated protocol %s", resp.Proto)
+
+	if viper.GetString("rsh-http-version") == "2" && resp.ProtoMajor != 2 {
+		resp.Body.Close()
+		return fmt.Errorf("HTTP/2 was required but the server responded using %s", resp.Proto)
+	}
+
+	return nil
+}
+
 type invalidateCachedTransport struct {
 	transport *httpcache.Transport
 }
@@ -110,3 +494,182 @@ func InvalidateCachedTransport() http.RoundTripper {
 		transport: CachedTransport(),
 	}
 }
+
+// transportSettings are the flags and API config options which change how
+// connections are made.
+type transportSettings struct {
+	connectTimeout string
+	tlsTimeout     string
+	httpVersion    string
+	maxIdleConns   int
+	tls            TLSConfig
+}
+
+// currentTransportSettings returns the transport settings for the API config,
+// where CLI flags overwrite profile options.
+func currentTransportSettings(config *APIConfig) transportSettings {
+	s := transportSettings{
+		connectTimeout: viper.GetString("rsh-connect-timeout"),
+		tlsTimeout:     viper.GetString("rsh-tls-timeout"),
+		httpVersion:    viper.GetString("rsh-http-version"),
+		maxIdleConns:   viper.GetInt("rsh-max-idle-conns"),
+	}
+
+	if config != nil && config.TLS != nil {
+		s.tls = *config.TLS
+	}
+
+	if viper.GetBool("rsh-insecure") {
+		s.tls.InsecureSkipVerify = true
+	}
+	if cert := viper.GetString("rsh-client-cert"); cert != "" {
+		s.tls.Cert = cert
+	}
+	if key := viper.GetString("rsh-client-key"); key != "" {
+		s.tls.Key = key
+	}
+	if caCert := viper.GetString("rsh-ca-cert"); caCert != "" {
+		s.tls.CACert = caCert
+	}
+
+	return s
+}
+
+var (
+	configuredTransportsMu sync.Mutex
+	configuredTransports   = map[string]*http.Transport{}
+
+	// defaultTransportVersion changes whenever the default HTTP transport is
+	// modified, so that new transports are cloned from it.
+	defaultTransportVersion int
+)
+
+// updateDefaultTransport modifies the default HTTP transport, from which
+// request transports are cloned. The update returns whether it changed
+// anything. Nothing is done if the default transport has been replaced.
+func updateDefaultTransport(update func(t *http.Transport) (bool, error)) error {
+	configuredTransportsMu.Lock()
+	defer configuredTransportsMu.Unlock()
+
+	t, ok := http.DefaultTransport.(*http.Transport)
+	if !ok {
+		return nil
+	}
+
+	changed, err := update(t)
+	if changed {
+		defaultTransportVersion++
+	}
+	return err
+}
+
+// configuredTransport returns a transport with the timeout, HTTP version,
+// connection pool and TLS settings applied, along with a key identifying
+// them. Transports are created once for each combination of settings and
+// never modified afterward, since other requests may be using them at the
+// same time. If the default HTTP transport has been replaced, e.g. by a mock
+// in tests, it is used as-is and a nil transport is returned.
+func configuredTransport(config *APIConfig) (http.RoundTripper, string, error) {
+	base, ok := http.DefaultTransport.(*http.Transport)
+	if !ok {
+		return nil, "default", nil
+	}
+
+	settings := currentTransportSettings(config)
+
+	configuredTransportsMu.Lock()
+	defer configuredTransportsMu.Unlock()
+
+	key := fmt.Sprintf("%p:%d %+v", base, defaultTransportVersion, settings)
+
+	if t, ok := configuredTransports[key]; ok {
+		return t, key, nil
+	}
+
+	LogDebug("Adding TLS configuration")
+	t := base.Clone()
+
+	if err := applyTransportTimeouts(t); err != nil {
+		return nil, "", err
+	}
+
+	if err := applyHTTPVersion(t); err != nil {
+		return nil, "", err
+	}
+
+	if err := applyConnectionPool(t); err != nil {
+		return nil, "", err
+	}
+
+	if err := applyTLSConfig(t, settings.tls); err != nil {
+		return nil, "", err
+	}
+
+	configuredTransports[key] = t
+	return t, key, nil
+}
+
+// applyTLSConfig sets up client certificates, custom CAs and insecure mode.
+func applyTLSConfig(t *http.Transport, config TLSConfig) error {
+	if t.TLSClientConfig == nil {
+		t.TLSClientConfig = &tls.Config{}
+	}
+
+	if config.InsecureSkipVerify {
+		LogWarning("Disabling TLS security checks")
+		t.TLSClientConfig.InsecureSkipVerify = config.InsecureSkipVerify
+	}
+	if config.Cert != "" {
+		cert, err := tls.LoadX509KeyPair(config.Cert, config.Key)
+		if err != nil {
+			return err
+		}
+		t.TLSClientConfig.Certificates = append(t.TLSClientConfig.Certificates, cert)
+	}
+	if config.CACert != "" {
+		caCert, err := ioutil.ReadFile(config.CACert)
+		if err != nil {
+			return err
+		}
+		systemCerts := BestEffortSystemCertPool()
+		if !systemCerts.AppendCertsFromPEM(caCert) {
+			return fmt.Errorf("Failed to append CACert %s RootCA list", config.CACert)
+		}
+		t.TLSClientConfig.RootCAs = systemCerts
+	}
+
+	return nil
+}
+
+// defaultClient returns the client for requests to the API. Clients are
+// shared between requests, e.g. for each page, so that open connections are
+// reused rather than paying for a new TLS handshake.
+func defaultClient(config *APIConfig) (*http.Client, error) {
+	transport, key, err := configuredTransport(config)
+	if err != nil {
+		return nil, err
+	}
+
+	ttl, err := cacheTTL(config)
+	if err != nil {
+		return nil, err
+	}
+
+	if viper.GetBool("rsh-no-cache") {
+		return sharedClient("no-cache "+key, func() *http.Client {
+			return &http.Client{Transport: &invalidateCachedTransport{
+				transport: cachedTransport(transport),
+			}}
+		}), nil
+	}
+
+	if ttl > 0 {
+		return sharedClient("ttl:"+ttl.String()+" "+key, func() *http.Client {
+			return ttlCachedTransport(ttl, transport).Client()
+		}), nil
+	}
+
+	return sharedClient("cache "+key, func() *http.Client {
+		return cachedTransport(transport).Client()
+	}), nil
+}
@@ -1,11 +1,26 @@
 package cli
 
 import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net"
 	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path"
+	"strings"
+	"sync/atomic"
 	"testing"
 	"time"
 
+	"github.com/spf13/viper"
 	"github.com/stretchr/testify/assert"
+	"golang.org/x/net/http2"
+	"golang.org/x/net/http2/h2c"
 	"gopkg.in/h2non/gock.v1"
 )
 
@@ -61,3 +76,256 @@ func TestMinCachedTransport(t *testing.T) {
 	assert.Equal(t, resp.StatusCode, 400)
 	assert.Equal(t, resp.Header.Get("cache-control"), "")
 }
+
+func TestCachedTransportRevalidates(t *testing.T) {
+	reset(false)
+
+	conditional := []string{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conditional = append(conditional, r.Header.Get("If-None-Match"))
+		if r.Header.Get("If-None-Match") == `"v1"` {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+
+		w.Header().Set("ETag", `"v1"`)
+		w.Header().Set("Content-Type", "text/plain")
+		w.Write([]byte("hello"))
+	}))
+	defer server.Close()
+
+	captured := &strings.Builder{}
+	Stderr = captured
+	enableVerbose = true
+	defer func() { enableVerbose = false }()
+
+	for i := 0; i < 2; i++ {
+		req, _ := http.NewRequest(http.MethodGet, server.URL+"/etag", nil)
+		resp, err := GetParsedResponse(req)
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusOK, resp.Status)
+		assert.Equal(t, "hello", resp.Body)
+	}
+
+	// The second request sends the stored validator and the 304 is served
+	// from the cache.
+	assert.Equal(t, []string{"", `"v1"`}, conditional)
+	assert.Contains(t, captured.String(), `Server confirmed ETag "v1" is not modified`)
+}
+
+func TestCacheTTL(t *testing.T) {
+	reset(false)
+
+	ttl, err := cacheTTL(&APIConfig{CacheTTL: "10m"})
+	assert.NoError(t, err)
+	assert.Equal(t, 10*time.Minute, ttl)
+
+	// The flag takes precedence over the API config.
+	viper.Set("rsh-cache-ttl", "1h")
+	defer viper.Set("rsh-cache-ttl", "")
+	ttl, err = cacheTTL(&APIConfig{CacheTTL: "10m"})
+	assert.NoError(t, err)
+	assert.Equal(t, time.Hour, ttl)
+
+	hits := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits++
+		w.Header().Set("Cache-Control", "no-cache")
+		w.Header().Set("Content-Type", "text/plain")
+		w.Write([]byte("reference data"))
+	}))
+	defer server.Close()
+
+	for i := 0; i < 2; i++ {
+		req, _ := http.NewRequest(http.MethodGet, server.URL+"/ttl", nil)
+		resp, err := GetParsedResponse(req)
+		assert.NoError(t, err)
+		assert.Equal(t, "reference data", resp.Body)
+	}
+
+	// The second response came from the cache.
+	assert.Equal(t, 1, hits)
+
+	viper.Set("rsh-cache-ttl", "nope")
+	req, _ := http.NewRequest(http.MethodGet, server.URL+"/ttl", nil)
+	_, err = GetParsedResponse(req)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "invalid cache TTL nope")
+}
+
+func TestHTTPVersion(t *testing.T) {
+	reset(false)
+
+	server := httptest.NewServer(h2c.NewHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, r.Proto)
+	}), &http2.Server{}))
+	defer server.Close()
+
+	viper.Set("rsh-no-cache", true)
+	defer viper.Set("rsh-no-cache", false)
+
+	req, _ := http.NewRequest(http.MethodGet, server.URL, nil)
+	resp, err := GetParsedResponse(req)
+	assert.NoError(t, err)
+	assert.Equal(t, "HTTP/1.1", resp.Body)
+
+	viper.Set("rsh-http-version", "2")
+	defer viper.Set("rsh-http-version", "auto")
+
+	req, _ = http.NewRequest(http.MethodGet, server.URL, nil)
+	resp, err = GetParsedResponse(req)
+	assert.NoError(t, err)
+	assert.Equal(t, "HTTP/2.0", resp.Body)
+	assert.Equal(t, "HTTP/2.0", resp.Proto)
+
+	viper.Set("rsh-http-version", "3")
+	_, err = GetParsedResponse(req)
+	assert.EqualError(t, err, "invalid HTTP version 3, expected one of auto, 1.1, 2")
+}
+
+func TestHTTPVersionDisableHTTP2(t *testing.T) {
+	viper.Set("rsh-http-version", "1.1")
+	defer viper.Set("rsh-http-version", "auto")
+
+	transport := &http.Transport{
+		ForceAttemptHTTP2: true,
+		TLSClientConfig:   &tls.Config{NextProtos: []string{"h2", "http/1.1"}},
+	}
+	assert.NoError(t, applyHTTPVersion(transport))
+	assert.False(t, transport.ForceAttemptHTTP2)
+	assert.NotNil(t, transport.TLSNextProto)
+	assert.Empty(t, transport.TLSNextProto)
+	assert.Equal(t, []string{"http/1.1"}, transport.TLSClientConfig.NextProtos)
+}
+
+func TestH2CDialUsesRequestContext(t *testing.T) {
+	viper.Set("rsh-http-version", "2")
+	defer viper.Set("rsh-http-version", "auto")
+
+	transport := &http.Transport{
+		DialContext: func(ctx context.Context, network, addr string) (net.Conn, error) {
+			// Never connects, so only cancelling the request ends the dial.
+			<-ctx.Done()
+			return nil, ctx.Err()
+		},
+	}
+	assert.NoError(t, applyHTTPVersion(transport))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, "http://example.com/", nil)
+	_, err := transport.RoundTrip(req)
+	assert.True(t, errors.Is(err, context.DeadlineExceeded))
+}
+
+func TestPaginationReusesConnections(t *testing.T) {
+	reset(false)
+
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		page := r.URL.Query().Get("page")
+		switch page {
+		case "":
+			w.Header().Set("Link", `</items?page=2>; rel="next"`)
+		case "2":
+			w.Header().Set("Link", `</items?page=3>; rel="next"`)
+		}
+		fmt.Fprintf(w, `["item%s"]`, page)
+	}))
+
+	var connections int32
+	server.Config.ConnState = func(c net.Conn, state http.ConnState) {
+		if state == http.StateNew {
+			atomic.AddInt32(&connections, 1)
+		}
+	}
+	server.Start()
+	defer server.Close()
+
+	viper.Set("rsh-no-cache", true)
+	defer viper.Set("rsh-no-cache", false)
+
+	req, _ := http.NewRequest(http.MethodGet, server.URL+"/items", nil)
+	resp, err := GetParsedResponse(req)
+	assert.NoError(t, err)
+	assert.Equal(t, []interface{}{"item", "item2", "item3"}, resp.Body)
+	assert.Equal(t, int32(1), atomic.LoadInt32(&connections))
+}
+
+func TestConfiguredTransport(t *testing.T) {
+	reset(false)
+
+	first, _, err := configuredTransport(&APIConfig{})
+	assert.NoError(t, err)
+
+	second, _, err := configuredTransport(&APIConfig{})
+	assert.NoError(t, err)
+	assert.Same(t, first, second)
+
+	// Changed settings get their own transport, leaving the shared default
+	// transport and those already in use untouched.
+	viper.Set("rsh-max-idle-conns", 2)
+	defer viper.Set("rsh-max-idle-conns", 0)
+
+	third, _, err := configuredTransport(&APIConfig{})
+	assert.NoError(t, err)
+	assert.NotSame(t, first, third)
+	assert.Equal(t, 2, third.(*http.Transport).MaxIdleConnsPerHost)
+	assert.Equal(t, defaultMaxIdleConns, first.(*http.Transport).MaxIdleConnsPerHost)
+	assert.NotSame(t, http.DefaultTransport, first)
+}
+
+func TestConnectionPool(t *testing.T) {
+	transport := &http.Transport{MaxIdleConns: 4, DisableKeepAlives: true}
+
+	viper.Set("rsh-max-idle-conns", 0)
+	assert.NoError(t, applyConnectionPool(transport))
+	assert.False(t, transport.DisableKeepAlives)
+	assert.Equal(t, defaultMaxIdleConns, transport.MaxIdleConnsPerHost)
+	assert.Equal(t, defaultMaxIdleConns, transport.MaxIdleConns)
+
+	viper.Set("rsh-max-idle-conns", 2)
+	defer viper.Set("rsh-max-idle-conns", 0)
+	assert.NoError(t, applyConnectionPool(transport))
+	assert.Equal(t, 2, transport.MaxIdleConnsPerHost)
+
+	viper.Set("rsh-max-idle-conns", -1)
+	assert.EqualError(t, applyConnectionPool(transport), "invalid max idle connections -1")
+}
+
+func TestSplitUnixSocketURL(t *testing.T) {
+	u, _ := url.Parse("http+unix:///var/run/docker.sock:/v1.41/containers/json?all=1")
+	socket, httpURL, err := splitUnixSocketURL(u)
+	assert.NoError(t, err)
+	assert.Equal(t, "/var/run/docker.sock", socket)
+	assert.Equal(t, "http://localhost/v1.41/containers/json?all=1", httpURL.String())
+
+	u, _ = url.Parse("http+unix:///var/run/docker.sock")
+	_, _, err = splitUnixSocketURL(u)
+	assert.Error(t, err)
+}
+
+func TestUnixSocket(t *testing.T) {
+	dir, err := ioutil.TempDir("", "restish-unix")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	socket := path.Join(dir, "api.sock")
+	listener, err := net.Listen("unix", socket)
+	assert.NoError(t, err)
+
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"path": "` + r.URL.Path + `"}`))
+	}))
+	server.Listener = listener
+	server.Start()
+	defer server.Close()
+
+	captured := run("http+unix://" + socket + ":/v1/info -f body.path -r")
+	assert.Equal(t, "/v1/info\n", captured)
+
+	captured = run("localhost/v1/flag --rsh-unix-socket " + socket + " -f body.path -r")
+	assert.Equal(t, "/v1/flag\n", captured)
+}
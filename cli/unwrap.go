@@ -0,0 +1,102 @@
+package cli
+
+import (
+	"strings"
+
+	jmespath "github.com/danielgtaylor/go-jmespath-plus"
+)
+
+// parseEmbeddedJSON returns the decoded value if the string contains a JSON
+// object or array, e.g. a stringified event payload.
+func parseEmbeddedJSON(s string) (interface{}, bool) {
+	trimmed := strings.TrimSpace(s)
+	if len(trimmed) < 2 {
+		return nil, false
+	}
+
+	if !(trimmed[0] == '{' && trimmed[len(trimmed)-1] == '}') && !(trimmed[0] == '[' && trimmed[len(trimmed)-1] == ']') {
+		return nil, false
+	}
+
+	var decoded interface{}
+	if err := (JSON{}).Unmarshal([]byte(trimmed), &decoded); err != nil {
+		return nil, false
+	}
+
+	return decoded, true
+}
+
+// unwrapJSON walks the value and replaces strings containing JSON objects or
+// arrays with their decoded value. If `match` is non-nil, only strings for
+// which it returns true are replaced. Decoded values are walked again in case
+// they were encoded more than once.
+func unwrapJSON(v interface{}, match func(string) bool) interface{} {
+	switch t := v.(type) {
+	case map[string]interface{}:
+		for k, item := range t {
+			t[k] = unwrapJSON(item, match)
+		}
+	case map[interface{}]interface{}:
+		for k, item := range t {
+			t[k] = unwrapJSON(item, match)
+		}
+	case []interface{}:
+		for i, item := range t {
+			t[i] = unwrapJSON(item, match)
+		}
+	case string:
+		if match != nil && !match(t) {
+			return t
+		}
+
+		if decoded, ok := parseEmbeddedJSON(t); ok {
+			return unwrapJSON(decoded, nil)
+		}
+	}
+
+	return v
+}
+
+// UnwrapResponseJSON decodes JSON which has been encoded as a string value
+// within the response body. When `auto` is set, all such strings are
+// decoded. Otherwise, the JMESPath `expr` is run against the response and any
+// string values it selects are decoded.
+func UnwrapResponseJSON(resp *Response, expr string, auto bool) error {
+	if auto {
+		resp.Body = unwrapJSON(resp.Body, nil)
+		return nil
+	}
+
+	if expr == "" {
+		return nil
+	}
+
+	result, err := jmespath.Search(expr, makeJSONSafe(resp.Map()))
+	if err != nil {
+		return err
+	}
+
+	selected := map[string]bool{}
+	var collect func(interface{})
+	collect = func(v interface{}) {
+		switch t := v.(type) {
+		case string:
+			selected[t] = true
+		case []interface{}:
+			for _, item := range t {
+				collect(item)
+			}
+		}
+	}
+	collect(result)
+
+	if len(selected) == 0 {
+		return nil
+	}
+
+	resp.Body = unwrapJSON(resp.Body, func(s string) bool {
+		return selected[s]
+	})
+
+	return nil
+}
@@ -0,0 +1,55 @@
+package cli
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func unwrapTestResponse() Response {
+	return Response{
+		Status:  200,
+		Headers: map[string]string{},
+		Body: map[string]interface{}{
+			"message": "{not json",
+			"events": []interface{}{
+				map[string]interface{}{
+					"payload": `{"id": 1, "nested": "[1, 2]"}`,
+				},
+			},
+			"meta": `{"page": 1}`,
+		},
+	}
+}
+
+func TestUnwrapJSONAuto(t *testing.T) {
+	resp := unwrapTestResponse()
+	assert.NoError(t, UnwrapResponseJSON(&resp, "", true))
+
+	assert.Equal(t, map[string]interface{}{
+		"message": "{not json",
+		"events": []interface{}{
+			map[string]interface{}{
+				"payload": map[string]interface{}{
+					"id":     1.0,
+					"nested": []interface{}{1.0, 2.0},
+				},
+			},
+		},
+		"meta": map[string]interface{}{
+			"page": 1.0,
+		},
+	}, resp.Body)
+}
+
+func TestUnwrapJSONExpression(t *testing.T) {
+	resp := unwrapTestResponse()
+	assert.NoError(t, UnwrapResponseJSON(&resp, "body.events[].payload", false))
+
+	body := resp.Body.(map[string]interface{})
+	assert.Equal(t, `{"page": 1}`, body["meta"])
+	assert.Equal(t, map[string]interface{}{
+		"id":     1.0,
+		"nested": []interface{}{1.0, 2.0},
+	}, body["events"].([]interface{})[0].(map[string]interface{})["payload"])
+}
@@ -0,0 +1,300 @@
+package cli
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// releaseURL returns the latest published release and can be overridden in
+// tests.
+var releaseURL = "https://api.github.com/repos/danielgtaylor/restish/releases/latest"
+
+// executablePath returns the path of the running binary and can be
+// overridden in tests.
+var executablePath = os.Executable
+
+// release describes a published release and its downloadable files.
+type release struct {
+	TagName string `json:"tag_name"`
+	Assets  []struct {
+		Name string `json:"name"`
+		URL  string `json:"browser_download_url"`
+	} `json:"assets"`
+}
+
+// assetURL returns the download URL of the named asset.
+func (r release) assetURL(name string) (string, error) {
+	for _, a := range r.Assets {
+		if a.Name == name {
+			return a.URL, nil
+		}
+	}
+	return "", fmt.Errorf("release %s has no %s", r.TagName, name)
+}
+
+// compareVersions compares two versions like `v1.2.3`, returning -1, 0, or 1.
+// Pre-release and build suffixes are ignored.
+func compareVersions(a, b string) int {
+	parse := func(v string) []int {
+		v = strings.TrimPrefix(v, "v")
+		if i := strings.IndexAny(v, "-+"); i != -1 {
+			v = v[:i]
+		}
+
+		parts := []int{}
+		for _, p := range strings.Split(v, ".") {
+			n, _ := strconv.Atoi(p)
+			parts = append(parts, n)
+		}
+		return parts
+	}
+
+	pa, pb := parse(a), parse(b)
+	for i := 0; i < len(pa) || i < len(pb); i++ {
+		var x, y int
+		if i < len(pa) {
+			x = pa[i]
+		}
+		if i < len(pb) {
+			y = pb[i]
+		}
+		if x != y {
+			if x < y {
+				return -1
+			}
+			return 1
+		}
+	}
+
+	return 0
+}
+
+// releaseAssetName returns the archive name for the given platform, matching
+// the release build configuration.
+func releaseAssetName(name, version, goos, goarch string) string {
+	switch goos {
+	case "darwin":
+		goos = "mac"
+	}
+
+	switch goarch {
+	case "amd64":
+		goarch = "x86_64"
+	case "386":
+		goarch = "i386"
+	}
+
+	ext := "tar.gz"
+	if goos == "windows" {
+		ext = "zip"
+	}
+
+	return fmt.Sprintf("%s-%s-%s-%s.%s", name, strings.TrimPrefix(version, "v"), goos, goarch, ext)
+}
+
+// download fetches a URL into memory.
+func download(client *http.Client, url string) ([]byte, error) {
+	resp, err := client.Get(url)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("cannot download %s: %s", url, resp.Status)
+	}
+
+	return ioutil.ReadAll(resp.Body)
+}
+
+// verifyChecksum checks the data against its entry in a `checksums.txt` file
+// containing lines of `<sha256> <filename>`.
+func verifyChecksum(checksums []byte, filename string, data []byte) error {
+	sum := sha256.Sum256(data)
+	actual := hex.EncodeToString(sum[:])
+
+	scanner := bufio.NewScanner(bytes.NewReader(checksums))
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 2 && fields[1] == filename {
+			if !strings.EqualFold(fields[0], actual) {
+				return fmt.Errorf("checksum mismatch for %s: expected %s, got %s", filename, fields[0], actual)
+			}
+			return nil
+		}
+	}
+
+	return fmt.Errorf("no checksum found for %s", filename)
+}
+
+// extractBinary returns the named binary from a `.tar.gz` or `.zip` archive.
+func extractBinary(archive []byte, archiveName, binary string) ([]byte, error) {
+	if strings.HasSuffix(archiveName, ".zip") {
+		r, err := zip.NewReader(bytes.NewReader(archive), int64(len(archive)))
+		if err != nil {
+			return nil, err
+		}
+
+		for _, f := range r.File {
+			if filepath.Base(f.Name) == binary {
+				rc, err := f.Open()
+				if err != nil {
+					return nil, err
+				}
+				defer rc.Close()
+				return ioutil.ReadAll(rc)
+			}
+		}
+	} else {
+		gz, err := gzip.NewReader(bytes.NewReader(archive))
+		if err != nil {
+			return nil, err
+		}
+
+		tr := tar.NewReader(gz)
+		for {
+			h, err := tr.Next()
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				return nil, err
+			}
+
+			if h.Typeflag == tar.TypeReg && filepath.Base(h.Name) == binary {
+				return ioutil.ReadAll(tr)
+			}
+		}
+	}
+
+	return nil, fmt.Errorf("%s not found in %s", binary, archiveName)
+}
+
+// replaceExecutable atomically replaces the running binary with the new one
+// by writing it next to the original and renaming it into place.
+func replaceExecutable(data []byte) error {
+	exe, err := executablePath()
+	if err != nil {
+		return err
+	}
+
+	if resolved, err := filepath.EvalSymlinks(exe); err == nil {
+		exe = resolved
+	}
+
+	info, err := os.Stat(exe)
+	if err != nil {
+		return err
+	}
+
+	tmp := exe + ".new"
+	if err := ioutil.WriteFile(tmp, data, info.Mode()); err != nil {
+		return err
+	}
+
+	if runtime.GOOS == "windows" {
+		// A running binary can't be replaced on Windows, but it can be moved.
+		old := exe + ".old"
+		os.Remove(old)
+		if err := os.Rename(exe, old); err != nil {
+			os.Remove(tmp)
+			return err
+		}
+	}
+
+	if err := os.Rename(tmp, exe); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+
+	return nil
+}
+
+// selfUpdate checks for a newer release than the current version and, unless
+// `checkOnly` is set, downloads, verifies and installs it.
+func selfUpdate(name, current string, checkOnly bool) error {
+	client := &http.Client{Timeout: 5 * time.Minute}
+
+	data, err := download(client, releaseURL)
+	if err != nil {
+		return err
+	}
+
+	var latest release
+	if err := json.Unmarshal(data, &latest); err != nil {
+		return fmt.Errorf("cannot parse release info: %w", err)
+	}
+
+	if current != "dev" && compareVersions(current, latest.TagName) >= 0 {
+		fmt.Fprintf(Stdout, "%s %s is up to date\n", name, current)
+		return nil
+	}
+
+	fmt.Fprintf(Stdout, "Update available: %s -> %s\n", current, latest.TagName)
+	if checkOnly {
+		return nil
+	}
+
+	if current == "dev" {
+		return fmt.Errorf("refusing to replace a development build, install a release first")
+	}
+
+	assetName := releaseAssetName(name, latest.TagName, runtime.GOOS, runtime.GOARCH)
+	assetURL, err := latest.assetURL(assetName)
+	if err != nil {
+		return err
+	}
+
+	checksumsURL, err := latest.assetURL("checksums.txt")
+	if err != nil {
+		return err
+	}
+
+	checksums, err := download(client, checksumsURL)
+	if err != nil {
+		return err
+	}
+
+	LogInfo("Downloading %s", assetName)
+	archive, err := download(client, assetURL)
+	if err != nil {
+		return err
+	}
+
+	if err := verifyChecksum(checksums, assetName, archive); err != nil {
+		return err
+	}
+
+	binary := name
+	if runtime.GOOS == "windows" {
+		binary += ".exe"
+	}
+
+	exe, err := extractBinary(archive, assetName, binary)
+	if err != nil {
+		return err
+	}
+
+	if err := replaceExecutable(exe); err != nil {
+		return fmt.Errorf("cannot replace binary: %w", err)
+	}
+
+	fmt.Fprintf(Stdout, "Updated to %s\n", latest.TagName)
+	return nil
+}
@@ -0,0 +1,129 @@
+package cli
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCompareVersions(t *testing.T) {
+	assert.Equal(t, 0, compareVersions("v1.2.3", "1.2.3"))
+	assert.Equal(t, -1, compareVersions("0.9.0", "v0.10.0"))
+	assert.Equal(t, 1, compareVersions("1.0.1", "1.0"))
+	assert.Equal(t, 0, compareVersions("1.0.0-rc1", "1.0.0"))
+}
+
+func TestReleaseAssetName(t *testing.T) {
+	assert.Equal(t, "restish-0.5.0-mac-x86_64.tar.gz", releaseAssetName("restish", "v0.5.0", "darwin", "amd64"))
+	assert.Equal(t, "restish-0.5.0-windows-i386.zip", releaseAssetName("restish", "v0.5.0", "windows", "386"))
+	assert.Equal(t, "restish-0.5.0-linux-arm64.tar.gz", releaseAssetName("restish", "0.5.0", "linux", "arm64"))
+}
+
+func testReleaseServer(t *testing.T, binary []byte, badChecksum bool) *httptest.Server {
+	buf := &bytes.Buffer{}
+	gz := gzip.NewWriter(buf)
+	tw := tar.NewWriter(gz)
+	assert.NoError(t, tw.WriteHeader(&tar.Header{Name: "restish", Mode: 0755, Size: int64(len(binary)), Typeflag: tar.TypeReg}))
+	tw.Write(binary)
+	tw.Close()
+	gz.Close()
+	archive := buf.Bytes()
+
+	asset := releaseAssetName("restish", "v1.1.0", runtime.GOOS, runtime.GOARCH)
+	sum := sha256.Sum256(archive)
+	checksum := hex.EncodeToString(sum[:])
+	if badChecksum {
+		checksum = strings.Repeat("0", 64)
+	}
+
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/latest":
+			fmt.Fprintf(w, `{"tag_name": "v1.1.0", "assets": [{"name": %q, "browser_download_url": "%s/asset"}, {"name": "checksums.txt", "browser_download_url": "%s/checksums"}]}`, asset, server.URL, server.URL)
+		case "/asset":
+			w.Write(archive)
+		case "/checksums":
+			fmt.Fprintf(w, "%s  %s\n", checksum, asset)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+
+	return server
+}
+
+func withTestExecutable(t *testing.T, server *httptest.Server) (string, func()) {
+	dir, err := ioutil.TempDir("", "restish-update")
+	assert.NoError(t, err)
+
+	exe := filepath.Join(dir, "restish")
+	assert.NoError(t, ioutil.WriteFile(exe, []byte("old"), 0755))
+
+	origURL, origExe := releaseURL, executablePath
+	releaseURL = server.URL + "/latest"
+	executablePath = func() (string, error) { return exe, nil }
+
+	return exe, func() {
+		releaseURL, executablePath = origURL, origExe
+		os.RemoveAll(dir)
+	}
+}
+
+func TestSelfUpdate(t *testing.T) {
+	reset(false)
+
+	server := testReleaseServer(t, []byte("new"), false)
+	defer server.Close()
+	exe, cleanup := withTestExecutable(t, server)
+	defer cleanup()
+
+	captured := &strings.Builder{}
+	Stdout = captured
+
+	// Up to date does nothing.
+	assert.NoError(t, selfUpdate("restish", "v1.1.0", false))
+	assert.Contains(t, captured.String(), "restish v1.1.0 is up to date")
+
+	// Check only reports the update without installing it.
+	assert.NoError(t, selfUpdate("restish", "v1.0.0", true))
+	assert.Contains(t, captured.String(), "Update available: v1.0.0 -> v1.1.0")
+	data, _ := ioutil.ReadFile(exe)
+	assert.Equal(t, "old", string(data))
+
+	assert.NoError(t, selfUpdate("restish", "v1.0.0", false))
+	data, _ = ioutil.ReadFile(exe)
+	assert.Equal(t, "new", string(data))
+	assert.Contains(t, captured.String(), "Updated to v1.1.0")
+}
+
+func TestSelfUpdateBadChecksum(t *testing.T) {
+	reset(false)
+
+	server := testReleaseServer(t, []byte("new"), true)
+	defer server.Close()
+	exe, cleanup := withTestExecutable(t, server)
+	defer cleanup()
+
+	Stdout = &strings.Builder{}
+
+	err := selfUpdate("restish", "v1.0.0", false)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "checksum mismatch")
+
+	data, _ := ioutil.ReadFile(exe)
+	assert.Equal(t, "old", string(data))
+}
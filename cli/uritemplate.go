@@ -0,0 +1,140 @@
+package cli
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// uriTemplateExpr matches a single RFC 6570 template expression like `{id}`.
+var uriTemplateExpr = regexp.MustCompile(`\{[^{}]*\}`)
+
+// uriTemplateOp describes how an RFC 6570 expression operator is expanded.
+type uriTemplateOp struct {
+	first    string
+	sep      string
+	named    bool
+	ifEmpty  string
+	reserved bool
+}
+
+var uriTemplateOps = map[byte]uriTemplateOp{
+	'+': {first: "", sep: ",", reserved: true},
+	'#': {first: "#", sep: ",", reserved: true},
+	'.': {first: ".", sep: "."},
+	'/': {first: "/", sep: "/"},
+	';': {first: ";", sep: ";", named: true},
+	'?': {first: "?", sep: "&", named: true, ifEmpty: "="},
+	'&': {first: "&", sep: "&", named: true, ifEmpty: "="},
+}
+
+// IsURITemplate returns whether the URI contains RFC 6570 template
+// expressions.
+func IsURITemplate(uri string) bool {
+	return uriTemplateExpr.MatchString(uri)
+}
+
+// uriTemplateEscape percent-encodes a value. Reserved characters and existing
+// percent-encoded triplets are kept as-is if `reserved` is set.
+func uriTemplateEscape(value string, reserved bool) string {
+	sb := strings.Builder{}
+
+	for i := 0; i < len(value); i++ {
+		c := value[i]
+
+		if (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || (c >= '0' && c <= '9') || strings.IndexByte("-._~", c) != -1 {
+			sb.WriteByte(c)
+			continue
+		}
+
+		if reserved {
+			if strings.IndexByte(":/?#[]@!$&'()*+,;=", c) != -1 {
+				sb.WriteByte(c)
+				continue
+			}
+
+			if c == '%' && i+2 < len(value) && isHex(value[i+1]) && isHex(value[i+2]) {
+				sb.WriteString(value[i : i+3])
+				i += 2
+				continue
+			}
+		}
+
+		fmt.Fprintf(&sb, "%%%02X", c)
+	}
+
+	return sb.String()
+}
+
+func isHex(c byte) bool {
+	return (c >= '0' && c <= '9') || (c >= 'a' && c <= 'f') || (c >= 'A' && c <= 'F')
+}
+
+// ExpandURITemplate expands an RFC 6570 URI template using the given variable
+// values. All operators and the prefix modifier are supported. Variables
+// without a value are left out of the expansion as described in the RFC.
+func ExpandURITemplate(template string, values map[string]string) (string, error) {
+	var expandErr error
+
+	expanded := uriTemplateExpr.ReplaceAllStringFunc(template, func(expr string) string {
+		body := expr[1 : len(expr)-1]
+		if body == "" {
+			expandErr = fmt.Errorf("empty expression in URI template %s", template)
+			return expr
+		}
+
+		op := uriTemplateOp{sep: ","}
+		if o, ok := uriTemplateOps[body[0]]; ok {
+			op = o
+			body = body[1:]
+		}
+
+		parts := []string{}
+		for _, spec := range strings.Split(body, ",") {
+			// Lists and maps are not supported, so explode is a no-op for strings.
+			name := strings.TrimSuffix(spec, "*")
+
+			prefix := -1
+			if i := strings.Index(name, ":"); i != -1 {
+				n, err := strconv.Atoi(name[i+1:])
+				if err != nil || n <= 0 || n >= 10000 {
+					expandErr = fmt.Errorf("invalid prefix in URI template expression %s", expr)
+					return expr
+				}
+				prefix = n
+				name = name[:i]
+			}
+
+			value, ok := values[name]
+			if !ok {
+				continue
+			}
+
+			if prefix != -1 {
+				if runes := []rune(value); len(runes) > prefix {
+					value = string(runes[:prefix])
+				}
+			}
+
+			part := uriTemplateEscape(value, op.reserved)
+			if op.named {
+				if value == "" {
+					part = name + op.ifEmpty
+				} else {
+					part = name + "=" + part
+				}
+			}
+
+			parts = append(parts, part)
+		}
+
+		if len(parts) == 0 {
+			return ""
+		}
+
+		return op.first + strings.Join(parts, op.sep)
+	})
+
+	return expanded, expandErr
+}
@@ -0,0 +1,46 @@
+package cli
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExpandURITemplate(t *testing.T) {
+	// Examples from RFC 6570 section 3.2.
+	values := map[string]string{
+		"var":   "value",
+		"hello": "Hello World!",
+		"path":  "/foo/bar",
+		"x":     "1024",
+		"y":     "768",
+		"empty": "",
+	}
+
+	cases := map[string]string{
+		"{var}":                "value",
+		"{hello}":              "Hello%20World%21",
+		"{+hello}":             "Hello%20World!",
+		"{+path}/here":         "/foo/bar/here",
+		"{#path,x}/here":       "#/foo/bar,1024/here",
+		"map?{x,y}":            "map?1024,768",
+		"X{.var}":              "X.value",
+		"{/var,x}/here":        "/value/1024/here",
+		"{;x,y,empty}":         ";x=1024;y=768;empty",
+		"{?x,y,empty}":         "?x=1024&y=768&empty=",
+		"?fixed=yes{&x}":       "?fixed=yes&x=1024",
+		"{var:3}":              "val",
+		"/users{/undef}{?q}":   "/users",
+		"{+path:6}/here":       "/foo/b/here",
+		"/items/{var}/{x}{?y}": "/items/value/1024?y=768",
+	}
+
+	for template, expected := range cases {
+		expanded, err := ExpandURITemplate(template, values)
+		assert.NoError(t, err, template)
+		assert.Equal(t, expected, expanded, template)
+	}
+
+	_, err := ExpandURITemplate("{var:abc}", values)
+	assert.Error(t, err)
+}
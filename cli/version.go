@@ -0,0 +1,80 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"runtime"
+	"runtime/debug"
+
+	"github.com/spf13/viper"
+)
+
+// BuildCommit is the source control revision the executable was built from.
+// It is typically set via linker flags by the release process.
+var BuildCommit string
+
+// BuildDate is when the executable was built, typically set via linker flags
+// by the release process.
+var BuildDate string
+
+// buildInfo returns metadata about the running executable for bug reports.
+func buildInfo(name, version string) map[string]interface{} {
+	info := map[string]interface{}{
+		"name":    name,
+		"version": version,
+		"go":      runtime.Version(),
+		"os":      runtime.GOOS,
+		"arch":    runtime.GOARCH,
+	}
+
+	if bi, ok := debug.ReadBuildInfo(); ok {
+		info["module"] = bi.Main.Path
+		if (version == "" || version == "dev") && bi.Main.Version != "" && bi.Main.Version != "(devel)" {
+			// Installed via `go get`, which records the module version.
+			info["version"] = bi.Main.Version
+		}
+	}
+
+	if BuildCommit != "" {
+		info["commit"] = BuildCommit
+	}
+
+	if BuildDate != "" {
+		info["date"] = BuildDate
+	}
+
+	return info
+}
+
+// showVersion prints the build metadata, as JSON with `-o json`.
+func showVersion(name, version string) error {
+	info := buildInfo(name, version)
+
+	if viper.GetString("rsh-output-format") == "json" {
+		encoded, err := json.MarshalIndent(info, "", "  ")
+		if err != nil {
+			return err
+		}
+
+		if tty {
+			if encoded, err = Highlight("json", encoded); err != nil {
+				return err
+			}
+		}
+
+		fmt.Fprintln(Stdout, string(encoded))
+		return nil
+	}
+
+	fmt.Fprintf(Stdout, "%s %s\n", info["name"], info["version"])
+	if commit, ok := info["commit"]; ok {
+		fmt.Fprintf(Stdout, "Commit: %s\n", commit)
+	}
+	if date, ok := info["date"]; ok {
+		fmt.Fprintf(Stdout, "Built: %s\n", date)
+	}
+	fmt.Fprintf(Stdout, "Go: %s\n", info["go"])
+	fmt.Fprintf(Stdout, "OS/Arch: %s/%s\n", info["os"], info["arch"])
+
+	return nil
+}
@@ -0,0 +1,28 @@
+package cli
+
+import (
+	"encoding/json"
+	"runtime"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestVersionCommand(t *testing.T) {
+	BuildCommit = "abc123"
+	BuildDate = "2021-04-01T00:00:00Z"
+	defer func() {
+		BuildCommit = ""
+		BuildDate = ""
+	}()
+
+	out := run("version")
+	assert.Contains(t, out, "Commit: abc123")
+	assert.Contains(t, out, "OS/Arch: "+runtime.GOOS+"/"+runtime.GOARCH)
+
+	var info map[string]interface{}
+	assert.NoError(t, json.Unmarshal([]byte(run("version -o json")), &info))
+	assert.Equal(t, "abc123", info["commit"])
+	assert.Equal(t, "2021-04-01T00:00:00Z", info["date"])
+	assert.Equal(t, runtime.Version(), info["go"])
+}
@@ -0,0 +1,126 @@
+package cli
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+	"os/signal"
+	"strings"
+	"time"
+)
+
+// clearScreen moves the cursor to the top left and clears the terminal.
+const clearScreen = "\x1b[H\x1b[2J"
+
+// formatToString formats the response like it would be written to stdout.
+func formatToString(resp Response) (string, error) {
+	buf := &bytes.Buffer{}
+	orig := Stdout
+	Stdout = buf
+	defer func() { Stdout = orig }()
+
+	err := Formatter.Format(resp)
+	return buf.String(), err
+}
+
+// markChanges prefixes each line with a gutter which highlights the lines that
+// differ from the same line in the previous output.
+func markChanges(lines, previous []string) []string {
+	marked := make([]string, len(lines))
+	for i, line := range lines {
+		if i < len(previous) && previous[i] == line {
+			marked[i] = "  " + line
+		} else {
+			marked[i] = au.Index(222, "▌ ").String() + line
+		}
+	}
+	return marked
+}
+
+// watchPoll fetches the URI and returns the formatted output. Errors are
+// returned as output so that watching continues, like the Unix `watch`.
+func watchPoll(ctx context.Context, uri string) string {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, uri, nil)
+	if err != nil {
+		return err.Error()
+	}
+
+	resp, err := GetParsedResponse(req)
+	if err != nil {
+		return fmt.Sprintf("%s %v\n", au.BgIndex(204, "ERROR:").White().Bold(), err)
+	}
+
+	output, err := formatToString(resp)
+	if err != nil {
+		return fmt.Sprintf("%s %v\n", au.BgIndex(204, "ERROR:").White().Bold(), err)
+	}
+
+	return output
+}
+
+// watch polls the URI every interval and outputs the formatted response. On a
+// terminal the screen is redrawn in place with lines that changed since the
+// previous poll highlighted. Stops after count polls if it is positive, or
+// when interrupted.
+func watch(uri string, interval time.Duration, count int) error {
+	if interval <= 0 {
+		return fmt.Errorf("invalid interval %s", interval)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	interrupt := make(chan os.Signal, 1)
+	signal.Notify(interrupt, os.Interrupt)
+	defer signal.Stop(interrupt)
+
+	go func() {
+		select {
+		case <-interrupt:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	var previous []string
+	for i := 0; count <= 0 || i < count; i++ {
+		if i > 0 {
+			select {
+			case <-ctx.Done():
+				return nil
+			case <-time.After(interval):
+			}
+		}
+
+		output := watchPoll(ctx, uri)
+		if ctx.Err() != nil {
+			// Interrupted while waiting for a response.
+			return nil
+		}
+
+		header := fmt.Sprintf("Every %s: GET %s", interval, uri)
+		lines := strings.Split(strings.TrimSuffix(output, "\n"), "\n")
+
+		if tty {
+			fmt.Fprint(Stdout, clearScreen)
+			fmt.Fprintf(Stdout, "%s  %s\n\n", au.Bold(header), time.Now().Format(time.RFC1123))
+			display := lines
+			if previous != nil {
+				display = markChanges(lines, previous)
+			}
+			fmt.Fprintln(Stdout, strings.Join(display, "\n"))
+		} else {
+			if i > 0 {
+				fmt.Fprintln(Stdout)
+			}
+			fmt.Fprintf(Stdout, "%s  %s\n", header, time.Now().Format(time.RFC1123))
+			fmt.Fprintln(Stdout, strings.Join(lines, "\n"))
+		}
+
+		previous = lines
+	}
+
+	return nil
+}
@@ -0,0 +1,37 @@
+package cli
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/h2non/gock.v1"
+)
+
+func TestMarkChanges(t *testing.T) {
+	reset(false)
+
+	marked := markChanges([]string{"a", "b", "c"}, []string{"a", "x"})
+	assert.Equal(t, []string{"  a", "▌ b", "▌ c"}, marked)
+}
+
+func TestWatch(t *testing.T) {
+	defer gock.Off()
+
+	gock.New("http://example.com").Get("/job").Reply(200).JSON(map[string]interface{}{"status": "running"})
+	gock.New("http://example.com").Get("/job").Reply(200).JSON(map[string]interface{}{"status": "done"})
+
+	captured := run("watch http://example.com/job --interval 1ms --count 2 --rsh-no-cache -f body.status")
+
+	polls := strings.Split(captured, "\n\n")
+	assert.Len(t, polls, 2)
+	assert.Contains(t, polls[0], "Every 1ms: GET http://example.com/job")
+	assert.Contains(t, polls[0], `"running"`)
+	assert.Contains(t, polls[1], `"done"`)
+	assert.True(t, gock.IsDone())
+}
+
+func TestWatchInvalidInterval(t *testing.T) {
+	assert.Error(t, watch("http://example.com/job", -time.Second, 1))
+}
@@ -0,0 +1,212 @@
+package cli
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// XML describes content types like `application/xml`, `text/xml` or
+// `application/foo+xml`.
+//
+// Documents are decoded into the same generic structure as other formats so
+// they can be filtered and formatted. Each element becomes a key in its
+// parent's object, attributes become keys prefixed with `@`, and text mixed
+// with attributes or child elements is stored in a `#text` key. Elements
+// which contain only text become strings and repeated elements become arrays.
+type XML struct{}
+
+// Detect if the content type is XML.
+func (x XML) Detect(contentType string) bool {
+	first := strings.Split(contentType, ";")[0]
+	if first == "application/xml" || first == "text/xml" || strings.HasSuffix(first, "+xml") {
+		return true
+	}
+
+	return false
+}
+
+// Marshal the value to encoded XML. The value is expected to be an object
+// with a single key for the root element, like the output of `Unmarshal`.
+func (x XML) Marshal(value interface{}) ([]byte, error) {
+	name := "root"
+	if m, ok := makeJSONSafe(value).(map[string]interface{}); ok && len(m) == 1 {
+		for k, v := range m {
+			name, value = k, v
+		}
+	}
+
+	buf := &bytes.Buffer{}
+	enc := xml.NewEncoder(buf)
+	if err := encodeXMLElement(enc, name, makeJSONSafe(value)); err != nil {
+		return nil, err
+	}
+
+	if err := enc.Flush(); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+// Unmarshal the value from encoded XML.
+func (x XML) Unmarshal(data []byte, value interface{}) error {
+	v := reflect.ValueOf(value)
+
+	if v.Kind() != reflect.Ptr {
+		return fmt.Errorf("value must be pointer but found %s", v.Kind())
+	}
+
+	if !v.Elem().CanSet() {
+		return fmt.Errorf("interface value cannot be set")
+	}
+
+	dec := xml.NewDecoder(bytes.NewReader(data))
+	for {
+		token, err := dec.Token()
+		if err != nil {
+			if err == io.EOF {
+				return fmt.Errorf("no root element found")
+			}
+			return err
+		}
+
+		if start, ok := token.(xml.StartElement); ok {
+			root, err := decodeXMLElement(dec, start)
+			if err != nil {
+				return err
+			}
+
+			v.Elem().Set(reflect.ValueOf(map[string]interface{}{
+				start.Name.Local: root,
+			}))
+			return nil
+		}
+	}
+}
+
+// decodeXMLElement reads the contents of an element up to and including its
+// end token and returns its generic representation.
+func decodeXMLElement(dec *xml.Decoder, start xml.StartElement) (interface{}, error) {
+	result := map[string]interface{}{}
+	text := strings.Builder{}
+
+	for _, attr := range start.Attr {
+		if attr.Name.Space == "xmlns" || attr.Name.Local == "xmlns" {
+			// Namespace declarations are not useful once decoded.
+			continue
+		}
+		result["@"+attr.Name.Local] = attr.Value
+	}
+
+	for {
+		token, err := dec.Token()
+		if err != nil {
+			return nil, err
+		}
+
+		switch t := token.(type) {
+		case xml.StartElement:
+			child, err := decodeXMLElement(dec, t)
+			if err != nil {
+				return nil, err
+			}
+
+			name := t.Name.Local
+			if existing, ok := result[name]; ok {
+				if list, ok := existing.([]interface{}); ok {
+					result[name] = append(list, child)
+				} else {
+					result[name] = []interface{}{existing, child}
+				}
+			} else {
+				result[name] = child
+			}
+		case xml.CharData:
+			text.Write(t)
+		case xml.EndElement:
+			s := strings.TrimSpace(text.String())
+
+			if len(result) == 0 {
+				return s, nil
+			}
+
+			if s != "" {
+				result["#text"] = s
+			}
+
+			return result, nil
+		}
+	}
+}
+
+// encodeXMLElement writes a generic value as an element with the given name.
+func encodeXMLElement(enc *xml.Encoder, name string, value interface{}) error {
+	if list, ok := value.([]interface{}); ok {
+		// Arrays are written as repeated elements.
+		for _, item := range list {
+			if err := encodeXMLElement(enc, name, item); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	start := xml.StartElement{Name: xml.Name{Local: name}}
+
+	m, ok := value.(map[string]interface{})
+	if !ok {
+		if err := enc.EncodeToken(start); err != nil {
+			return err
+		}
+
+		if value != nil {
+			if err := enc.EncodeToken(xml.CharData(fmt.Sprintf("%v", value))); err != nil {
+				return err
+			}
+		}
+
+		return enc.EncodeToken(start.End())
+	}
+
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	for _, k := range keys {
+		if strings.HasPrefix(k, "@") {
+			start.Attr = append(start.Attr, xml.Attr{
+				Name:  xml.Name{Local: k[1:]},
+				Value: fmt.Sprintf("%v", m[k]),
+			})
+		}
+	}
+
+	if err := enc.EncodeToken(start); err != nil {
+		return err
+	}
+
+	if text, ok := m["#text"]; ok {
+		if err := enc.EncodeToken(xml.CharData(fmt.Sprintf("%v", text))); err != nil {
+			return err
+		}
+	}
+
+	for _, k := range keys {
+		if strings.HasPrefix(k, "@") || k == "#text" {
+			continue
+		}
+
+		if err := encodeXMLElement(enc, k, m[k]); err != nil {
+			return err
+		}
+	}
+
+	return enc.EncodeToken(start.End())
+}
@@ -11,6 +11,8 @@ var commit string
 var date string
 
 func main() {
+	cli.BuildCommit = commit
+	cli.BuildDate = date
 	cli.Init("restish", version)
 
 	// Register default encodings, content type handlers, and link parsers.
@@ -17,6 +17,7 @@ import (
 	"context"
 
 	"github.com/danielgtaylor/restish/cli"
+	"github.com/spf13/cast"
 	"golang.org/x/oauth2"
 )
 
@@ -326,12 +327,11 @@ func (h *AuthorizationCodeHandler) OnRequest(request *http.Request, key string,
 
 		// Try to get a cached refresh token from the current profile and use
 		// it to wrap the auth code token source with a refreshing source.
-		refreshKey := key + ".refresh"
 		refreshSource := RefreshTokenSource{
 			ClientID:       params["client_id"],
 			TokenURL:       params["token_url"],
 			EndpointParams: &endpointParams,
-			RefreshToken:   cli.Cache.GetString(refreshKey),
+			RefreshToken:   cast.ToString(cli.GetAuthCache(key)["refresh"]),
 			TokenSource:    source,
 		}
 
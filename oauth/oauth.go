@@ -5,6 +5,7 @@ import (
 	"net/http"
 
 	"github.com/danielgtaylor/restish/cli"
+	"github.com/spf13/cast"
 	"golang.org/x/oauth2"
 )
 
@@ -18,18 +19,15 @@ func TokenHandler(source oauth2.TokenSource, key string, request *http.Request)
 	var cached *oauth2.Token
 
 	// Load any existing token from the CLI's cache file.
-	expiresKey := key + ".expires"
-	typeKey := key + ".type"
-	tokenKey := key + ".token"
-	refreshKey := key + ".refresh"
+	data := cli.GetAuthCache(key)
 
-	expiry := cli.Cache.GetTime(expiresKey)
+	expiry := cast.ToTime(data["expires"])
 	if !expiry.IsZero() {
 		cli.LogDebug("Loading OAuth2 token from cache.")
 		cached = &oauth2.Token{
-			AccessToken:  cli.Cache.GetString(tokenKey),
-			RefreshToken: cli.Cache.GetString(refreshKey),
-			TokenType:    cli.Cache.GetString(typeKey),
+			AccessToken:  cast.ToString(data["token"]),
+			RefreshToken: cast.ToString(data["refresh"]),
+			TokenType:    cast.ToString(data["type"]),
 			Expiry:       expiry,
 		}
 	}
@@ -50,19 +48,25 @@ func TokenHandler(source oauth2.TokenSource, key string, request *http.Request)
 		// the new values to the CLI cache.
 		cli.LogDebug("Token refreshed. Updating cache.")
 
-		cli.Cache.Set(expiresKey, token.Expiry)
-		cli.Cache.Set(typeKey, token.Type())
-		cli.Cache.Set(tokenKey, token.AccessToken)
+		updated := map[string]interface{}{
+			"expires": token.Expiry,
+			"type":    token.Type(),
+			"token":   token.AccessToken,
+		}
 
-		if token.RefreshToken != "" {
-			// Only set the refresh token if present. This prevents overwriting it
-			// after using a refresh token, because the newly returned token won't
-			// have another refresh token set on it (you keep using the same one).
-			cli.Cache.Set(refreshKey, token.RefreshToken)
+		// Only set the refresh token if present. This prevents overwriting it
+		// after using a refresh token, because the newly returned token won't
+		// have another refresh token set on it (you keep using the same one).
+		refresh := token.RefreshToken
+		if refresh == "" {
+			refresh = cast.ToString(data["refresh"])
+		}
+		if refresh != "" {
+			updated["refresh"] = refresh
 		}
 
 		// Save the cache to disk.
-		if err := cli.Cache.WriteConfig(); err != nil {
+		if err := cli.SetAuthCache(key, updated); err != nil {
 			return err
 		}
 	}
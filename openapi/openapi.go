@@ -338,6 +338,7 @@ func openapiOperation(cmd *cobra.Command, method string, uriTemplate *url.URL, p
 		BodyMediaType: mediaType,
 		Examples:      examples,
 		Hidden:        hidden,
+		Tags:          op.Tags,
 	}
 }
 
@@ -521,6 +522,14 @@ func loadOpenAPI3(cfg Resolver, cmd *cobra.Command, location *url.URL, resp *htt
 		Auth:       authSchemes,
 	}
 
+	if swagger.Info != nil {
+		api.TermsURL = swagger.Info.TermsOfService
+	}
+
+	if swagger.ExternalDocs != nil {
+		api.DocsURL = swagger.ExternalDocs.URL
+	}
+
 	if swagger.Extensions["x-cli-config"] != nil {
 		loadAutoConfig(&api, swagger)
 	}
@@ -170,6 +170,7 @@ func TestLoadOpenAPI(t *testing.T) {
 				Short:        "Create a pet",
 				Long:         "\n## Response 201\n\nNull response\n\n## Response default (application/json)\n\nunexpected error\n\n```schema\n{\n  code*: (integer format:int32) \n  message*: (string) \n}\n```\n",
 				Method:       "POST",
+				Tags:         []string{"pets"},
 				URITemplate:  "http://api.example.com/pets",
 				PathParams:   []*cli.Param{},
 				QueryParams:  []*cli.Param{},
@@ -181,6 +182,7 @@ func TestLoadOpenAPI(t *testing.T) {
 				Short:       "List all pets",
 				Long:        "\n## Response 200 (application/json)\n\nA paged array of pets\n\n```schema\n[\n  {\n    id*: (integer format:int64) \n    name*: (string) \n    tag: (string) \n  }\n]\n```\n\n## Response default (application/json)\n\nunexpected error\n\n```schema\n{\n  code*: (integer format:int32) \n  message*: (string) \n}\n```\n",
 				Method:      "GET",
+				Tags:        []string{"pets"},
 				URITemplate: "http://api.example.com/pets",
 				PathParams:  []*cli.Param{},
 				QueryParams: []*cli.Param{
@@ -198,6 +200,7 @@ func TestLoadOpenAPI(t *testing.T) {
 				Short:       "Info for a specific pet",
 				Long:        "\n## Response 200 (application/json)\n\nExpected response to a valid request\n\n```schema\n{\n  id*: (integer format:int64) \n  name*: (string) \n  tag: (string) \n}\n```\n\n## Response default (application/json)\n\nunexpected error\n\n```schema\n{\n  code*: (integer format:int32) \n  message*: (string) \n}\n```\n",
 				Method:      "GET",
+				Tags:        []string{"pets"},
 				URITemplate: "http://api.example.com/pets/{petId}",
 				PathParams: []*cli.Param{
 					{